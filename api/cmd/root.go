@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/sneakybugs/corewarden/api/services"
 	"github.com/sneakybugs/corewarden/api/services/auth"
@@ -60,16 +61,18 @@ func CreateRootCommand() Command {
 			}
 
 			app := services.NewApp(services.Options{
-				GRPCPort:         cfg.GetUint16("grpc-port"),
-				HTTPPort:         cfg.GetUint16("http-port"),
-				PostgresDatabase: cfg.GetString("postgres-database"),
-				PostgresHost:     cfg.GetString("postgres-host"),
-				PostgresPassword: cfg.GetString("postgres-password"),
-				PostgresPort:     cfg.GetUint16("postgres-port"),
-				PostgresUser:     cfg.GetString("postgres-user"),
-				PolicyFile:       cfg.GetString("policy-file"),
-				ServiceAccounts:  parsedServiceAccounts,
-				Verbose:          cfg.GetBool("verbose"),
+				GRPCPort:          cfg.GetUint16("grpc-port"),
+				HTTPPort:          cfg.GetUint16("http-port"),
+				PostgresDatabase:  cfg.GetString("postgres-database"),
+				PostgresHost:      cfg.GetString("postgres-host"),
+				PostgresPassword:  cfg.GetString("postgres-password"),
+				PostgresPort:      cfg.GetUint16("postgres-port"),
+				PostgresUser:      cfg.GetString("postgres-user"),
+				PolicyFile:        cfg.GetString("policy-file"),
+				QueryLogRetention: cfg.GetDuration("query-log-retention"),
+				RecordCache:       cfg.GetBool("record-cache"),
+				ServiceAccounts:   parsedServiceAccounts,
+				Verbose:           cfg.GetBool("verbose"),
 			})
 			app.Run()
 		},
@@ -106,6 +109,14 @@ func CreateRootCommand() Command {
 	cmd.Flags().String("postgres-password", "", "Postgres password")
 	_ = cfg.BindPFlag("postgres-password", cmd.Flags().Lookup("postgres-password"))
 
+	cmd.Flags().Duration("query-log-retention", 7*24*time.Hour, "Delete query log entries older than this, 0s keeps them forever")
+	_ = cfg.BindPFlag("query-log-retention", cmd.Flags().Lookup("query-log-retention"))
+	cfg.SetDefault("query-log-retention", 7*24*time.Hour)
+
+	cmd.Flags().Bool("record-cache", true, "Resolve DNS queries from an in-memory record cache")
+	_ = cfg.BindPFlag("record-cache", cmd.Flags().Lookup("record-cache"))
+	cfg.SetDefault("record-cache", true)
+
 	cmd.Flags().Bool("verbose", false, "Enable verbose debug logging")
 	_ = cfg.BindPFlag("verbose", cmd.Flags().Lookup("verbose"))
 	cfg.SetDefault("verbose", false)
@@ -0,0 +1,26 @@
+-- +migrate Up
+CREATE TABLE Zones (
+	id SERIAL PRIMARY KEY,
+
+	-- Zone
+	name TEXT NOT NULL UNIQUE,
+	default_ttl INTEGER NOT NULL DEFAULT 3600,
+	soa_serial BIGINT NOT NULL DEFAULT 1,
+	nameservers TEXT[] NOT NULL DEFAULT '{}',
+
+	-- Metadata
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	modified_on TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	comment TEXT NOT NULL DEFAULT ''
+);
+
+-- Zones of existing records become zones.
+INSERT INTO Zones (name)
+SELECT DISTINCT zone FROM Records;
+
+ALTER TABLE Records
+ADD CONSTRAINT records_zone_fkey FOREIGN KEY (zone) REFERENCES Zones (name);
+
+-- +migrate Down
+ALTER TABLE Records DROP CONSTRAINT records_zone_fkey;
+DROP TABLE Zones;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- Indexes for paginating and filtering records in a zone.
+CREATE INDEX records_zone_id_idx ON Records (zone, id);
+CREATE INDEX records_zone_name_id_idx ON Records (zone, name, id);
+CREATE INDEX records_zone_modified_on_idx ON Records (zone, modified_on);
+
+-- +migrate Down
+DROP INDEX records_zone_modified_on_idx;
+DROP INDEX records_zone_name_id_idx;
+DROP INDEX records_zone_id_idx;
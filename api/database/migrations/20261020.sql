@@ -0,0 +1,24 @@
+-- +migrate Up
+-- Notifies listeners with the node name of every changed record, so that
+-- record caches in all API replicas can be refreshed.
+-- +migrate StatementBegin
+CREATE FUNCTION notify_records_changed() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		PERFORM pg_notify('records_changed', OLD.name);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		PERFORM pg_notify('records_changed', NEW.name);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER records_changed
+AFTER INSERT OR UPDATE OR DELETE ON Records
+FOR EACH ROW EXECUTE FUNCTION notify_records_changed();
+
+-- +migrate Down
+DROP TRIGGER records_changed ON Records;
+DROP FUNCTION notify_records_changed();
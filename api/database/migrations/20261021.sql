@@ -0,0 +1,28 @@
+-- +migrate Up
+-- Names without records in authoritative zones are answered with NXDOMAIN
+-- instead of being forwarded.
+ALTER TABLE Zones ADD COLUMN authoritative BOOLEAN NOT NULL DEFAULT false;
+
+-- Notifies listeners with the name of every changed zone, so that record
+-- caches in all API replicas can answer negative responses.
+-- +migrate StatementBegin
+CREATE FUNCTION notify_zones_changed() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		PERFORM pg_notify('zones_changed', OLD.name);
+	ELSE
+		PERFORM pg_notify('zones_changed', NEW.name);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER zones_changed
+AFTER INSERT OR UPDATE OR DELETE ON Zones
+FOR EACH ROW EXECUTE FUNCTION notify_zones_changed();
+
+-- +migrate Down
+DROP TRIGGER zones_changed ON Zones;
+DROP FUNCTION notify_zones_changed();
+ALTER TABLE Zones DROP COLUMN authoritative;
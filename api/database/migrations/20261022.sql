@@ -0,0 +1,18 @@
+-- +migrate Up
+-- Rules combined with the blocklists of the filterlist CoreDNS plugin.
+CREATE TABLE Filter_Rules (
+	id SERIAL PRIMARY KEY,
+
+	-- Rule
+	rule TEXT NOT NULL,
+	-- Client group the rule applies to, empty for all groups.
+	client_group TEXT NOT NULL DEFAULT '',
+
+	-- Metadata
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	modified_on TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	comment TEXT NOT NULL DEFAULT ''
+);
+
+-- +migrate Down
+DROP TABLE Filter_Rules;
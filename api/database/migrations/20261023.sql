@@ -0,0 +1,25 @@
+-- +migrate Up
+-- Queries answered by the CoreDNS servers, deleted after the retention period.
+CREATE TABLE Query_Log (
+	id BIGSERIAL PRIMARY KEY,
+	time TIMESTAMPTZ NOT NULL,
+
+	-- Client
+	client TEXT NOT NULL,
+	client_id TEXT NOT NULL DEFAULT '',
+	client_group TEXT NOT NULL DEFAULT '',
+
+	-- Query
+	name TEXT NOT NULL,
+	type INTEGER NOT NULL,
+	rcode INTEGER NOT NULL,
+	-- List of the rule blocking the query, empty when not blocked.
+	blocklist TEXT NOT NULL DEFAULT '',
+	-- Time to answer the query in microseconds.
+	duration BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX query_log_time_idx ON Query_Log (time, id);
+
+-- +migrate Down
+DROP TABLE Query_Log;
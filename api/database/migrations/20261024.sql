@@ -0,0 +1,23 @@
+-- +migrate Up
+-- Pauses of blocking by the filterlist CoreDNS plugin. Ended pauses are kept
+-- as an audit trail.
+CREATE TABLE Filter_Pauses (
+	id SERIAL PRIMARY KEY,
+
+	-- Pause
+	-- Client group the pause applies to, empty for all groups.
+	client_group TEXT NOT NULL DEFAULT '',
+	until TIMESTAMPTZ NOT NULL,
+
+	-- Audit
+	created_by TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	-- Empty unless the pause was ended early.
+	ended_by TEXT NOT NULL DEFAULT '',
+	comment TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX filter_pauses_until_idx ON Filter_Pauses (until);
+
+-- +migrate Down
+DROP TABLE Filter_Pauses;
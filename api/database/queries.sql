@@ -4,22 +4,27 @@ INSERT INTO Records
 VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
--- name: AnyRecordsExistAtNode :one
+-- name: AnyOtherRecordsExistAtNode :one
 SELECT EXISTS(
   SELECT 1 FROM Records
-  WHERE zone = $1 and name = $2
+  WHERE zone = $1 and name = $2 and id != $3
 );
 
--- name: CNAMERecordExistsAtNode :one
+-- name: OtherCNAMERecordExistsAtNode :one
 SELECT EXISTS(
   SELECT 1 FROM Records
-  WHERE zone = $1 and name = $2 and type = 5
+  WHERE zone = $1 and name = $2 and type = 5 and id != $3
 );
 
 -- name: ReadRecord :one
 SELECT * FROM Records
 WHERE id = $1;
 
+-- name: ReadRecordForUpdate :one
+SELECT * FROM Records
+WHERE id = $1
+FOR UPDATE;
+
 -- name: UpdateRecord :one
 UPDATE Records
 SET zone = $1, content = $2, name = $3, is_wildcard = $4, type = $5, comment = $6, modified_on = NOW()
@@ -35,10 +40,175 @@ RETURNING *;
 SELECT * FROM Records
 WHERE zone = $1;
 
+-- name: ListRecordsPageByID :many
+SELECT * FROM Records
+WHERE zone = @zone
+  AND (sqlc.narg(name)::text IS NULL OR name = sqlc.narg(name))
+  AND (sqlc.narg(type)::integer IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(is_wildcard)::boolean IS NULL OR is_wildcard = sqlc.narg(is_wildcard))
+  AND (sqlc.narg(comment)::text IS NULL OR strpos(lower(comment), lower(sqlc.narg(comment))) > 0)
+  AND (sqlc.narg(modified_since)::timestamptz IS NULL OR modified_on >= sqlc.narg(modified_since))
+  AND id > @after_id
+ORDER BY id
+LIMIT @page_size;
+
+-- name: ListRecordsPageByName :many
+SELECT * FROM Records
+WHERE zone = @zone
+  AND (sqlc.narg(name)::text IS NULL OR name = sqlc.narg(name))
+  AND (sqlc.narg(type)::integer IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(is_wildcard)::boolean IS NULL OR is_wildcard = sqlc.narg(is_wildcard))
+  AND (sqlc.narg(comment)::text IS NULL OR strpos(lower(comment), lower(sqlc.narg(comment))) > 0)
+  AND (sqlc.narg(modified_since)::timestamptz IS NULL OR modified_on >= sqlc.narg(modified_since))
+  AND (name, id) > (@after_name::text, @after_id::integer)
+ORDER BY name, id
+LIMIT @page_size;
+
 -- name: ResolveRecord :many
 SELECT * FROM Records
 WHERE name = $1 and (type = $2 or type = 5) and is_wildcard = false;
 
+-- name: ListAllRecords :many
+SELECT * FROM Records;
+
+-- name: ListRecordsAtNodes :many
+SELECT * FROM Records
+WHERE name = ANY(sqlc.arg(names)::text[]);
+
 -- name: ResolveWildcardRecord :many
 SELECT * FROM Records
 WHERE name = ANY(sqlc.arg(names)::text[]) and type = $1 and is_wildcard = true;
+
+-- name: ReadClosestZone :one
+SELECT * FROM Zones
+WHERE name = ANY(sqlc.arg(names)::text[])
+ORDER BY length(name) DESC
+LIMIT 1;
+
+-- name: AnyRecordsExistAtNode :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE name = $1 and is_wildcard = false
+);
+
+-- name: AnyWildcardRecordsExistAtNodes :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE name = ANY(sqlc.arg(names)::text[]) and is_wildcard = true
+);
+
+-- name: AnyRecordsExistBelowNode :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE (name = sqlc.arg(name) and is_wildcard = true) or right(name, length(sqlc.arg(name)) + 1) = '.' || sqlc.arg(name)
+);
+
+-- name: CreateZone :one
+INSERT INTO Zones
+(name, default_ttl, nameservers, authoritative, comment)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ReadZone :one
+SELECT * FROM Zones
+WHERE name = $1;
+
+-- name: UpdateZone :one
+UPDATE Zones
+SET default_ttl = $1, nameservers = $2, authoritative = $3, comment = $4, soa_serial = soa_serial + 1, modified_on = NOW()
+WHERE name = $5
+RETURNING *;
+
+-- name: DeleteZone :one
+DELETE FROM Zones
+WHERE name = $1
+RETURNING *;
+
+-- name: ListZones :many
+SELECT * FROM Zones
+ORDER BY name;
+
+-- name: IncrementZoneSerial :exec
+UPDATE Zones
+SET soa_serial = soa_serial + 1
+WHERE name = $1;
+
+-- name: AnyRecordsExistInZone :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE zone = $1
+);
+
+-- name: CreateFilterRule :one
+INSERT INTO Filter_Rules
+(rule, client_group, comment)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ReadFilterRule :one
+SELECT * FROM Filter_Rules
+WHERE id = $1;
+
+-- name: UpdateFilterRule :one
+UPDATE Filter_Rules
+SET rule = $1, client_group = $2, comment = $3, modified_on = NOW()
+WHERE id = $4
+RETURNING *;
+
+-- name: DeleteFilterRule :one
+DELETE FROM Filter_Rules
+WHERE id = $1
+RETURNING *;
+
+-- name: ListFilterRules :many
+SELECT * FROM Filter_Rules
+ORDER BY id;
+
+-- name: CreateFilterPause :one
+INSERT INTO Filter_Pauses
+(client_group, until, created_by, comment)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: EndFilterPause :one
+UPDATE Filter_Pauses
+SET until = @ended_at, ended_by = @ended_by
+WHERE id = @id AND until > @ended_at
+RETURNING *;
+
+-- name: ListActiveFilterPauses :many
+SELECT * FROM Filter_Pauses
+WHERE until > $1
+ORDER BY id;
+
+-- name: CreateQueryLogEntries :exec
+INSERT INTO Query_Log
+(time, client, client_id, client_group, name, type, rcode, blocklist, duration)
+SELECT * FROM unnest(
+  @times::timestamptz[],
+  @clients::text[],
+  @client_ids::text[],
+  @client_groups::text[],
+  @names::text[],
+  @types::integer[],
+  @rcodes::integer[],
+  @blocklists::text[],
+  @durations::bigint[]
+);
+
+-- name: ListQueryLog :many
+SELECT * FROM Query_Log
+WHERE (sqlc.narg(client)::text IS NULL OR client = sqlc.narg(client) OR client_id = sqlc.narg(client))
+  AND (sqlc.narg(name)::text IS NULL OR name = sqlc.narg(name))
+  AND (sqlc.narg(type)::integer IS NULL OR type = sqlc.narg(type))
+  AND (sqlc.narg(rcode)::integer IS NULL OR rcode = sqlc.narg(rcode))
+  AND (sqlc.narg(blocklist)::text IS NULL OR blocklist = sqlc.narg(blocklist))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR time >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR time < sqlc.narg(until))
+  AND (sqlc.narg(before_time)::timestamptz IS NULL OR (time, id) < (sqlc.narg(before_time), @before_id::bigint))
+ORDER BY time DESC, id DESC
+LIMIT @page_size;
+
+-- name: DeleteQueryLogBefore :execrows
+DELETE FROM Query_Log
+WHERE time < $1;
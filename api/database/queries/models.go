@@ -8,6 +8,38 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type FilterPause struct {
+	ID          int32
+	ClientGroup string
+	Until       pgtype.Timestamptz
+	CreatedBy   string
+	CreatedAt   pgtype.Timestamptz
+	EndedBy     string
+	Comment     string
+}
+
+type FilterRule struct {
+	ID          int32
+	Rule        string
+	ClientGroup string
+	CreatedAt   pgtype.Timestamptz
+	ModifiedOn  pgtype.Timestamptz
+	Comment     string
+}
+
+type QueryLog struct {
+	ID          int64
+	Time        pgtype.Timestamptz
+	Client      string
+	ClientID    string
+	ClientGroup string
+	Name        string
+	Type        int32
+	Rcode       int32
+	Blocklist   string
+	Duration    int64
+}
+
 type Record struct {
 	ID         int32
 	Zone       string
@@ -19,3 +51,15 @@ type Record struct {
 	ModifiedOn pgtype.Timestamptz
 	Comment    string
 }
+
+type Zone struct {
+	ID            int32
+	Name          string
+	DefaultTtl    int32
+	SoaSerial     int64
+	Nameservers   []string
+	CreatedAt     pgtype.Timestamptz
+	ModifiedOn    pgtype.Timestamptz
+	Comment       string
+	Authoritative bool
+}
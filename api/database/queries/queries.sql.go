@@ -7,46 +7,190 @@ package queries
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const anyRecordsExistAtNode = `-- name: AnyRecordsExistAtNode :one
+const anyOtherRecordsExistAtNode = `-- name: AnyOtherRecordsExistAtNode :one
 SELECT EXISTS(
   SELECT 1 FROM Records
-  WHERE zone = $1 and name = $2
+  WHERE zone = $1 and name = $2 and id != $3
 )
 `
 
-type AnyRecordsExistAtNodeParams struct {
+type AnyOtherRecordsExistAtNodeParams struct {
 	Zone string
 	Name string
+	ID   int32
 }
 
-func (q *Queries) AnyRecordsExistAtNode(ctx context.Context, arg AnyRecordsExistAtNodeParams) (bool, error) {
-	row := q.db.QueryRow(ctx, anyRecordsExistAtNode, arg.Zone, arg.Name)
+func (q *Queries) AnyOtherRecordsExistAtNode(ctx context.Context, arg AnyOtherRecordsExistAtNodeParams) (bool, error) {
+	row := q.db.QueryRow(ctx, anyOtherRecordsExistAtNode, arg.Zone, arg.Name, arg.ID)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
 }
 
-const cNAMERecordExistsAtNode = `-- name: CNAMERecordExistsAtNode :one
+const anyRecordsExistAtNode = `-- name: AnyRecordsExistAtNode :one
 SELECT EXISTS(
   SELECT 1 FROM Records
-  WHERE zone = $1 and name = $2 and type = 5
+  WHERE name = $1 and is_wildcard = false
 )
 `
 
-type CNAMERecordExistsAtNodeParams struct {
-	Zone string
-	Name string
+func (q *Queries) AnyRecordsExistAtNode(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRow(ctx, anyRecordsExistAtNode, name)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const anyRecordsExistBelowNode = `-- name: AnyRecordsExistBelowNode :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE (name = $1 and is_wildcard = true) or right(name, length($1) + 1) = '.' || $1
+)
+`
+
+func (q *Queries) AnyRecordsExistBelowNode(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRow(ctx, anyRecordsExistBelowNode, name)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
 }
 
-func (q *Queries) CNAMERecordExistsAtNode(ctx context.Context, arg CNAMERecordExistsAtNodeParams) (bool, error) {
-	row := q.db.QueryRow(ctx, cNAMERecordExistsAtNode, arg.Zone, arg.Name)
+const anyRecordsExistInZone = `-- name: AnyRecordsExistInZone :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE zone = $1
+)
+`
+
+func (q *Queries) AnyRecordsExistInZone(ctx context.Context, zone string) (bool, error) {
+	row := q.db.QueryRow(ctx, anyRecordsExistInZone, zone)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
 }
 
+const anyWildcardRecordsExistAtNodes = `-- name: AnyWildcardRecordsExistAtNodes :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE name = ANY($1::text[]) and is_wildcard = true
+)
+`
+
+func (q *Queries) AnyWildcardRecordsExistAtNodes(ctx context.Context, names []string) (bool, error) {
+	row := q.db.QueryRow(ctx, anyWildcardRecordsExistAtNodes, names)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const createFilterPause = `-- name: CreateFilterPause :one
+INSERT INTO Filter_Pauses
+(client_group, until, created_by, comment)
+VALUES ($1, $2, $3, $4)
+RETURNING id, client_group, until, created_by, created_at, ended_by, comment
+`
+
+type CreateFilterPauseParams struct {
+	ClientGroup string
+	Until       pgtype.Timestamptz
+	CreatedBy   string
+	Comment     string
+}
+
+func (q *Queries) CreateFilterPause(ctx context.Context, arg CreateFilterPauseParams) (FilterPause, error) {
+	row := q.db.QueryRow(ctx, createFilterPause,
+		arg.ClientGroup,
+		arg.Until,
+		arg.CreatedBy,
+		arg.Comment,
+	)
+	var i FilterPause
+	err := row.Scan(
+		&i.ID,
+		&i.ClientGroup,
+		&i.Until,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.EndedBy,
+		&i.Comment,
+	)
+	return i, err
+}
+
+const createFilterRule = `-- name: CreateFilterRule :one
+INSERT INTO Filter_Rules
+(rule, client_group, comment)
+VALUES ($1, $2, $3)
+RETURNING id, rule, client_group, created_at, modified_on, comment
+`
+
+type CreateFilterRuleParams struct {
+	Rule        string
+	ClientGroup string
+	Comment     string
+}
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRow(ctx, createFilterRule, arg.Rule, arg.ClientGroup, arg.Comment)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.Rule,
+		&i.ClientGroup,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+	)
+	return i, err
+}
+
+const createQueryLogEntries = `-- name: CreateQueryLogEntries :exec
+INSERT INTO Query_Log
+(time, client, client_id, client_group, name, type, rcode, blocklist, duration)
+SELECT * FROM unnest(
+  $1::timestamptz[],
+  $2::text[],
+  $3::text[],
+  $4::text[],
+  $5::text[],
+  $6::integer[],
+  $7::integer[],
+  $8::text[],
+  $9::bigint[]
+)
+`
+
+type CreateQueryLogEntriesParams struct {
+	Times        []pgtype.Timestamptz
+	Clients      []string
+	ClientIds    []string
+	ClientGroups []string
+	Names        []string
+	Types        []int32
+	Rcodes       []int32
+	Blocklists   []string
+	Durations    []int64
+}
+
+func (q *Queries) CreateQueryLogEntries(ctx context.Context, arg CreateQueryLogEntriesParams) error {
+	_, err := q.db.Exec(ctx, createQueryLogEntries,
+		arg.Times,
+		arg.Clients,
+		arg.ClientIds,
+		arg.ClientGroups,
+		arg.Names,
+		arg.Types,
+		arg.Rcodes,
+		arg.Blocklists,
+		arg.Durations,
+	)
+	return err
+}
+
 const createRecord = `-- name: CreateRecord :one
 INSERT INTO Records
 (zone, content, name, is_wildcard, type, comment)
@@ -87,6 +231,77 @@ func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Rec
 	return i, err
 }
 
+const createZone = `-- name: CreateZone :one
+INSERT INTO Zones
+(name, default_ttl, nameservers, authoritative, comment)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative
+`
+
+type CreateZoneParams struct {
+	Name          string
+	DefaultTtl    int32
+	Nameservers   []string
+	Authoritative bool
+	Comment       string
+}
+
+func (q *Queries) CreateZone(ctx context.Context, arg CreateZoneParams) (Zone, error) {
+	row := q.db.QueryRow(ctx, createZone,
+		arg.Name,
+		arg.DefaultTtl,
+		arg.Nameservers,
+		arg.Authoritative,
+		arg.Comment,
+	)
+	var i Zone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DefaultTtl,
+		&i.SoaSerial,
+		&i.Nameservers,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+		&i.Authoritative,
+	)
+	return i, err
+}
+
+const deleteFilterRule = `-- name: DeleteFilterRule :one
+DELETE FROM Filter_Rules
+WHERE id = $1
+RETURNING id, rule, client_group, created_at, modified_on, comment
+`
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, id int32) (FilterRule, error) {
+	row := q.db.QueryRow(ctx, deleteFilterRule, id)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.Rule,
+		&i.ClientGroup,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+	)
+	return i, err
+}
+
+const deleteQueryLogBefore = `-- name: DeleteQueryLogBefore :execrows
+DELETE FROM Query_Log
+WHERE time < $1
+`
+
+func (q *Queries) DeleteQueryLogBefore(ctx context.Context, time pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteQueryLogBefore, time)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteRecord = `-- name: DeleteRecord :one
 DELETE FROM Records
 WHERE id = $1
@@ -110,6 +325,237 @@ func (q *Queries) DeleteRecord(ctx context.Context, id int32) (Record, error) {
 	return i, err
 }
 
+const deleteZone = `-- name: DeleteZone :one
+DELETE FROM Zones
+WHERE name = $1
+RETURNING id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative
+`
+
+func (q *Queries) DeleteZone(ctx context.Context, name string) (Zone, error) {
+	row := q.db.QueryRow(ctx, deleteZone, name)
+	var i Zone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DefaultTtl,
+		&i.SoaSerial,
+		&i.Nameservers,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+		&i.Authoritative,
+	)
+	return i, err
+}
+
+const endFilterPause = `-- name: EndFilterPause :one
+UPDATE Filter_Pauses
+SET until = $1, ended_by = $2
+WHERE id = $3 AND until > $1
+RETURNING id, client_group, until, created_by, created_at, ended_by, comment
+`
+
+type EndFilterPauseParams struct {
+	EndedAt pgtype.Timestamptz
+	EndedBy string
+	ID      int32
+}
+
+func (q *Queries) EndFilterPause(ctx context.Context, arg EndFilterPauseParams) (FilterPause, error) {
+	row := q.db.QueryRow(ctx, endFilterPause, arg.EndedAt, arg.EndedBy, arg.ID)
+	var i FilterPause
+	err := row.Scan(
+		&i.ID,
+		&i.ClientGroup,
+		&i.Until,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.EndedBy,
+		&i.Comment,
+	)
+	return i, err
+}
+
+const incrementZoneSerial = `-- name: IncrementZoneSerial :exec
+UPDATE Zones
+SET soa_serial = soa_serial + 1
+WHERE name = $1
+`
+
+func (q *Queries) IncrementZoneSerial(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, incrementZoneSerial, name)
+	return err
+}
+
+const listActiveFilterPauses = `-- name: ListActiveFilterPauses :many
+SELECT id, client_group, until, created_by, created_at, ended_by, comment FROM Filter_Pauses
+WHERE until > $1
+ORDER BY id
+`
+
+func (q *Queries) ListActiveFilterPauses(ctx context.Context, until pgtype.Timestamptz) ([]FilterPause, error) {
+	rows, err := q.db.Query(ctx, listActiveFilterPauses, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterPause
+	for rows.Next() {
+		var i FilterPause
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClientGroup,
+			&i.Until,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.EndedBy,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllRecords = `-- name: ListAllRecords :many
+SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
+`
+
+func (q *Queries) ListAllRecords(ctx context.Context) ([]Record, error) {
+	rows, err := q.db.Query(ctx, listAllRecords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Record
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Zone,
+			&i.Content,
+			&i.Name,
+			&i.IsWildcard,
+			&i.Type,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilterRules = `-- name: ListFilterRules :many
+SELECT id, rule, client_group, created_at, modified_on, comment FROM Filter_Rules
+ORDER BY id
+`
+
+func (q *Queries) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	rows, err := q.db.Query(ctx, listFilterRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterRule
+	for rows.Next() {
+		var i FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Rule,
+			&i.ClientGroup,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQueryLog = `-- name: ListQueryLog :many
+SELECT id, time, client, client_id, client_group, name, type, rcode, blocklist, duration FROM Query_Log
+WHERE ($1::text IS NULL OR client = $1 OR client_id = $1)
+  AND ($2::text IS NULL OR name = $2)
+  AND ($3::integer IS NULL OR type = $3)
+  AND ($4::integer IS NULL OR rcode = $4)
+  AND ($5::text IS NULL OR blocklist = $5)
+  AND ($6::timestamptz IS NULL OR time >= $6)
+  AND ($7::timestamptz IS NULL OR time < $7)
+  AND ($8::timestamptz IS NULL OR (time, id) < ($8, $9::bigint))
+ORDER BY time DESC, id DESC
+LIMIT $10
+`
+
+type ListQueryLogParams struct {
+	Client     pgtype.Text
+	Name       pgtype.Text
+	Type       pgtype.Int4
+	Rcode      pgtype.Int4
+	Blocklist  pgtype.Text
+	Since      pgtype.Timestamptz
+	Until      pgtype.Timestamptz
+	BeforeTime pgtype.Timestamptz
+	BeforeID   int64
+	PageSize   int32
+}
+
+func (q *Queries) ListQueryLog(ctx context.Context, arg ListQueryLogParams) ([]QueryLog, error) {
+	rows, err := q.db.Query(ctx, listQueryLog,
+		arg.Client,
+		arg.Name,
+		arg.Type,
+		arg.Rcode,
+		arg.Blocklist,
+		arg.Since,
+		arg.Until,
+		arg.BeforeTime,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QueryLog
+	for rows.Next() {
+		var i QueryLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Time,
+			&i.Client,
+			&i.ClientID,
+			&i.ClientGroup,
+			&i.Name,
+			&i.Type,
+			&i.Rcode,
+			&i.Blocklist,
+			&i.Duration,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecords = `-- name: ListRecords :many
 SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
 WHERE zone = $1
@@ -145,6 +591,267 @@ func (q *Queries) ListRecords(ctx context.Context, zone string) ([]Record, error
 	return items, nil
 }
 
+const listRecordsAtNodes = `-- name: ListRecordsAtNodes :many
+SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
+WHERE name = ANY($1::text[])
+`
+
+func (q *Queries) ListRecordsAtNodes(ctx context.Context, names []string) ([]Record, error) {
+	rows, err := q.db.Query(ctx, listRecordsAtNodes, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Record
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Zone,
+			&i.Content,
+			&i.Name,
+			&i.IsWildcard,
+			&i.Type,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordsPageByID = `-- name: ListRecordsPageByID :many
+SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
+WHERE zone = $1
+  AND ($2::text IS NULL OR name = $2)
+  AND ($3::integer IS NULL OR type = $3)
+  AND ($4::boolean IS NULL OR is_wildcard = $4)
+  AND ($5::text IS NULL OR strpos(lower(comment), lower($5)) > 0)
+  AND ($6::timestamptz IS NULL OR modified_on >= $6)
+  AND id > $7
+ORDER BY id
+LIMIT $8
+`
+
+type ListRecordsPageByIDParams struct {
+	Zone          string
+	Name          pgtype.Text
+	Type          pgtype.Int4
+	IsWildcard    pgtype.Bool
+	Comment       pgtype.Text
+	ModifiedSince pgtype.Timestamptz
+	AfterID       int32
+	PageSize      int32
+}
+
+func (q *Queries) ListRecordsPageByID(ctx context.Context, arg ListRecordsPageByIDParams) ([]Record, error) {
+	rows, err := q.db.Query(ctx, listRecordsPageByID,
+		arg.Zone,
+		arg.Name,
+		arg.Type,
+		arg.IsWildcard,
+		arg.Comment,
+		arg.ModifiedSince,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Record
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Zone,
+			&i.Content,
+			&i.Name,
+			&i.IsWildcard,
+			&i.Type,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordsPageByName = `-- name: ListRecordsPageByName :many
+SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
+WHERE zone = $1
+  AND ($2::text IS NULL OR name = $2)
+  AND ($3::integer IS NULL OR type = $3)
+  AND ($4::boolean IS NULL OR is_wildcard = $4)
+  AND ($5::text IS NULL OR strpos(lower(comment), lower($5)) > 0)
+  AND ($6::timestamptz IS NULL OR modified_on >= $6)
+  AND (name, id) > ($7::text, $8::integer)
+ORDER BY name, id
+LIMIT $9
+`
+
+type ListRecordsPageByNameParams struct {
+	Zone          string
+	Name          pgtype.Text
+	Type          pgtype.Int4
+	IsWildcard    pgtype.Bool
+	Comment       pgtype.Text
+	ModifiedSince pgtype.Timestamptz
+	AfterName     string
+	AfterID       int32
+	PageSize      int32
+}
+
+func (q *Queries) ListRecordsPageByName(ctx context.Context, arg ListRecordsPageByNameParams) ([]Record, error) {
+	rows, err := q.db.Query(ctx, listRecordsPageByName,
+		arg.Zone,
+		arg.Name,
+		arg.Type,
+		arg.IsWildcard,
+		arg.Comment,
+		arg.ModifiedSince,
+		arg.AfterName,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Record
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Zone,
+			&i.Content,
+			&i.Name,
+			&i.IsWildcard,
+			&i.Type,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listZones = `-- name: ListZones :many
+SELECT id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative FROM Zones
+ORDER BY name
+`
+
+func (q *Queries) ListZones(ctx context.Context) ([]Zone, error) {
+	rows, err := q.db.Query(ctx, listZones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Zone
+	for rows.Next() {
+		var i Zone
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.DefaultTtl,
+			&i.SoaSerial,
+			&i.Nameservers,
+			&i.CreatedAt,
+			&i.ModifiedOn,
+			&i.Comment,
+			&i.Authoritative,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const otherCNAMERecordExistsAtNode = `-- name: OtherCNAMERecordExistsAtNode :one
+SELECT EXISTS(
+  SELECT 1 FROM Records
+  WHERE zone = $1 and name = $2 and type = 5 and id != $3
+)
+`
+
+type OtherCNAMERecordExistsAtNodeParams struct {
+	Zone string
+	Name string
+	ID   int32
+}
+
+func (q *Queries) OtherCNAMERecordExistsAtNode(ctx context.Context, arg OtherCNAMERecordExistsAtNodeParams) (bool, error) {
+	row := q.db.QueryRow(ctx, otherCNAMERecordExistsAtNode, arg.Zone, arg.Name, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const readClosestZone = `-- name: ReadClosestZone :one
+SELECT id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative FROM Zones
+WHERE name = ANY($1::text[])
+ORDER BY length(name) DESC
+LIMIT 1
+`
+
+func (q *Queries) ReadClosestZone(ctx context.Context, names []string) (Zone, error) {
+	row := q.db.QueryRow(ctx, readClosestZone, names)
+	var i Zone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DefaultTtl,
+		&i.SoaSerial,
+		&i.Nameservers,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+		&i.Authoritative,
+	)
+	return i, err
+}
+
+const readFilterRule = `-- name: ReadFilterRule :one
+SELECT id, rule, client_group, created_at, modified_on, comment FROM Filter_Rules
+WHERE id = $1
+`
+
+func (q *Queries) ReadFilterRule(ctx context.Context, id int32) (FilterRule, error) {
+	row := q.db.QueryRow(ctx, readFilterRule, id)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.Rule,
+		&i.ClientGroup,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+	)
+	return i, err
+}
+
 const readRecord = `-- name: ReadRecord :one
 SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
 WHERE id = $1
@@ -167,6 +874,51 @@ func (q *Queries) ReadRecord(ctx context.Context, id int32) (Record, error) {
 	return i, err
 }
 
+const readRecordForUpdate = `-- name: ReadRecordForUpdate :one
+SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) ReadRecordForUpdate(ctx context.Context, id int32) (Record, error) {
+	row := q.db.QueryRow(ctx, readRecordForUpdate, id)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Zone,
+		&i.Content,
+		&i.Name,
+		&i.IsWildcard,
+		&i.Type,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+	)
+	return i, err
+}
+
+const readZone = `-- name: ReadZone :one
+SELECT id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative FROM Zones
+WHERE name = $1
+`
+
+func (q *Queries) ReadZone(ctx context.Context, name string) (Zone, error) {
+	row := q.db.QueryRow(ctx, readZone, name)
+	var i Zone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DefaultTtl,
+		&i.SoaSerial,
+		&i.Nameservers,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+		&i.Authoritative,
+	)
+	return i, err
+}
+
 const resolveRecord = `-- name: ResolveRecord :many
 SELECT id, zone, content, name, is_wildcard, type, created_at, modified_on, comment FROM Records
 WHERE name = $1 and (type = $2 or type = 5) and is_wildcard = false
@@ -247,6 +999,39 @@ func (q *Queries) ResolveWildcardRecord(ctx context.Context, arg ResolveWildcard
 	return items, nil
 }
 
+const updateFilterRule = `-- name: UpdateFilterRule :one
+UPDATE Filter_Rules
+SET rule = $1, client_group = $2, comment = $3, modified_on = NOW()
+WHERE id = $4
+RETURNING id, rule, client_group, created_at, modified_on, comment
+`
+
+type UpdateFilterRuleParams struct {
+	Rule        string
+	ClientGroup string
+	Comment     string
+	ID          int32
+}
+
+func (q *Queries) UpdateFilterRule(ctx context.Context, arg UpdateFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRow(ctx, updateFilterRule,
+		arg.Rule,
+		arg.ClientGroup,
+		arg.Comment,
+		arg.ID,
+	)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.Rule,
+		&i.ClientGroup,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+	)
+	return i, err
+}
+
 const updateRecord = `-- name: UpdateRecord :one
 UPDATE Records
 SET zone = $1, content = $2, name = $3, is_wildcard = $4, type = $5, comment = $6, modified_on = NOW()
@@ -288,3 +1073,41 @@ func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) (Rec
 	)
 	return i, err
 }
+
+const updateZone = `-- name: UpdateZone :one
+UPDATE Zones
+SET default_ttl = $1, nameservers = $2, authoritative = $3, comment = $4, soa_serial = soa_serial + 1, modified_on = NOW()
+WHERE name = $5
+RETURNING id, name, default_ttl, soa_serial, nameservers, created_at, modified_on, comment, authoritative
+`
+
+type UpdateZoneParams struct {
+	DefaultTtl    int32
+	Nameservers   []string
+	Authoritative bool
+	Comment       string
+	Name          string
+}
+
+func (q *Queries) UpdateZone(ctx context.Context, arg UpdateZoneParams) (Zone, error) {
+	row := q.db.QueryRow(ctx, updateZone,
+		arg.DefaultTtl,
+		arg.Nameservers,
+		arg.Authoritative,
+		arg.Comment,
+		arg.Name,
+	)
+	var i Zone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DefaultTtl,
+		&i.SoaSerial,
+		&i.Nameservers,
+		&i.CreatedAt,
+		&i.ModifiedOn,
+		&i.Comment,
+		&i.Authoritative,
+	)
+	return i, err
+}
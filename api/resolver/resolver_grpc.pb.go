@@ -23,6 +23,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ResolverClient interface {
 	Resolve(ctx context.Context, in *Question, opts ...grpc.CallOption) (*Response, error)
+	// Streams a snapshot of all records followed by changes to them.
+	// Empty responses are sent periodically as heartbeats.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Resolver_WatchClient, error)
 }
 
 type resolverClient struct {
@@ -42,11 +45,46 @@ func (c *resolverClient) Resolve(ctx context.Context, in *Question, opts ...grpc
 	return out, nil
 }
 
+func (c *resolverClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Resolver_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Resolver_ServiceDesc.Streams[0], "/resolver.Resolver/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &resolverWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Resolver_WatchClient interface {
+	Recv() (*WatchResponse, error)
+	grpc.ClientStream
+}
+
+type resolverWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *resolverWatchClient) Recv() (*WatchResponse, error) {
+	m := new(WatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ResolverServer is the server API for Resolver service.
 // All implementations must embed UnimplementedResolverServer
 // for forward compatibility
 type ResolverServer interface {
 	Resolve(context.Context, *Question) (*Response, error)
+	// Streams a snapshot of all records followed by changes to them.
+	// Empty responses are sent periodically as heartbeats.
+	Watch(*WatchRequest, Resolver_WatchServer) error
 	mustEmbedUnimplementedResolverServer()
 }
 
@@ -57,6 +95,9 @@ type UnimplementedResolverServer struct {
 func (UnimplementedResolverServer) Resolve(context.Context, *Question) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
 }
+func (UnimplementedResolverServer) Watch(*WatchRequest, Resolver_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedResolverServer) mustEmbedUnimplementedResolverServer() {}
 
 // UnsafeResolverServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +129,27 @@ func _Resolver_Resolve_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Resolver_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ResolverServer).Watch(m, &resolverWatchServer{stream})
+}
+
+type Resolver_WatchServer interface {
+	Send(*WatchResponse) error
+	grpc.ServerStream
+}
+
+type resolverWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *resolverWatchServer) Send(m *WatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Resolver_ServiceDesc is the grpc.ServiceDesc for Resolver service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -100,6 +162,226 @@ var Resolver_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Resolver_Resolve_Handler,
 		},
 	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Resolver_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "resolver.proto",
+}
+
+// FilterClient is the client API for Filter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FilterClient interface {
+	// Lists the filter rules managed in the API server.
+	ListFilterRules(ctx context.Context, in *ListFilterRulesRequest, opts ...grpc.CallOption) (*ListFilterRulesResponse, error)
+	// Lists the active pauses of blocking.
+	ListFilterPauses(ctx context.Context, in *ListFilterPausesRequest, opts ...grpc.CallOption) (*ListFilterPausesResponse, error)
+}
+
+type filterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFilterClient(cc grpc.ClientConnInterface) FilterClient {
+	return &filterClient{cc}
+}
+
+func (c *filterClient) ListFilterRules(ctx context.Context, in *ListFilterRulesRequest, opts ...grpc.CallOption) (*ListFilterRulesResponse, error) {
+	out := new(ListFilterRulesResponse)
+	err := c.cc.Invoke(ctx, "/resolver.Filter/ListFilterRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *filterClient) ListFilterPauses(ctx context.Context, in *ListFilterPausesRequest, opts ...grpc.CallOption) (*ListFilterPausesResponse, error) {
+	out := new(ListFilterPausesResponse)
+	err := c.cc.Invoke(ctx, "/resolver.Filter/ListFilterPauses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FilterServer is the server API for Filter service.
+// All implementations must embed UnimplementedFilterServer
+// for forward compatibility
+type FilterServer interface {
+	// Lists the filter rules managed in the API server.
+	ListFilterRules(context.Context, *ListFilterRulesRequest) (*ListFilterRulesResponse, error)
+	// Lists the active pauses of blocking.
+	ListFilterPauses(context.Context, *ListFilterPausesRequest) (*ListFilterPausesResponse, error)
+	mustEmbedUnimplementedFilterServer()
+}
+
+// UnimplementedFilterServer must be embedded to have forward compatible implementations.
+type UnimplementedFilterServer struct {
+}
+
+func (UnimplementedFilterServer) ListFilterRules(context.Context, *ListFilterRulesRequest) (*ListFilterRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFilterRules not implemented")
+}
+func (UnimplementedFilterServer) ListFilterPauses(context.Context, *ListFilterPausesRequest) (*ListFilterPausesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFilterPauses not implemented")
+}
+func (UnimplementedFilterServer) mustEmbedUnimplementedFilterServer() {}
+
+// UnsafeFilterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FilterServer will
+// result in compilation errors.
+type UnsafeFilterServer interface {
+	mustEmbedUnimplementedFilterServer()
+}
+
+func RegisterFilterServer(s grpc.ServiceRegistrar, srv FilterServer) {
+	s.RegisterService(&Filter_ServiceDesc, srv)
+}
+
+func _Filter_ListFilterRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilterRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilterServer).ListFilterRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/resolver.Filter/ListFilterRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilterServer).ListFilterRules(ctx, req.(*ListFilterRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Filter_ListFilterPauses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilterPausesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilterServer).ListFilterPauses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/resolver.Filter/ListFilterPauses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilterServer).ListFilterPauses(ctx, req.(*ListFilterPausesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Filter_ServiceDesc is the grpc.ServiceDesc for Filter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Filter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "resolver.Filter",
+	HandlerType: (*FilterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFilterRules",
+			Handler:    _Filter_ListFilterRules_Handler,
+		},
+		{
+			MethodName: "ListFilterPauses",
+			Handler:    _Filter_ListFilterPauses_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "resolver.proto",
+}
+
+// QueryLogClient is the client API for QueryLog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type QueryLogClient interface {
+	// Stores a batch of query events of a DNS server.
+	AppendQueryLog(ctx context.Context, in *AppendQueryLogRequest, opts ...grpc.CallOption) (*AppendQueryLogResponse, error)
+}
+
+type queryLogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQueryLogClient(cc grpc.ClientConnInterface) QueryLogClient {
+	return &queryLogClient{cc}
+}
+
+func (c *queryLogClient) AppendQueryLog(ctx context.Context, in *AppendQueryLogRequest, opts ...grpc.CallOption) (*AppendQueryLogResponse, error) {
+	out := new(AppendQueryLogResponse)
+	err := c.cc.Invoke(ctx, "/resolver.QueryLog/AppendQueryLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QueryLogServer is the server API for QueryLog service.
+// All implementations must embed UnimplementedQueryLogServer
+// for forward compatibility
+type QueryLogServer interface {
+	// Stores a batch of query events of a DNS server.
+	AppendQueryLog(context.Context, *AppendQueryLogRequest) (*AppendQueryLogResponse, error)
+	mustEmbedUnimplementedQueryLogServer()
+}
+
+// UnimplementedQueryLogServer must be embedded to have forward compatible implementations.
+type UnimplementedQueryLogServer struct {
+}
+
+func (UnimplementedQueryLogServer) AppendQueryLog(context.Context, *AppendQueryLogRequest) (*AppendQueryLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendQueryLog not implemented")
+}
+func (UnimplementedQueryLogServer) mustEmbedUnimplementedQueryLogServer() {}
+
+// UnsafeQueryLogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QueryLogServer will
+// result in compilation errors.
+type UnsafeQueryLogServer interface {
+	mustEmbedUnimplementedQueryLogServer()
+}
+
+func RegisterQueryLogServer(s grpc.ServiceRegistrar, srv QueryLogServer) {
+	s.RegisterService(&QueryLog_ServiceDesc, srv)
+}
+
+func _QueryLog_AppendQueryLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendQueryLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryLogServer).AppendQueryLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/resolver.QueryLog/AppendQueryLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryLogServer).AppendQueryLog(ctx, req.(*AppendQueryLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// QueryLog_ServiceDesc is the grpc.ServiceDesc for QueryLog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QueryLog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "resolver.QueryLog",
+	HandlerType: (*QueryLogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AppendQueryLog",
+			Handler:    _QueryLog_AppendQueryLog_Handler,
+		},
+	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "resolver.proto",
 }
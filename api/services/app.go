@@ -2,31 +2,37 @@ package services
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/sneakybugs/corewarden/api/services/auth"
 	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/filterrules"
 	"github.com/sneakybugs/corewarden/api/services/grpc"
 	"github.com/sneakybugs/corewarden/api/services/health"
 	"github.com/sneakybugs/corewarden/api/services/logger"
+	"github.com/sneakybugs/corewarden/api/services/querylog"
 	"github.com/sneakybugs/corewarden/api/services/records"
 	"github.com/sneakybugs/corewarden/api/services/resolver"
 	"github.com/sneakybugs/corewarden/api/services/rest"
 	"github.com/sneakybugs/corewarden/api/services/storage"
 	"github.com/sneakybugs/corewarden/api/services/telemetry"
+	"github.com/sneakybugs/corewarden/api/services/zones"
 	"go.uber.org/fx"
 )
 
 type Options struct {
-	GRPCPort         uint16
-	HTTPPort         uint16
-	PostgresDatabase string
-	PostgresHost     string
-	PostgresPassword string
-	PostgresPort     uint16
-	PostgresUser     string
-	PolicyFile       string
-	ServiceAccounts  []auth.ServiceAccount
-	Verbose          bool
+	GRPCPort          uint16
+	HTTPPort          uint16
+	PostgresDatabase  string
+	PostgresHost      string
+	PostgresPassword  string
+	PostgresPort      uint16
+	PostgresUser      string
+	PolicyFile        string
+	QueryLogRetention time.Duration
+	RecordCache       bool
+	ServiceAccounts   []auth.ServiceAccount
+	Verbose           bool
 }
 
 func NewApp(options Options) *fx.App {
@@ -47,6 +53,7 @@ func NewApp(options Options) *fx.App {
 					options.PostgresPort,
 					options.PostgresDatabase,
 				),
+				CacheRecords: options.RecordCache,
 			},
 			enforcer.CasbinEnforcerOptions{
 				PolicyFile: options.PolicyFile,
@@ -57,6 +64,9 @@ func NewApp(options Options) *fx.App {
 			logger.Options{
 				DevelopmentMode: options.Verbose,
 			},
+			querylog.Options{
+				Retention: options.QueryLogRetention,
+			},
 		),
 		fx.Provide(
 			grpc.NewListener,
@@ -74,6 +84,12 @@ func NewApp(options Options) *fx.App {
 			telemetry.Register,
 			resolver.Register,
 			records.Register,
+			zones.Register,
+			filterrules.Register,
+			filterrules.RegisterFilterServer,
+			querylog.Register,
+			querylog.RegisterQueryLogServer,
+			querylog.RegisterRetention,
 			health.Register,
 		),
 		fx.WithLogger(
@@ -0,0 +1,271 @@
+package filterrules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+type FilterRulesStorage interface {
+	CreateFilterRule(ctx context.Context, p storage.FilterRuleCreateParameters) (storage.FilterRule, error)
+	ReadFilterRule(ctx context.Context, id int) (storage.FilterRule, error)
+	UpdateFilterRule(ctx context.Context, p storage.FilterRuleUpdateParameters) (storage.FilterRule, error)
+	DeleteFilterRule(ctx context.Context, id int) (storage.FilterRule, error)
+	ListFilterRules(ctx context.Context) ([]storage.FilterRule, error)
+	CreateFilterPause(ctx context.Context, p storage.FilterPauseCreateParameters) (storage.FilterPause, error)
+	EndFilterPause(ctx context.Context, p storage.FilterPauseEndParameters) (storage.FilterPause, error)
+	ListActiveFilterPauses(ctx context.Context) ([]storage.FilterPause, error)
+}
+
+func (s service) HandleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+		data := &FilterRuleRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		rule, err := s.handler.CreateFilterRule(r.Context(), storage.FilterRuleCreateParameters{
+			Rule:    data.Rule,
+			Group:   data.Group,
+			Comment: data.Comment,
+		})
+		if err != nil {
+			s.logger.Error("failed to create filter rule", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info(
+			"filter rule created",
+			zap.String("rule", rule.Rule),
+			zap.String("group", rule.Group),
+			zap.String("comment", rule.Comment),
+		)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, newFilterRuleResponse(rule))
+	}
+}
+
+type FilterRuleRequest struct {
+	Rule string `json:"rule"`
+	// Empty applies the rule to all client groups.
+	Group   string `json:"group,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func (fr *FilterRuleRequest) Bind(r *http.Request) error {
+	fieldErrors := []rest.KeyError{}
+	fr.Rule = strings.TrimSpace(fr.Rule)
+	if fr.Rule == "" {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "rule",
+			Message: "required",
+		})
+	} else if message, ok := validateRule(fr.Rule); !ok {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "rule",
+			Message: message,
+		})
+	}
+	if strings.ContainsAny(fr.Group, " \t\r\n{}") {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "group",
+			Message: "must be a Corefile group name",
+		})
+	}
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
+
+// Rules are parsed like the filterlist plugin parses list lines, returns the
+// error message when the rule is invalid.
+func validateRule(rule string) (string, bool) {
+	if strings.ContainsAny(rule, "\r\n") {
+		return "must be a single line", false
+	}
+	parsed, err := rules.NewRule(rule, 0)
+	if err != nil {
+		return err.Error(), false
+	}
+	if parsed == nil {
+		return "must not be a comment", false
+	}
+	if _, ok := parsed.(*rules.CosmeticRule); ok {
+		return "cosmetic rules are not supported", false
+	}
+	return "", true
+}
+
+func (s service) HandleRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+		id, ok := s.idParam(w, r)
+		if !ok {
+			return
+		}
+		rule, err := s.handler.ReadFilterRule(r.Context(), id)
+		if err != nil {
+			s.logger.Error("failed to read filter rule", zap.Error(err))
+			if errors.Is(err, storage.ErrFilterRuleNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newFilterRuleResponse(rule))
+	}
+}
+
+func (s service) HandleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+		id, ok := s.idParam(w, r)
+		if !ok {
+			return
+		}
+		data := &FilterRuleRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		rule, err := s.handler.UpdateFilterRule(r.Context(), storage.FilterRuleUpdateParameters{
+			ID:      id,
+			Rule:    data.Rule,
+			Group:   data.Group,
+			Comment: data.Comment,
+		})
+		if err != nil {
+			s.logger.Error("failed updating filter rule", zap.Error(err))
+			if errors.Is(err, storage.ErrFilterRuleNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newFilterRuleResponse(rule))
+	}
+}
+
+func (s service) HandleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+		id, ok := s.idParam(w, r)
+		if !ok {
+			return
+		}
+		rule, err := s.handler.DeleteFilterRule(r.Context(), id)
+		if err != nil {
+			s.logger.Error("failed deleting filter rule", zap.Error(err))
+			if errors.Is(err, storage.ErrFilterRuleNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newFilterRuleResponse(rule))
+	}
+}
+
+func (s service) HandleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(w, r) {
+			return
+		}
+		filterRules, err := s.handler.ListFilterRules(r.Context())
+		if err != nil {
+			s.logger.Error("failed to list filter rules", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		response := make([]FilterRuleResponse, len(filterRules))
+		for i, rule := range filterRules {
+			response[i] = newFilterRuleResponse(rule)
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, response)
+	}
+}
+
+// Enforces authorization on the request.
+// Renders an error response and returns false when the request must not proceed.
+func (s service) authorize(w http.ResponseWriter, r *http.Request) bool {
+	return s.authorizeObject(w, r, s.enforcer)
+}
+
+func (s service) authorizeObject(w http.ResponseWriter, r *http.Request, e enforcer.RequestEnforcer) bool {
+	ok, err := e.IsAuthorized(r, enforcedZone)
+	if err != nil {
+		s.logger.Error("failed to enforce action", zap.Error(err))
+		rest.RenderError(w, r, &rest.InternalServerError)
+		return false
+	}
+	if !ok {
+		s.logger.Error("unauthorized")
+		rest.RenderError(w, r, &rest.ForbiddenError)
+		return false
+	}
+	return true
+}
+
+// Reads the id URL parameter.
+// Renders an error response and returns false when the request must not proceed.
+func (s service) idParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.logger.Error("failed parsing ID to int", zap.Error(err))
+		rest.RenderError(w, r, &rest.NotFoundError)
+		return 0, false
+	}
+	return id, true
+}
+
+type FilterRuleResponse struct {
+	ID        int       `json:"id"`
+	Rule      string    `json:"rule"`
+	Group     string    `json:"group,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedOn time.Time `json:"updatedOn"`
+}
+
+func newFilterRuleResponse(r storage.FilterRule) FilterRuleResponse {
+	return FilterRuleResponse{
+		ID:        r.ID,
+		Rule:      r.Rule,
+		Group:     r.Group,
+		Comment:   r.Comment,
+		CreatedAt: r.CreatedAt,
+		UpdatedOn: r.ModifiedOn,
+	}
+}
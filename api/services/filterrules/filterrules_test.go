@@ -0,0 +1,414 @@
+package filterrules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pb33f/libopenapi"
+	validator "github.com/pb33f/libopenapi-validator"
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/logger"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateFilterRule(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/filter-rules",
+		strings.NewReader(`{"rule": "@@||example.com^", "group": "kids", "comment": "test"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+	var response FilterRuleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Rule != "@@||example.com^" {
+		t.Errorf("Expected rule to be '@@||example.com^', got '%s'", response.Rule)
+	}
+	if response.Group != "kids" {
+		t.Errorf("Expected group to be 'kids', got '%s'", response.Group)
+	}
+}
+
+func TestCreateFilterRuleBadRequest(t *testing.T) {
+	h := createTestHandler(nil)
+	for _, body := range []string{
+		`{}`,
+		`{"rule": "! comment"}`,
+		`{"rule": "example.com##.ad"}`,
+		`{"rule": "||example.com^\n||example.net^"}`,
+		`{"rule": "||example.com^$unknown"}`,
+		`{"rule": "||example.com^", "group": "two words"}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/filter-rules", strings.NewReader(body))
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: Expected status 400, got %d", body, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestCreateFilterRuleForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/filter-rules",
+		strings.NewReader(`{"rule": "||example.com^"}`),
+	)
+	auth.MockLogin(r, "bob")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestReadFilterRule(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestFilterRule(t, h, "||example.com^")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/filter-rules/1", nil)
+	auth.MockLogin(r, "bob")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response FilterRuleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Rule != "||example.com^" {
+		t.Errorf("Expected rule to be '||example.com^', got '%s'", response.Rule)
+	}
+}
+
+func TestReadFilterRuleNotFound(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/filter-rules/1", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestUpdateFilterRule(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestFilterRule(t, h, "||example.com^")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPut,
+		"/v1/filter-rules/1",
+		strings.NewReader(`{"rule": "||example.net^$important"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response FilterRuleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Rule != "||example.net^$important" {
+		t.Errorf("Expected rule to be '||example.net^$important', got '%s'", response.Rule)
+	}
+}
+
+func TestDeleteFilterRule(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestFilterRule(t, h, "||example.com^")
+	for _, expectedStatus := range []int{http.StatusOK, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/v1/filter-rules/1", nil)
+		auth.MockLogin(r, "alice")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != expectedStatus {
+			t.Errorf("Expected status %d, got %d", expectedStatus, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestListFilterRules(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestFilterRule(t, h, "||example.com^")
+	createTestFilterRule(t, h, "@@||example.net^")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/filter-rules", nil)
+	auth.MockLogin(r, "bob")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response []FilterRuleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 2 {
+		t.Errorf("Expected 2 filter rules, got %d", len(response))
+	}
+}
+
+func TestListFilterRulesServerError(t *testing.T) {
+	h := createTestHandler(errors.New("test"))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/filter-rules", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestListFilterRulesGRPC(t *testing.T) {
+	s := storage.NewMockService(storage.MockStorageOptions{})
+	ctx := context.Background()
+	if _, err := s.CreateFilterRule(ctx, storage.FilterRuleCreateParameters{Rule: "||example.com^", Group: "kids"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := filterServer{handler: s, logger: zap.NewNop()}
+	res, err := server.ListFilterRules(ctx, &resolver.ListFilterRulesRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Rules) != 1 || res.Rules[0].Rule != "||example.com^" || res.Rules[0].Group != "kids" {
+		t.Errorf("Expected the created filter rule, got %v", res.Rules)
+	}
+
+	server.handler = storage.NewMockService(storage.MockStorageOptions{ReturnError: errors.New("test")})
+	if _, err := server.ListFilterRules(ctx, &resolver.ListFilterRulesRequest{}); status.Convert(err).Code() != codes.Internal {
+		t.Errorf("Expected Internal error, got %v", err)
+	}
+}
+
+func TestFilterPauses(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/filter-pauses",
+		strings.NewReader(`{"group": "kids", "duration": "10m", "comment": "broken site"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+	var created FilterPauseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Group != "kids" || created.CreatedBy != "alice" {
+		t.Errorf("Expected a pause of kids created by alice, got %v", created)
+	}
+	if until := time.Until(created.Until); until < 9*time.Minute || 10*time.Minute < until {
+		t.Errorf("Expected the pause to end in 10 minutes, got %v", until)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/filter-pauses", nil)
+	auth.MockLogin(r, "bob")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	var pauses []FilterPauseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &pauses); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(pauses) != 1 {
+		t.Fatalf("Expected 1 active pause, got %d", len(pauses))
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/filter-pauses/%d", created.ID), nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var ended FilterPauseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &ended); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ended.EndedBy != "alice" {
+		t.Errorf("Expected the pause to be ended by alice, got %v", ended)
+	}
+
+	// Ended pauses are not active.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/filter-pauses/%d", created.ID), nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestCreateFilterPauseBadRequest(t *testing.T) {
+	h := createTestHandler(nil)
+	for _, body := range []string{
+		`{}`,
+		`{"duration": "soon"}`,
+		`{"duration": "-10m"}`,
+		`{"duration": "48h"}`,
+		`{"duration": "10m", "group": "two words"}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/filter-pauses", strings.NewReader(body))
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: Expected status 400, got %d", body, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestCreateFilterPauseForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/filter-pauses", strings.NewReader(`{"duration": "10m"}`))
+	auth.MockLogin(r, "bob")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestListFilterPausesGRPC(t *testing.T) {
+	s := storage.NewMockService(storage.MockStorageOptions{})
+	ctx := context.Background()
+	until := time.Now().Add(time.Hour)
+	if _, err := s.CreateFilterPause(ctx, storage.FilterPauseCreateParameters{Until: until, CreatedBy: "alice"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := filterServer{handler: s, logger: zap.NewNop()}
+	res, err := server.ListFilterPauses(ctx, &resolver.ListFilterPausesRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Pauses) != 1 || res.Pauses[0].Group != "" || res.Pauses[0].Until != until.Unix() {
+		t.Errorf("Expected the created pause of all groups, got %v", res.Pauses)
+	}
+
+	server.handler = storage.NewMockService(storage.MockStorageOptions{ReturnError: errors.New("test")})
+	if _, err := server.ListFilterPauses(ctx, &resolver.ListFilterPausesRequest{}); status.Convert(err).Code() != codes.Internal {
+		t.Errorf("Expected Internal error, got %v", err)
+	}
+}
+
+func createTestFilterRule(t *testing.T, h http.Handler, rule string) {
+	w := httptest.NewRecorder()
+	body, err := json.Marshal(FilterRuleRequest{Rule: rule})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/v1/filter-rules", strings.NewReader(string(body)))
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+}
+
+func createTestHandler(returnError error) http.Handler {
+	var handler *chi.Mux
+	_ = fx.New(
+		fx.Supply(
+			storage.MockStorageOptions{
+				ReturnError: returnError,
+			},
+			enforcer.CasbinEnforcerOptions{
+				PolicyFile: "test_policy.csv",
+			},
+			logger.Options{
+				DevelopmentMode: true,
+			},
+		),
+		fx.Provide(
+			logger.NewService,
+			rest.NewMockService,
+			storage.NewMockService,
+			enforcer.NewCasbinEnforcer,
+			auth.NewMockAuthenticator,
+			auth.NewService,
+		),
+		fx.Invoke(
+			Register,
+		),
+		fx.Populate(
+			&handler,
+		),
+		fx.NopLogger,
+	)
+	return handler
+}
+
+var docValidator validator.Validator
+
+func validateResponseBody(t *testing.T, r *http.Request, w *http.Response) {
+	if docValidator == nil {
+		apiSpec, err := os.ReadFile("../../openapi.yaml")
+		if err != nil {
+			t.Fatalf("Failed reading OpenAPI spec: %v\n", err)
+		}
+		document, err := libopenapi.NewDocument(apiSpec)
+		if err != nil {
+			t.Fatalf("Failed to parse OpenAPI spec: %v\n", err)
+		}
+		validator, validationErrs := validator.NewValidator(document)
+		if validationErrs != nil {
+			t.Fatalf("Failed to create validator: %v\n", validationErrs)
+		}
+		docValidator = validator
+	}
+	valid, errs := docValidator.ValidateHttpResponse(r, w)
+	if !valid {
+		t.Fatalf("Request body failed OpenAPI spec validation: %v", errs)
+	}
+}
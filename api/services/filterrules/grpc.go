@@ -0,0 +1,52 @@
+package filterrules
+
+import (
+	"context"
+
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type filterServer struct {
+	resolver.UnimplementedFilterServer
+	handler FilterRulesStorage
+	logger  *zap.Logger
+}
+
+func (s *filterServer) ListFilterRules(ctx context.Context, _ *resolver.ListFilterRulesRequest) (*resolver.ListFilterRulesResponse, error) {
+	filterRules, err := s.handler.ListFilterRules(ctx)
+	if err != nil {
+		s.logger.Error("failed to list filter rules", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	response := &resolver.ListFilterRulesResponse{
+		Rules: make([]*resolver.FilterRule, len(filterRules)),
+	}
+	for i, rule := range filterRules {
+		response.Rules[i] = &resolver.FilterRule{
+			Rule:  rule.Rule,
+			Group: rule.Group,
+		}
+	}
+	return response, nil
+}
+
+func (s *filterServer) ListFilterPauses(ctx context.Context, _ *resolver.ListFilterPausesRequest) (*resolver.ListFilterPausesResponse, error) {
+	pauses, err := s.handler.ListActiveFilterPauses(ctx)
+	if err != nil {
+		s.logger.Error("failed to list filter pauses", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	response := &resolver.ListFilterPausesResponse{
+		Pauses: make([]*resolver.FilterPause, len(pauses)),
+	}
+	for i, pause := range pauses {
+		response.Pauses[i] = &resolver.FilterPause{
+			Group: pause.Group,
+			Until: pause.Until.Unix(),
+		}
+	}
+	return response, nil
+}
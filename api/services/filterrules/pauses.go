@@ -0,0 +1,172 @@
+package filterrules
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+// Longer pauses are rejected, so that blocking is not left off by mistake.
+const maxPauseDuration = 24 * time.Hour
+
+func (s service) HandleCreatePause() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorizeObject(w, r, s.pauseEnforcer) {
+			return
+		}
+		data := &FilterPauseRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		subject, _ := auth.GetSubject(r.Context())
+		pause, err := s.handler.CreateFilterPause(r.Context(), storage.FilterPauseCreateParameters{
+			Group:     data.Group,
+			Until:     time.Now().Add(data.duration),
+			CreatedBy: subject,
+			Comment:   data.Comment,
+		})
+		if err != nil {
+			s.logger.Error("failed to create filter pause", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info(
+			"filter pause created",
+			zap.Int("id", pause.ID),
+			zap.String("group", pause.Group),
+			zap.Time("until", pause.Until),
+			zap.String("subject", subject),
+			zap.String("comment", pause.Comment),
+		)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, newFilterPauseResponse(pause))
+	}
+}
+
+type FilterPauseRequest struct {
+	// Empty pauses blocking for all client groups.
+	Group string `json:"group,omitempty"`
+	// Go duration string, such as "10m".
+	Duration string `json:"duration"`
+	Comment  string `json:"comment,omitempty"`
+	duration time.Duration
+}
+
+func (fp *FilterPauseRequest) Bind(r *http.Request) error {
+	fieldErrors := []rest.KeyError{}
+	if strings.ContainsAny(fp.Group, " \t\r\n{}") {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "group",
+			Message: "must be a Corefile group name",
+		})
+	}
+	if fp.Duration == "" {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "duration",
+			Message: "required",
+		})
+	} else {
+		var err error
+		fp.duration, err = time.ParseDuration(fp.Duration)
+		if err != nil || fp.duration <= 0 || maxPauseDuration < fp.duration {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     "duration",
+				Message: fmt.Sprintf("must be a positive duration up to %s", maxPauseDuration),
+			})
+		}
+	}
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
+
+// Ends the pause early, the ended pause is kept for auditing.
+func (s service) HandleEndPause() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorizeObject(w, r, s.pauseEnforcer) {
+			return
+		}
+		id, ok := s.idParam(w, r)
+		if !ok {
+			return
+		}
+		subject, _ := auth.GetSubject(r.Context())
+		pause, err := s.handler.EndFilterPause(r.Context(), storage.FilterPauseEndParameters{
+			ID:      id,
+			EndedBy: subject,
+		})
+		if err != nil {
+			s.logger.Error("failed ending filter pause", zap.Error(err))
+			if errors.Is(err, storage.ErrFilterPauseNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info(
+			"filter pause ended",
+			zap.Int("id", pause.ID),
+			zap.String("group", pause.Group),
+			zap.String("subject", subject),
+		)
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newFilterPauseResponse(pause))
+	}
+}
+
+// Lists the active pauses.
+func (s service) HandleListPauses() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorizeObject(w, r, s.pauseEnforcer) {
+			return
+		}
+		pauses, err := s.handler.ListActiveFilterPauses(r.Context())
+		if err != nil {
+			s.logger.Error("failed to list filter pauses", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		response := make([]FilterPauseResponse, len(pauses))
+		for i, pause := range pauses {
+			response[i] = newFilterPauseResponse(pause)
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, response)
+	}
+}
+
+type FilterPauseResponse struct {
+	ID        int       `json:"id"`
+	Group     string    `json:"group,omitempty"`
+	Until     time.Time `json:"until"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	EndedBy   string    `json:"endedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+func newFilterPauseResponse(p storage.FilterPause) FilterPauseResponse {
+	return FilterPauseResponse{
+		ID:        p.ID,
+		Group:     p.Group,
+		Until:     p.Until,
+		CreatedBy: p.CreatedBy,
+		CreatedAt: p.CreatedAt,
+		EndedBy:   p.EndedBy,
+		Comment:   p.Comment,
+	}
+}
@@ -0,0 +1,50 @@
+package filterrules
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Filter rules are not scoped to zones, authorization is enforced on the root
+// zone.
+const enforcedZone = "."
+
+type service struct {
+	enforcer      enforcer.RequestEnforcer
+	pauseEnforcer enforcer.RequestEnforcer
+	handler       FilterRulesStorage
+	logger        *zap.Logger
+}
+
+func Register(r *chi.Mux, e enforcer.Enforcer, s storage.Storage, l *zap.Logger, a auth.Service) {
+	sf := service{
+		enforcer:      enforcer.NewRequestEnforcer(e, "filterrules"),
+		pauseEnforcer: enforcer.NewRequestEnforcer(e, "filterpauses"),
+		handler:       s,
+		logger:        l,
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(a.Middleware())
+		r.Get("/v1/filter-rules", sf.HandleList())
+		r.Post("/v1/filter-rules", sf.HandleCreate())
+		r.Get("/v1/filter-rules/{id}", sf.HandleRead())
+		r.Put("/v1/filter-rules/{id}", sf.HandleUpdate())
+		r.Delete("/v1/filter-rules/{id}", sf.HandleDelete())
+		r.Get("/v1/filter-pauses", sf.HandleListPauses())
+		r.Post("/v1/filter-pauses", sf.HandleCreatePause())
+		r.Delete("/v1/filter-pauses/{id}", sf.HandleEndPause())
+	})
+}
+
+// Registers the Filter gRPC service used by the filterlist CoreDNS plugin.
+func RegisterFilterServer(e *grpc.Server, s storage.Storage, l *zap.Logger) {
+	resolver.RegisterFilterServer(e, &filterServer{
+		handler: s,
+		logger:  l,
+	})
+}
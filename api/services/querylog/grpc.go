@@ -0,0 +1,47 @@
+package querylog
+
+import (
+	"context"
+	"time"
+
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Larger batches are rejected, so that a single request cannot hold a large
+// insert.
+const maxBatchSize = 10000
+
+type queryLogServer struct {
+	resolver.UnimplementedQueryLogServer
+	handler QueryLogStorage
+	logger  *zap.Logger
+}
+
+func (s *queryLogServer) AppendQueryLog(ctx context.Context, req *resolver.AppendQueryLogRequest) (*resolver.AppendQueryLogResponse, error) {
+	if maxBatchSize < len(req.Events) {
+		return nil, status.Errorf(codes.InvalidArgument, "batch must have at most %d events", maxBatchSize)
+	}
+	entries := make([]storage.QueryLogEntry, len(req.Events))
+	for i, event := range req.Events {
+		entries[i] = storage.QueryLogEntry{
+			Time:      time.UnixMicro(event.Time),
+			Client:    event.Client,
+			ClientID:  event.ClientId,
+			Group:     event.Group,
+			Name:      event.Name,
+			Type:      uint16(event.Qtype),
+			Rcode:     int(event.Rcode),
+			Blocklist: event.Blocklist,
+			Duration:  time.Duration(event.Duration) * time.Microsecond,
+		}
+	}
+	if err := s.handler.AppendQueryLog(ctx, entries); err != nil {
+		s.logger.Error("failed to append query log", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return &resolver.AppendQueryLogResponse{}, nil
+}
@@ -0,0 +1,182 @@
+package querylog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+type QueryLogStorage interface {
+	AppendQueryLog(ctx context.Context, entries []storage.QueryLogEntry) error
+	ListQueryLog(ctx context.Context, p storage.QueryLogListParameters) (storage.QueryLogPage, error)
+	DeleteQueryLogBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+const defaultPageSize = 100
+const maxPageSize = 1000
+
+func (s service) HandleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, err := s.enforcer.IsAuthorized(r, enforcedZone)
+		if err != nil {
+			s.logger.Error("failed to enforce action", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		if !ok {
+			s.logger.Error("unauthorized")
+			rest.RenderError(w, r, &rest.ForbiddenError)
+			return
+		}
+
+		params, err := parseListParams(r)
+		if err != nil {
+			s.logger.Error("invalid list parameters", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		page, err := s.handler.ListQueryLog(r.Context(), params)
+		if err != nil {
+			if errors.Is(err, storage.ErrInvalidCursor) {
+				s.logger.Error("invalid cursor", zap.Error(err))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Params: []rest.KeyError{
+						{
+							Key:     "cursor",
+							Message: "invalid cursor",
+						},
+					},
+				})
+				return
+			}
+			s.logger.Error("failed to list query log", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		entries := make([]QueryLogEntryResponse, len(page.Entries))
+		for i, entry := range page.Entries {
+			entries[i] = newQueryLogEntryResponse(entry)
+		}
+		if page.NextCursor != "" {
+			w.Header().Set(rest.NextCursorHeader, page.NextCursor)
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, entries)
+	}
+}
+
+func parseListParams(r *http.Request) (storage.QueryLogListParameters, error) {
+	query := r.URL.Query()
+	paramErrors := []rest.KeyError{}
+	params := storage.QueryLogListParameters{
+		Client:    query.Get("client"),
+		Name:      query.Get("name"),
+		Blocklist: query.Get("blocklist"),
+		PageSize:  defaultPageSize,
+		Cursor:    query.Get("cursor"),
+	}
+	if params.Name != "" && !dns.IsFqdn(params.Name) {
+		paramErrors = append(paramErrors, rest.KeyError{
+			Key:     "name",
+			Message: "must be FQDN",
+		})
+	}
+	if qtype := query.Get("type"); qtype != "" {
+		var ok bool
+		params.Type, ok = dns.StringToType[strings.ToUpper(qtype)]
+		if !ok {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "type",
+				Message: "unknown record type",
+			})
+		}
+	}
+	if rcode := query.Get("rcode"); rcode != "" {
+		value, ok := dns.StringToRcode[strings.ToUpper(rcode)]
+		if !ok {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "rcode",
+				Message: "unknown rcode",
+			})
+		}
+		params.Rcode = &value
+	}
+	for _, bound := range []struct {
+		key   string
+		value *time.Time
+	}{
+		{key: "since", value: &params.Since},
+		{key: "until", value: &params.Until},
+	} {
+		if query.Get(bound.key) == "" {
+			continue
+		}
+		var err error
+		*bound.value, err = time.Parse(time.RFC3339, query.Get(bound.key))
+		if err != nil {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     bound.key,
+				Message: "must be an RFC 3339 timestamp",
+			})
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		params.PageSize, err = strconv.Atoi(limit)
+		if err != nil || params.PageSize < 1 || maxPageSize < params.PageSize {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "limit",
+				Message: fmt.Sprintf("must be an integer between 1 and %d", maxPageSize),
+			})
+		}
+	}
+	if 0 < len(paramErrors) {
+		return storage.QueryLogListParameters{}, &rest.BadRequestErrorResponse{
+			Params: paramErrors,
+		}
+	}
+	return params, nil
+}
+
+type QueryLogEntryResponse struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Client    string    `json:"client"`
+	ClientID  string    `json:"clientId,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Rcode     string    `json:"rcode"`
+	Blocklist string    `json:"blocklist,omitempty"`
+	// Microseconds.
+	Duration int64 `json:"duration"`
+}
+
+func newQueryLogEntryResponse(entry storage.QueryLogEntry) QueryLogEntryResponse {
+	rcode, ok := dns.RcodeToString[entry.Rcode]
+	if !ok {
+		rcode = strconv.Itoa(entry.Rcode)
+	}
+	return QueryLogEntryResponse{
+		ID:        entry.ID,
+		Time:      entry.Time,
+		Client:    entry.Client,
+		ClientID:  entry.ClientID,
+		Group:     entry.Group,
+		Name:      entry.Name,
+		Type:      dns.Type(entry.Type).String(),
+		Rcode:     rcode,
+		Blocklist: entry.Blocklist,
+		Duration:  entry.Duration.Microseconds(),
+	}
+}
@@ -0,0 +1,275 @@
+package querylog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/miekg/dns"
+	"github.com/pb33f/libopenapi"
+	validator "github.com/pb33f/libopenapi-validator"
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/logger"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testTime = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func TestListQueryLog(t *testing.T) {
+	h := createTestHandler(t, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/querylog?limit=2", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response []QueryLogEntryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(response))
+	}
+	if response[0].Name != "example.net." || response[0].Rcode != "NXDOMAIN" || response[0].Type != "AAAA" {
+		t.Errorf("Expected the newest entry first, got %v", response[0])
+	}
+	cursor := w.Result().Header.Get(rest.NextCursorHeader)
+	if cursor == "" {
+		t.Fatalf("Expected a next cursor")
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/querylog?limit=2&cursor="+cursor, nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 1 || w.Result().Header.Get(rest.NextCursorHeader) != "" {
+		t.Errorf("Expected the last entry without a next cursor, got %v", response)
+	}
+}
+
+func TestListQueryLogFilters(t *testing.T) {
+	h := createTestHandler(t, nil)
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{query: "client=192.0.2.1", expected: 2},
+		{query: "client=tablet", expected: 1},
+		{query: "name=ads.example.com.", expected: 1},
+		{query: "type=aaaa", expected: 1},
+		{query: "rcode=NOERROR", expected: 2},
+		{query: "blocklist=local", expected: 1},
+		{query: "since=2026-10-18T11:59:00Z", expected: 2},
+		{query: "until=2026-10-18T11:59:00Z", expected: 1},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/querylog?"+test.query, nil)
+		auth.MockLogin(r, "alice")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		var response []QueryLogEntryResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: Expected no error, got %v", test.query, err)
+		}
+		if len(response) != test.expected {
+			t.Errorf("%s: Expected %d entries, got %d", test.query, test.expected, len(response))
+		}
+	}
+}
+
+func TestListQueryLogBadRequest(t *testing.T) {
+	h := createTestHandler(t, nil)
+	for _, query := range []string{
+		"name=example.com",
+		"type=FOO",
+		"rcode=FOO",
+		"since=yesterday",
+		"limit=0",
+		"cursor=foo",
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/querylog?"+query, nil)
+		auth.MockLogin(r, "alice")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: Expected status 400, got %d", query, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestListQueryLogForbidden(t *testing.T) {
+	h := createTestHandler(t, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/querylog", nil)
+	auth.MockLogin(r, "bob")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestListQueryLogServerError(t *testing.T) {
+	h := createTestHandler(t, errors.New("test"))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/querylog", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestAppendQueryLogGRPC(t *testing.T) {
+	s := storage.NewMockService(storage.MockStorageOptions{})
+	ctx := context.Background()
+	server := queryLogServer{handler: s, logger: zap.NewNop()}
+	_, err := server.AppendQueryLog(ctx, &resolver.AppendQueryLogRequest{
+		Events: []*resolver.QueryEvent{{
+			Time:      testTime.UnixMicro(),
+			Client:    "192.0.2.1",
+			ClientId:  "tablet",
+			Group:     "kids",
+			Name:      "ads.example.com.",
+			Qtype:     uint32(dns.TypeA),
+			Blocklist: "local",
+			Duration:  1500,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	page, err := s.ListQueryLog(ctx, storage.QueryLogListParameters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(page.Entries))
+	}
+	entry := page.Entries[0]
+	if !entry.Time.Equal(testTime) || entry.ClientID != "tablet" || entry.Blocklist != "local" || entry.Duration != 1500*time.Microsecond {
+		t.Errorf("Expected the appended event, got %v", entry)
+	}
+
+	if _, err = server.AppendQueryLog(ctx, &resolver.AppendQueryLogRequest{
+		Events: make([]*resolver.QueryEvent, maxBatchSize+1),
+	}); status.Convert(err).Code() != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument error, got %v", err)
+	}
+
+	server.handler = storage.NewMockService(storage.MockStorageOptions{ReturnError: errors.New("test")})
+	if _, err = server.AppendQueryLog(ctx, &resolver.AppendQueryLogRequest{}); status.Convert(err).Code() != codes.Internal {
+		t.Errorf("Expected Internal error, got %v", err)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	s := storage.NewMockService(storage.MockStorageOptions{})
+	ctx := context.Background()
+	now := time.Now()
+	if err := s.AppendQueryLog(ctx, []storage.QueryLogEntry{
+		{Time: now.Add(-48 * time.Hour), Name: "example.com."},
+		{Time: now, Name: "example.com."},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	deleteExpired(ctx, s, 24*time.Hour, zap.NewNop())
+	page, err := s.ListQueryLog(ctx, storage.QueryLogListParameters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(page.Entries) != 1 || !page.Entries[0].Time.Equal(now) {
+		t.Errorf("Expected only the recent entry, got %v", page.Entries)
+	}
+}
+
+func createTestHandler(t *testing.T, returnError error) http.Handler {
+	var handler *chi.Mux
+	var s storage.Storage
+	_ = fx.New(
+		fx.Supply(
+			storage.MockStorageOptions{
+				ReturnError: returnError,
+			},
+			enforcer.CasbinEnforcerOptions{
+				PolicyFile: "test_policy.csv",
+			},
+			logger.Options{
+				DevelopmentMode: true,
+			},
+		),
+		fx.Provide(
+			logger.NewService,
+			rest.NewMockService,
+			storage.NewMockService,
+			enforcer.NewCasbinEnforcer,
+			auth.NewMockAuthenticator,
+			auth.NewService,
+		),
+		fx.Invoke(
+			Register,
+		),
+		fx.Populate(
+			&handler,
+			&s,
+		),
+		fx.NopLogger,
+	)
+	if returnError == nil {
+		err := s.AppendQueryLog(context.Background(), []storage.QueryLogEntry{
+			{Time: testTime.Add(-2 * time.Minute), Client: "192.0.2.1", Name: "example.com.", Type: dns.TypeA},
+			{Time: testTime.Add(-time.Minute), Client: "192.0.2.1", ClientID: "tablet", Group: "kids", Name: "ads.example.com.", Type: dns.TypeA, Blocklist: "local"},
+			{Time: testTime, Client: "192.0.2.2", Name: "example.net.", Type: dns.TypeAAAA, Rcode: dns.RcodeNameError},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	return handler
+}
+
+var docValidator validator.Validator
+
+func validateResponseBody(t *testing.T, r *http.Request, w *http.Response) {
+	if docValidator == nil {
+		apiSpec, err := os.ReadFile("../../openapi.yaml")
+		if err != nil {
+			t.Fatalf("Failed reading OpenAPI spec: %v\n", err)
+		}
+		document, err := libopenapi.NewDocument(apiSpec)
+		if err != nil {
+			t.Fatalf("Failed to parse OpenAPI spec: %v\n", err)
+		}
+		validator, validationErrs := validator.NewValidator(document)
+		if validationErrs != nil {
+			t.Fatalf("Failed to create validator: %v\n", validationErrs)
+		}
+		docValidator = validator
+	}
+	valid, errs := docValidator.ValidateHttpResponse(r, w)
+	if !valid {
+		t.Fatalf("Request body failed OpenAPI spec validation: %v", errs)
+	}
+}
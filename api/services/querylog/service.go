@@ -0,0 +1,101 @@
+package querylog
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sneakybugs/corewarden/api/resolver"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// The query log is not scoped to zones, authorization is enforced on the root
+// zone.
+const enforcedZone = "."
+
+// How often entries older than the retention period are deleted.
+const retentionInterval = time.Hour
+
+type Options struct {
+	// Entries older than this are deleted, they are kept forever when zero.
+	Retention time.Duration
+}
+
+type service struct {
+	enforcer enforcer.RequestEnforcer
+	handler  QueryLogStorage
+	logger   *zap.Logger
+}
+
+func Register(r *chi.Mux, e enforcer.Enforcer, s storage.Storage, l *zap.Logger, a auth.Service) {
+	sq := service{
+		enforcer: enforcer.NewRequestEnforcer(e, "querylog"),
+		handler:  s,
+		logger:   l,
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(a.Middleware())
+		r.Get("/v1/querylog", sq.HandleList())
+	})
+}
+
+// Registers the QueryLog gRPC service the querylog CoreDNS plugin sends query
+// events to.
+func RegisterQueryLogServer(e *grpc.Server, s storage.Storage, l *zap.Logger) {
+	resolver.RegisterQueryLogServer(e, &queryLogServer{
+		handler: s,
+		logger:  l,
+	})
+}
+
+// Periodically deletes the entries older than the retention period.
+func RegisterRetention(lc fx.Lifecycle, o Options, s storage.Storage, l *zap.Logger) {
+	if o.Retention == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(retentionInterval)
+				defer ticker.Stop()
+				for {
+					deleteExpired(ctx, s, o.Retention, l)
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
+
+func deleteExpired(ctx context.Context, s QueryLogStorage, retention time.Duration, l *zap.Logger) {
+	deleted, err := s.DeleteQueryLogBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		l.Error("failed to delete expired query log entries", zap.Error(err))
+		return
+	}
+	if deleted != 0 {
+		l.Info("expired query log entries deleted", zap.Int("count", deleted))
+	}
+}
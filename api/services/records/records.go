@@ -3,8 +3,10 @@ package records
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -20,7 +22,8 @@ type RecordsStorage interface {
 	ReadRecord(ctx context.Context, id int) (storage.Record, error)
 	UpdateRecord(ctx context.Context, p storage.RecordUpdateParameters) (storage.Record, error)
 	DeleteRecord(ctx context.Context, id int) (storage.Record, error)
-	ListRecords(ctx context.Context, zone string) ([]storage.Record, error)
+	ListRecordsPage(ctx context.Context, p storage.RecordListParameters) (storage.RecordPage, error)
+	ApplyChangeset(ctx context.Context, operations []storage.ChangesetOperation, authorize storage.ChangesetAuthorizer) ([]storage.Record, error)
 }
 
 func (s service) HandleCreate() http.HandlerFunc {
@@ -60,6 +63,18 @@ func (s service) HandleCreate() http.HandlerFunc {
 				})
 				return
 			}
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				s.logger.Error("failed to create record because zone does not exist", zap.String("zone", data.Zone))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     "zone",
+							Message: "zone does not exist",
+						},
+					},
+				})
+				return
+			}
 			s.logger.Error("failed to create record", zap.Error(err))
 			rest.RenderError(w, r, &rest.InternalServerError)
 			return
@@ -239,6 +254,30 @@ func (s service) HandleUpdate() http.HandlerFunc {
 			Zone:    data.Zone,
 		})
 		if err != nil {
+			if errors.Is(err, storage.ErrCNAMEArgument) {
+				s.logger.Error("failed to update CNAME record because data exists in node", zap.String("zone", data.Zone), zap.String("rr", data.RR.String()))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     "content",
+							Message: "CNAME record must be the only record in the node",
+						},
+					},
+				})
+				return
+			}
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				s.logger.Error("failed to update record because zone does not exist", zap.String("zone", data.Zone))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     "zone",
+							Message: "zone does not exist",
+						},
+					},
+				})
+				return
+			}
 			s.logger.Error("failed updating record", zap.Error(err))
 			rest.RenderError(w, r, &rest.InternalServerError)
 			return
@@ -312,35 +351,21 @@ func (s service) HandleDelete() http.HandlerFunc {
 
 }
 
+// Page size when listing with a cursor but no limit. Records are only paginated
+// when either is given, so that listing the whole zone keeps working.
+const defaultPageSize = 100
+const maxPageSize = 1000
+
 func (s service) HandleList() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		zone := r.URL.Query().Get("zone")
-		if zone == "" {
-			s.logger.Error("missing zone GET parameter")
-			rest.RenderError(w, r, &rest.BadRequestErrorResponse{
-				Params: []rest.KeyError{
-					{
-						Key:     "zone",
-						Message: "required",
-					},
-				},
-			})
-			return
-		}
-		if !dns.IsFqdn(zone) {
-			s.logger.Error("zone is not FQDN")
-			rest.RenderError(w, r, &rest.BadRequestErrorResponse{
-				Params: []rest.KeyError{
-					{
-						Key:     "zone",
-						Message: "must be FQDN",
-					},
-				},
-			})
+		params, err := parseListParams(r)
+		if err != nil {
+			s.logger.Error("invalid list parameters", zap.Error(err))
+			rest.RenderError(w, r, err)
 			return
 		}
 
-		ok, err := s.enforcer.IsAuthorized(r, zone)
+		ok, err := s.enforcer.IsAuthorized(r, params.Zone)
 		if err != nil {
 			s.logger.Error("failed to enforce action", zap.Error(err))
 			rest.RenderError(w, r, &rest.InternalServerError)
@@ -352,14 +377,26 @@ func (s service) HandleList() http.HandlerFunc {
 			return
 		}
 
-		recs, err := s.handler.ListRecords(r.Context(), zone)
+		page, err := s.handler.ListRecordsPage(r.Context(), params)
 		if err != nil {
+			if errors.Is(err, storage.ErrInvalidCursor) {
+				s.logger.Error("invalid cursor", zap.Error(err))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Params: []rest.KeyError{
+						{
+							Key:     "cursor",
+							Message: "invalid cursor",
+						},
+					},
+				})
+				return
+			}
 			s.logger.Error("failed to list records", zap.Error(err))
 			rest.RenderError(w, r, &rest.InternalServerError)
 			return
 		}
-		records := make([]RecordResponse, len(recs))
-		for i, rec := range recs {
+		records := make([]RecordResponse, len(page.Records))
+		for i, rec := range page.Records {
 			records[i] = RecordResponse{
 				ID:        rec.ID,
 				Zone:      rec.Zone,
@@ -369,11 +406,101 @@ func (s service) HandleList() http.HandlerFunc {
 				UpdatedOn: rec.ModifiedOn,
 			}
 		}
+		if page.NextCursor != "" {
+			w.Header().Set(rest.NextCursorHeader, page.NextCursor)
+		}
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, records)
 	}
 }
 
+func parseListParams(r *http.Request) (storage.RecordListParameters, error) {
+	query := r.URL.Query()
+	paramErrors := []rest.KeyError{}
+	params := storage.RecordListParameters{
+		Zone:    query.Get("zone"),
+		Name:    query.Get("name"),
+		Comment: query.Get("comment"),
+		Sort:    storage.RecordSortID,
+		Cursor:  query.Get("cursor"),
+	}
+	if params.Cursor != "" {
+		params.PageSize = defaultPageSize
+	}
+	if params.Zone == "" {
+		paramErrors = append(paramErrors, rest.KeyError{
+			Key:     "zone",
+			Message: "required",
+		})
+	} else if !dns.IsFqdn(params.Zone) {
+		paramErrors = append(paramErrors, rest.KeyError{
+			Key:     "zone",
+			Message: "must be FQDN",
+		})
+	}
+	if params.Name != "" && !dns.IsFqdn(params.Name) {
+		paramErrors = append(paramErrors, rest.KeyError{
+			Key:     "name",
+			Message: "must be FQDN",
+		})
+	}
+	if rtype := query.Get("type"); rtype != "" {
+		var ok bool
+		params.Type, ok = dns.StringToType[strings.ToUpper(rtype)]
+		if !ok {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "type",
+				Message: "unknown record type",
+			})
+		}
+	}
+	if wildcard := query.Get("wildcard"); wildcard != "" {
+		isWildcard, err := strconv.ParseBool(wildcard)
+		if err != nil {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "wildcard",
+				Message: "must be 'true' or 'false'",
+			})
+		}
+		params.IsWildcard = &isWildcard
+	}
+	if modifiedSince := query.Get("modifiedSince"); modifiedSince != "" {
+		var err error
+		params.ModifiedSince, err = time.Parse(time.RFC3339, modifiedSince)
+		if err != nil {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "modifiedSince",
+				Message: "must be an RFC 3339 timestamp",
+			})
+		}
+	}
+	if sort := query.Get("sort"); sort != "" {
+		params.Sort = storage.RecordSort(sort)
+		if params.Sort != storage.RecordSortID && params.Sort != storage.RecordSortName {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "sort",
+				Message: "must be one of 'id' or 'name'",
+			})
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		params.PageSize, err = strconv.Atoi(limit)
+		if err != nil || params.PageSize < 1 || maxPageSize < params.PageSize {
+			paramErrors = append(paramErrors, rest.KeyError{
+				Key:     "limit",
+				Message: fmt.Sprintf("must be an integer between 1 and %d", maxPageSize),
+			})
+		}
+	}
+	if 0 < len(paramErrors) {
+		return storage.RecordListParameters{}, &rest.BadRequestErrorResponse{
+			Params: paramErrors,
+		}
+	}
+	return params, nil
+}
+
 type RecordResponse struct {
 	ID        int       `json:"id"`
 	Zone      string    `json:"zone"`
@@ -382,3 +509,214 @@ type RecordResponse struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedOn time.Time `json:"updatedOn"`
 }
+
+func (s service) HandleApplyChangeset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := &ChangesetRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+
+		operations := make([]storage.ChangesetOperation, len(data.Operations))
+		for i, op := range data.Operations {
+			// Enforce authorization on new zone.
+			if op.Action == storage.ChangesetCreate || op.Action == storage.ChangesetUpdate {
+				ok, err := s.enforcer.IsAuthorized(r, op.Zone)
+				if err != nil {
+					s.logger.Error("failed to enforce action", zap.Error(err))
+					rest.RenderError(w, r, &rest.InternalServerError)
+					return
+				}
+				if !ok {
+					s.logger.Error("unauthorized", zap.Int("operation", i))
+					rest.RenderError(w, r, &rest.ForbiddenError)
+					return
+				}
+			}
+
+			operations[i] = storage.ChangesetOperation{
+				Action:  op.Action,
+				ID:      op.ID,
+				Zone:    op.Zone,
+				Comment: op.Comment,
+			}
+			if op.RR != nil {
+				operations[i].RR = op.RR.String()
+			}
+		}
+
+		// Existing records are authorized within the changeset transaction, so
+		// that they cannot move to another zone after being authorized.
+		authorize := func(i int, existing storage.Record) error {
+			ok, err := s.enforcer.IsAuthorized(r, existing.Zone)
+			if err != nil {
+				return fmt.Errorf("failed to enforce action: %w", err)
+			}
+			if !ok {
+				// Unauthorized records are reported as not found, same as
+				// for single record operations.
+				s.logger.Error("unauthorized", zap.Int("operation", i), zap.Int("id", existing.ID))
+				return storage.ErrRecordNotFound
+			}
+			return nil
+		}
+		recs, err := s.handler.ApplyChangeset(r.Context(), operations, authorize)
+		if err != nil {
+			var opErr *storage.ChangesetOperationError
+			if errors.As(err, &opErr) && errors.Is(err, storage.ErrCNAMEArgument) {
+				s.logger.Error("failed to apply changeset because CNAME data exists in node", zap.Int("operation", opErr.Index))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     operationKey(opErr.Index, "content"),
+							Message: "CNAME record must be the only record in the node",
+						},
+					},
+				})
+				return
+			}
+			if errors.As(err, &opErr) && errors.Is(err, storage.ErrZoneNotFound) {
+				s.logger.Error("failed to apply changeset because zone does not exist", zap.Int("operation", opErr.Index))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     operationKey(opErr.Index, "zone"),
+							Message: "zone does not exist",
+						},
+					},
+				})
+				return
+			}
+			if errors.As(err, &opErr) && errors.Is(err, storage.ErrRecordNotFound) {
+				s.logger.Error("failed to apply changeset because record was not found", zap.Int("operation", opErr.Index))
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     operationKey(opErr.Index, "id"),
+							Message: "not found",
+						},
+					},
+				})
+				return
+			}
+			s.logger.Error("failed to apply changeset", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info(
+			"changeset applied",
+			zap.Int("operations", len(operations)),
+		)
+		records := make([]RecordResponse, len(recs))
+		for i, rec := range recs {
+			records[i] = RecordResponse{
+				ID:        rec.ID,
+				Zone:      rec.Zone,
+				Content:   rec.RR,
+				Comment:   rec.Comment,
+				CreatedAt: rec.CreatedAt,
+				UpdatedOn: rec.ModifiedOn,
+			}
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, records)
+	}
+}
+
+// Key of an operation field in bad request errors, for example "operations[0].zone".
+func operationKey(index int, field string) string {
+	return fmt.Sprintf("operations[%d].%s", index, field)
+}
+
+type ChangesetRequest struct {
+	Operations []ChangesetOperationRequest `json:"operations"`
+}
+
+type ChangesetOperationRequest struct {
+	Action  storage.ChangesetAction `json:"action"`
+	ID      int                     `json:"id,omitempty"`
+	Zone    string                  `json:"zone,omitempty"`
+	Content string                  `json:"content,omitempty"`
+	Comment string                  `json:"comment,omitempty"`
+	RR      dns.RR                  `json:"-"`
+}
+
+func (cr *ChangesetRequest) Bind(r *http.Request) error {
+	fieldErrors := []rest.KeyError{}
+	if len(cr.Operations) == 0 {
+		return &rest.BadRequestErrorResponse{
+			Fields: []rest.KeyError{
+				{
+					Key:     "operations",
+					Message: "required",
+				},
+			},
+		}
+	}
+	for i := range cr.Operations {
+		op := &cr.Operations[i]
+		switch op.Action {
+		case storage.ChangesetCreate, storage.ChangesetUpdate:
+		case storage.ChangesetDelete:
+			if op.ID == 0 {
+				fieldErrors = append(fieldErrors, rest.KeyError{
+					Key:     operationKey(i, "id"),
+					Message: "required",
+				})
+			}
+			continue
+		case "":
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "action"),
+				Message: "required",
+			})
+			continue
+		default:
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "action"),
+				Message: "must be one of 'create', 'update', or 'delete'",
+			})
+			continue
+		}
+		if op.Action == storage.ChangesetUpdate && op.ID == 0 {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "id"),
+				Message: "required",
+			})
+		}
+		if op.Zone == "" {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "zone"),
+				Message: "required",
+			})
+		} else if !dns.IsFqdn(op.Zone) {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "zone"),
+				Message: "must end with '.'",
+			})
+		}
+		if op.Content == "" {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "content"),
+				Message: "required",
+			})
+			continue
+		}
+		var err error
+		op.RR, err = dns.NewRR(op.Content)
+		if err != nil {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     operationKey(i, "content"),
+				Message: err.Error(),
+			})
+		}
+	}
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package records
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -50,6 +51,33 @@ func TestCreateRecord(t *testing.T) {
 	}
 }
 
+func TestCreateRecordZoneNotFound(t *testing.T) {
+	h := createTestHandler(storage.ErrZoneNotFound)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/records",
+		strings.NewReader(`{"zone": "example.com.", "content": "@ A 127.0.0.1", "comment": "test"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Fields) != 1 {
+		t.Fatalf("Expected fields to of length 1, got %d", len(response.Fields))
+	}
+	if response.Fields[0].Key != "zone" {
+		t.Errorf("Expected fields[0].key to be 'zone', got '%s'", response.Fields[0].Key)
+	}
+}
+
 func TestCreateRecordCNAMEExistingData(t *testing.T) {
 	h := createTestHandler(storage.ErrCNAMEArgument)
 	w := httptest.NewRecorder()
@@ -694,6 +722,213 @@ func TestListRecords(t *testing.T) {
 	validateResponseBody(t, r, w.Result())
 }
 
+func TestListRecordsPagination(t *testing.T) {
+	h := createTestHandler(nil)
+	for _, content := range []string{"c A 127.0.0.1", "a A 127.0.0.2", "b A 127.0.0.3"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/v1/records",
+			strings.NewReader(`{"zone": "example.com.", "content": "`+content+`"}`),
+		)
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		if w.Result().StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+		}
+	}
+
+	contents := []string{}
+	cursor := ""
+	for page := 0; page < 2; page++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodGet,
+			"/v1/records?zone=example.com.&sort=name&limit=2&cursor="+cursor,
+			nil,
+		)
+		auth.MockLogin(r, "alice")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+		}
+		var response []RecordResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, record := range response {
+			contents = append(contents, record.Content)
+		}
+		cursor = w.Result().Header.Get(rest.NextCursorHeader)
+	}
+	if cursor != "" {
+		t.Errorf("Expected no cursor after the last page, got '%s'", cursor)
+	}
+	expected := []string{
+		"a.\t3600\tIN\tA\t127.0.0.2",
+		"b.\t3600\tIN\tA\t127.0.0.3",
+		"c.\t3600\tIN\tA\t127.0.0.1",
+	}
+	if len(contents) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(contents))
+	}
+	for i := range expected {
+		if contents[i] != expected[i] {
+			t.Errorf("Expected record %d to be '%s', got '%s'", i, expected[i], contents[i])
+		}
+	}
+}
+
+func TestListRecordsUnpaginated(t *testing.T) {
+	h := createTestHandler(nil)
+	count := defaultPageSize + 1
+	for i := 0; i < count; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/v1/records",
+			strings.NewReader(fmt.Sprintf(`{"zone": "example.com.", "content": "host%d A 127.0.0.1"}`, i)),
+		)
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		if w.Result().StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+		}
+	}
+
+	// Without limit and cursor the whole zone is listed.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/records?zone=example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response []RecordResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != count {
+		t.Errorf("Expected %d records, got %d", count, len(response))
+	}
+	if cursor := w.Result().Header.Get(rest.NextCursorHeader); cursor != "" {
+		t.Errorf("Expected no cursor, got '%s'", cursor)
+	}
+}
+
+func TestListRecordsFilters(t *testing.T) {
+	h := createTestHandler(nil)
+	records := []struct {
+		content string
+		comment string
+	}{
+		{"foo A 127.0.0.1", "Managed by ExternalDNS"},
+		{"foo TXT hello", ""},
+		{"*.foo A 127.0.0.2", ""},
+		{"bar A 127.0.0.3", "managed by hand"},
+	}
+	for _, record := range records {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/v1/records",
+			strings.NewReader(`{"zone": "example.com.", "content": "`+record.content+`", "comment": "`+record.comment+`"}`),
+		)
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		if w.Result().StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+		}
+	}
+
+	tests := []struct {
+		query       string
+		expectedIDs []int
+	}{
+		{"name=foo.example.com.", []int{1, 2, 3}},
+		{"name=foo.example.com.&wildcard=false", []int{1, 2}},
+		{"wildcard=true", []int{3}},
+		{"type=a", []int{1, 3, 4}},
+		{"comment=MANAGED", []int{1, 4}},
+		{"modifiedSince=2000-01-01T00:00:00Z", []int{1, 2, 3, 4}},
+		{"modifiedSince=2999-01-01T00:00:00Z", []int{}},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodGet,
+			"/v1/records?zone=example.com.&"+test.query,
+			nil,
+		)
+		auth.MockLogin(r, "alice")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 for '%s', got %d", test.query, w.Result().StatusCode)
+		}
+		var response []RecordResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ids := []int{}
+		for _, record := range response {
+			ids = append(ids, record.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.expectedIDs) {
+			t.Errorf("Expected IDs %v for '%s', got %v", test.expectedIDs, test.query, ids)
+		}
+	}
+}
+
+func TestListRecordsInvalidParams(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodGet,
+		"/v1/records?zone=example.com.&name=foo&type=NOPE&wildcard=maybe&modifiedSince=yesterday&sort=content&limit=0",
+		nil,
+	)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedKeys := []string{"name", "type", "wildcard", "modifiedSince", "sort", "limit"}
+	if len(response.Params) != len(expectedKeys) {
+		t.Fatalf("Expected params to of length %d, got %d", len(expectedKeys), len(response.Params))
+	}
+	for i, key := range expectedKeys {
+		if response.Params[i].Key != key {
+			t.Errorf("Expected params[%d].key to be '%s', got '%s'", i, key, response.Params[i].Key)
+		}
+	}
+}
+
+func TestListRecordsInvalidCursor(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodGet,
+		"/v1/records?zone=example.com.&cursor=garbage",
+		nil,
+	)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+}
+
 func TestListRecordsEmpty(t *testing.T) {
 	h := createTestHandler(nil)
 	w := httptest.NewRecorder()
@@ -804,6 +1039,287 @@ func TestListRecordsForbidden(t *testing.T) {
 	}
 }
 
+func TestApplyChangeset(t *testing.T) {
+	h := createTestHandler(nil)
+	for _, content := range []string{"foo A 127.0.0.1", "bar A 127.0.0.2"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/v1/records",
+			strings.NewReader(`{"zone": "example.com.", "content": "`+content+`"}`),
+		)
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		if w.Result().StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [
+			{"action": "delete", "id": 1},
+			{"action": "update", "id": 2, "zone": "example.com.", "content": "bar A 127.0.0.3", "comment": "changed"},
+			{"action": "create", "zone": "example.com.", "content": "foo CNAME bar.example.com."}
+		]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response []RecordResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 3 {
+		t.Fatalf("Expected response length to be 3, got %d", len(response))
+	}
+	if response[0].ID != 1 {
+		t.Errorf("Expected response[0].id to be 1, got %d", response[0].ID)
+	}
+	if response[1].Comment != "changed" {
+		t.Errorf("Expected response[1].comment to be 'changed', got '%s'", response[1].Comment)
+	}
+	if response[2].Content != "foo.\t3600\tIN\tCNAME\tbar.example.com." {
+		t.Errorf("Expected response[2].content to be 'foo.\\t3600\\tIN\\tCNAME\\tbar.example.com.', got '%s'", response[2].Content)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/records?zone=example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	var listResponse []RecordResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &listResponse); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(listResponse) != 2 {
+		t.Errorf("Expected 2 records after changeset, got %d", len(listResponse))
+	}
+}
+
+func TestApplyChangesetRollback(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/records",
+		strings.NewReader(`{"zone": "example.com.", "content": "foo A 127.0.0.1"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+
+	// The second delete of the same record fails, rolling back the first one.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [
+			{"action": "delete", "id": 1},
+			{"action": "delete", "id": 1}
+		]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Fields) != 1 {
+		t.Fatalf("Expected fields to of length 1, got %d", len(response.Fields))
+	}
+	if response.Fields[0].Key != "operations[1].id" {
+		t.Errorf("Expected fields[0].key to be 'operations[1].id', got '%s'", response.Fields[0].Key)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/records/1", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("Expected record to still exist with status 200, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestApplyChangesetCNAMEExistingData(t *testing.T) {
+	h := createTestHandler(&storage.ChangesetOperationError{Index: 0, Err: storage.ErrCNAMEArgument})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [{"action": "create", "zone": "example.com.", "content": "@ CNAME example.net."}]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Fields) != 1 {
+		t.Fatalf("Expected fields to of length 1, got %d", len(response.Fields))
+	}
+	if response.Fields[0].Key != "operations[0].content" {
+		t.Errorf("Expected fields[0].key to be 'operations[0].content', got '%s'", response.Fields[0].Key)
+	}
+}
+
+func TestApplyChangesetInvalidOperations(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [
+			{"action": "rename"},
+			{"action": "delete"},
+			{"action": "create", "zone": "example.com", "content": "@ A 127.0.0.1"}
+		]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedKeys := []string{"operations[0].action", "operations[1].id", "operations[2].zone"}
+	if len(response.Fields) != len(expectedKeys) {
+		t.Fatalf("Expected fields to of length %d, got %d", len(expectedKeys), len(response.Fields))
+	}
+	for i, key := range expectedKeys {
+		if response.Fields[i].Key != key {
+			t.Errorf("Expected fields[%d].key to be '%s', got '%s'", i, key, response.Fields[i].Key)
+		}
+	}
+}
+
+func TestApplyChangesetForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [
+			{"action": "create", "zone": "example.com.", "content": "@ A 127.0.0.1"},
+			{"action": "create", "zone": "example.net.", "content": "@ A 127.0.0.1"}
+		]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestApplyChangesetForbiddenExistingRecord(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/records",
+		strings.NewReader(`{"zone": "example.net.", "content": "@ A 127.0.0.1"}`),
+	)
+	auth.MockLogin(r, "bob")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [{"action": "delete", "id": 1}]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestApplyChangesetForbiddenExistingRecordRollback(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/records",
+		strings.NewReader(`{"zone": "example.net.", "content": "@ A 127.0.0.1"}`),
+	)
+	auth.MockLogin(r, "bob")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+
+	// Existing records are authorized while the changeset is applied, earlier
+	// operations are rolled back.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(
+		http.MethodPost,
+		"/v1/changesets",
+		strings.NewReader(`{"operations": [
+			{"action": "create", "zone": "example.com.", "content": "@ A 127.0.0.1"},
+			{"action": "update", "id": 1, "zone": "example.com.", "content": "@ A 127.0.0.2"}
+		]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response.Fields) != 1 || response.Fields[0].Key != "operations[1].id" {
+		t.Errorf("Expected the id of operation 1 to be not found, got %v", response.Fields)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/records?zone=example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	var records []RecordResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected the created record to be rolled back, got %v", records)
+	}
+}
+
 func createTestHandler(returnError error) http.Handler {
 	var handler *chi.Mux
 	_ = fx.New(
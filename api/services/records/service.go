@@ -27,5 +27,6 @@ func Register(r *chi.Mux, e enforcer.Enforcer, s storage.Storage, l *zap.Logger,
 		r.Get("/v1/records/{id}", sr.HandleRead())
 		r.Put("/v1/records/{id}", sr.HandleUpdate())
 		r.Delete("/v1/records/{id}", sr.HandleDelete())
+		r.Post("/v1/changesets", sr.HandleApplyChangeset())
 	})
 }
@@ -8,4 +8,5 @@ import (
 
 type Resolver interface {
 	Resolve(ctx context.Context, q storage.DNSQuestion) (storage.DNSResponse, error)
+	Watch(ctx context.Context) (<-chan storage.RecordsChange, error)
 }
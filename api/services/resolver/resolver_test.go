@@ -73,7 +73,66 @@ func TestResolveServerError(t *testing.T) {
 	}
 }
 
+func TestWatch(t *testing.T) {
+	client, store, closer := createTestWatchClient(t, nil)
+	defer closer(context.Background())
+	_, err := store.CreateRecord(context.Background(), storage.RecordCreateParameters{
+		Zone: "example.com.",
+		RR:   "*.foo.\t3600\tIN\tA\t127.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("failed creating record: %v", err)
+	}
+
+	// Cancelled before closing, the server waits for open streams to stop.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Watch(ctx, &resolver.WatchRequest{})
+	if err != nil {
+		t.Fatalf("failed watch request: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed receiving snapshot: %v", err)
+	}
+	if !resp.Snapshot {
+		t.Fatalf("expected the first response to be a snapshot")
+	}
+	if len(resp.Nodes) != 1 {
+		t.Fatalf("expected nodes length 1, got %d", len(resp.Nodes))
+	}
+	node := resp.Nodes[0]
+	if node.Name != "foo.example.com." {
+		t.Errorf("expected node name 'foo.example.com.', got '%s'", node.Name)
+	}
+	if len(node.Records) != 1 {
+		t.Fatalf("expected records length 1, got %d", len(node.Records))
+	}
+	if !node.Records[0].Wildcard || node.Records[0].Type != uint32(dns.TypeA) {
+		t.Errorf("expected a wildcard A record, got %v", node.Records[0])
+	}
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	client, closer := createTestClient(t, storage.ErrWatchUnsupported)
+	defer closer(context.Background())
+
+	stream, err := client.Watch(context.Background(), &resolver.WatchRequest{})
+	if err != nil {
+		t.Fatalf("failed watch request: %v", err)
+	}
+	_, err = stream.Recv()
+	if c := status.Convert(err).Code(); c != codes.Unimplemented {
+		t.Fatalf("expected code %d, got %d", codes.Unimplemented, c)
+	}
+}
+
 func createTestClient(t *testing.T, returnError error) (resolver.ResolverClient, func(context.Context)) {
+	client, _, closer := createTestWatchClient(t, returnError)
+	return client, closer
+}
+
+func createTestWatchClient(t *testing.T, returnError error) (resolver.ResolverClient, storage.Storage, func(context.Context)) {
 	lis := bufconn.Listen(10 * 1024 * 1024)
 	var store storage.Storage
 	app := fx.New(
@@ -117,7 +176,7 @@ func createTestClient(t *testing.T, returnError error) (resolver.ResolverClient,
 		t.Fatalf("failed creating gRPC client: %v", err)
 	}
 
-	return resolver.NewResolverClient(conn), func(ctx context.Context) {
+	return resolver.NewResolverClient(conn), store, func(ctx context.Context) {
 		err := app.Stop(ctx)
 		if err != nil {
 			fmt.Printf("%v\n", err)
@@ -2,6 +2,8 @@ package resolver
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/sneakybugs/corewarden/api/resolver"
 	"github.com/sneakybugs/corewarden/api/services/storage"
@@ -11,6 +13,10 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// Clients consider their copy of the records stale after missing a few
+// heartbeats.
+const watchHeartbeatInterval = 15 * time.Second
+
 type service struct {
 	resolver.UnimplementedResolverServer
 	handler Resolver
@@ -44,11 +50,75 @@ func (s *service) Resolve(ctx context.Context, q *resolver.Question) (*resolver.
 	s.logger.Info(
 		"DNS request",
 		zap.String("name", q.Name),
-		zap.Bool("found", true),
+		zap.Bool("found", 0 < len(resp.Answer)),
+		zap.Bool("nxdomain", resp.NXDomain),
 	)
 	return &resolver.Response{
-		Answer: resp.Answer,
-		Ns:     resp.NS,
-		Extra:  resp.Extra,
+		Answer:   resp.Answer,
+		Ns:       resp.NS,
+		Extra:    resp.Extra,
+		Nxdomain: resp.NXDomain,
 	}, nil
 }
+
+func (s *service) Watch(_ *resolver.WatchRequest, stream resolver.Resolver_WatchServer) error {
+	changes, err := s.handler.Watch(stream.Context())
+	if err != nil {
+		if errors.Is(err, storage.ErrWatchUnsupported) {
+			return status.Error(codes.Unimplemented, "record cache is disabled")
+		}
+		s.logger.Error("watch error", zap.Error(err))
+		return status.Error(codes.Internal, "internal server error")
+	}
+	s.logger.Info("watch started")
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var response *resolver.WatchResponse
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				return status.Error(codes.Unavailable, "record changes interrupted")
+			}
+			response = watchResponse(change)
+		case <-heartbeat.C:
+			response = &resolver.WatchResponse{}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func watchResponse(change storage.RecordsChange) *resolver.WatchResponse {
+	nodes := make([]*resolver.Node, len(change.Nodes))
+	for i, node := range change.Nodes {
+		records := make([]*resolver.Record, len(node.Records))
+		for j, record := range node.Records {
+			records[j] = &resolver.Record{
+				Type:     uint32(record.Type),
+				Wildcard: record.Wildcard,
+				Content:  record.Content,
+			}
+		}
+		nodes[i] = &resolver.Node{
+			Name:    node.Name,
+			Records: records,
+		}
+	}
+	zones := make([]*resolver.Zone, len(change.Zones))
+	for i, zone := range change.Zones {
+		zones[i] = &resolver.Zone{
+			Name:          zone.Name,
+			Authoritative: zone.Authoritative,
+			Soa:           zone.SOA,
+		}
+	}
+	return &resolver.WatchResponse{
+		Snapshot: change.Snapshot,
+		Nodes:    nodes,
+		Zones:    zones,
+	}
+}
@@ -35,6 +35,11 @@ var MethodNotAllowedError = ErrorResponse{
 	Status:  http.StatusMethodNotAllowed,
 }
 
+var ConflictError = ErrorResponse{
+	Message: "conflict",
+	Status:  http.StatusConflict,
+}
+
 var UnauthorizedError = ErrorResponse{
 	Message: "unauthorized",
 	Status:  http.StatusUnauthorized,
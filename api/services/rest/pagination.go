@@ -0,0 +1,5 @@
+package rest
+
+// Header with the cursor of the next page of paginated lists, absent on the
+// last page.
+const NextCursorHeader = "X-Next-Cursor"
@@ -0,0 +1,465 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/database/queries"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// Notified with the node name of every changed record by the
+// notify_records_changed trigger.
+const recordsChangedChannel = "records_changed"
+
+// Notified with the name of every changed zone by the notify_zones_changed
+// trigger.
+const zonesChangedChannel = "zones_changed"
+
+// Delay before listening again after losing the notification connection.
+const cacheRetryInterval = 5 * time.Second
+
+// Changes buffered per watcher, watchers falling further behind are dropped.
+const watchBufferSize = 64
+
+// Serves Resolve from an in-memory trie of all records, other methods are
+// passed through to the wrapped Storage.
+// The trie is kept up to date with Postgres LISTEN/NOTIFY, so writes made
+// through any API replica are seen by all of them. Resolve falls back to the
+// wrapped Storage while the trie is not in sync.
+// Watchers receive a snapshot of the trie and the nodes refreshed after it.
+type CachedStorage struct {
+	Storage
+	pool    *pgxpool.Pool
+	queries *queries.Queries
+	logger  *zap.Logger
+	hits    metric.Int64Counter
+	misses  metric.Int64Counter
+
+	mu       sync.RWMutex
+	root     *cacheNode
+	zones    map[string]Zone
+	synced   bool
+	watchers map[chan RecordsChange]struct{}
+}
+
+func newCachedStorage(s *PostgresStorage, l *zap.Logger) (*CachedStorage, error) {
+	meter := otel.Meter("github.com/sneakybugs/corewarden/api/services/storage")
+	hits, err := meter.Int64Counter(
+		"storage.resolve_cache.hits",
+		metric.WithDescription("Resolve requests answered from the record cache"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.Int64Counter(
+		"storage.resolve_cache.misses",
+		metric.WithDescription("Resolve requests answered from Postgres because the record cache was not in sync"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedStorage{
+		Storage:  s,
+		pool:     s.pool,
+		queries:  s.queries,
+		logger:   l,
+		hits:     hits,
+		misses:   misses,
+		root:     newCacheNode(),
+		zones:    map[string]Zone{},
+		watchers: map[chan RecordsChange]struct{}{},
+	}, nil
+}
+
+func (s *CachedStorage) Resolve(ctx context.Context, q DNSQuestion) (DNSResponse, error) {
+	s.mu.RLock()
+	if !s.synced {
+		s.mu.RUnlock()
+		s.misses.Add(ctx, 1)
+		return s.Storage.Resolve(ctx, q)
+	}
+	records := s.root.resolve(q)
+	if len(records) == 0 {
+		response, err := s.resolveNegative(q.Name)
+		s.mu.RUnlock()
+		s.hits.Add(ctx, 1)
+		return response, err
+	}
+	s.mu.RUnlock()
+	s.hits.Add(ctx, 1)
+
+	answer := make([]string, len(records))
+	for i, record := range records {
+		rr, err := replaceName(record.content, q.Name)
+		if err != nil {
+			return DNSResponse{}, ResolveServerError
+		}
+		answer[i] = rr
+	}
+	return DNSResponse{Answer: answer}, nil
+}
+
+// Mirrors PostgresStorage.resolveNegative, must be called with mu held.
+func (s *CachedStorage) resolveNegative(name string) (DNSResponse, error) {
+	var zone Zone
+	found := false
+	// Offsets of the labels, longest name first.
+	for _, i := range dns.Split(name) {
+		if zone, found = s.zones[name[i:]]; found {
+			break
+		}
+	}
+	if !found {
+		return DNSResponse{}, ResolveRecordNotFoundError
+	}
+	node := s.root.lookup(name)
+	atNode := node != nil && 0 < len(node.records)
+	return negativeResponse(zone, atNode || s.root.wildcardCovers(name), func() (bool, error) {
+		return node != nil && node.hasRecordsBelow(), nil
+	})
+}
+
+// The snapshot is sent once the cache is in sync.
+func (s *CachedStorage) Watch(ctx context.Context) (<-chan RecordsChange, error) {
+	ch := make(chan RecordsChange, watchBufferSize)
+	s.mu.Lock()
+	if s.synced {
+		ch <- s.snapshot()
+	}
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.unwatch(ch)
+	}()
+	return ch, nil
+}
+
+// Must be called with mu held.
+func (s *CachedStorage) unwatch(ch chan RecordsChange) {
+	if _, ok := s.watchers[ch]; ok {
+		delete(s.watchers, ch)
+		close(ch)
+	}
+}
+
+// Must be called with mu held.
+func (s *CachedStorage) publish(change RecordsChange) {
+	for ch := range s.watchers {
+		select {
+		case ch <- change:
+		default:
+			s.logger.Warn("dropping record watcher falling behind")
+			s.unwatch(ch)
+		}
+	}
+}
+
+// Keeps the cache in sync until ctx is cancelled.
+func (s *CachedStorage) run(ctx context.Context) {
+	for {
+		err := s.listen(ctx)
+		s.unsync()
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Error("record cache out of sync, retrying", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cacheRetryInterval):
+		}
+	}
+}
+
+// Loads all records and refreshes changed nodes on notification.
+// Listening starts before loading so that no change in between is missed.
+func (s *CachedStorage) listen(ctx context.Context) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays subscribed to the channel, it must not be reused.
+	pgConn := conn.Hijack()
+	defer func() {
+		_ = pgConn.Close(context.Background())
+	}()
+	for _, channel := range []string{recordsChangedChannel, zonesChangedChannel} {
+		if _, err = pgConn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return err
+		}
+	}
+	if err = s.load(ctx); err != nil {
+		return err
+	}
+	s.logger.Info("record cache in sync")
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if notification.Channel == zonesChangedChannel {
+			err = s.refreshZone(ctx, notification.Payload)
+		} else {
+			err = s.refresh(ctx, notification.Payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *CachedStorage) load(ctx context.Context) error {
+	rows, err := s.queries.ListAllRecords(ctx)
+	if err != nil {
+		return err
+	}
+	root := newCacheNode()
+	for _, row := range rows {
+		root.add(row)
+	}
+	zoneRows, err := s.queries.ListZones(ctx)
+	if err != nil {
+		return err
+	}
+	zones := map[string]Zone{}
+	for _, row := range zoneRows {
+		zones[row.Name] = zoneFromRow(row)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.root = root
+	s.zones = zones
+	s.synced = true
+	s.publish(s.snapshot())
+	return nil
+}
+
+// Replaces the cached zone with the zone in Postgres.
+func (s *CachedStorage) refreshZone(ctx context.Context, name string) error {
+	row, err := s.queries.ReadZone(ctx, name)
+	deleted := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !deleted {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if deleted {
+		delete(s.zones, name)
+		s.publish(RecordsChange{Zones: []RecordZone{{Name: name}}})
+		return nil
+	}
+	zone := zoneFromRow(row)
+	s.zones[name] = zone
+	s.publish(RecordsChange{Zones: []RecordZone{recordZone(zone)}})
+	return nil
+}
+
+// Must be called with mu held.
+func (s *CachedStorage) snapshot() RecordsChange {
+	change := s.root.snapshot()
+	change.Zones = make([]RecordZone, 0, len(s.zones))
+	for _, zone := range s.zones {
+		change.Zones = append(change.Zones, recordZone(zone))
+	}
+	return change
+}
+
+// Replaces the cached records at the node with the records in Postgres.
+func (s *CachedStorage) refresh(ctx context.Context, name string) error {
+	rows, err := s.queries.ListRecordsAtNodes(ctx, []string{name})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.root.clear(name)
+	node := RecordNode{Name: name, Records: []NodeRecord{}}
+	for _, row := range rows {
+		s.root.add(row)
+		node.Records = append(node.Records, NodeRecord{
+			Type:     uint16(row.Type),
+			Wildcard: row.IsWildcard,
+			Content:  row.Content,
+		})
+	}
+	s.publish(RecordsChange{Nodes: []RecordNode{node}})
+	return nil
+}
+
+// Changes may be missed until the next load, so watchers are dropped and
+// receive a new snapshot when watching again.
+func (s *CachedStorage) unsync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = false
+	for ch := range s.watchers {
+		s.unwatch(ch)
+	}
+}
+
+// Trie node keyed by labels from the root, holding the records at the node.
+type cacheNode struct {
+	children        map[string]*cacheNode
+	records         []cachedRecord
+	wildcardRecords []cachedRecord
+}
+
+type cachedRecord struct {
+	rtype   uint16
+	content string
+}
+
+func (r cachedRecord) nodeRecord(wildcard bool) NodeRecord {
+	return NodeRecord{Type: r.rtype, Wildcard: wildcard, Content: r.content}
+}
+
+func newCacheNode() *cacheNode {
+	return &cacheNode{children: map[string]*cacheNode{}}
+}
+
+func (n *cacheNode) add(row queries.Record) {
+	node := n
+	labels := dns.SplitDomainName(row.Name)
+	for i := len(labels) - 1; 0 <= i; i-- {
+		child, ok := node.children[labels[i]]
+		if !ok {
+			child = newCacheNode()
+			node.children[labels[i]] = child
+		}
+		node = child
+	}
+	record := cachedRecord{rtype: uint16(row.Type), content: row.Content}
+	if row.IsWildcard {
+		node.wildcardRecords = append(node.wildcardRecords, record)
+	} else {
+		node.records = append(node.records, record)
+	}
+}
+
+// Empty nodes are kept until the next full load.
+func (n *cacheNode) clear(name string) {
+	node := n
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 <= i; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			return
+		}
+	}
+	node.records = nil
+	node.wildcardRecords = nil
+}
+
+// Lists the nodes holding records, zones are added by CachedStorage.
+func (n *cacheNode) snapshot() RecordsChange {
+	change := RecordsChange{Snapshot: true, Nodes: []RecordNode{}}
+	n.walk(".", func(name string, node *cacheNode) {
+		if len(node.records) == 0 && len(node.wildcardRecords) == 0 {
+			return
+		}
+		records := make([]NodeRecord, 0, len(node.records)+len(node.wildcardRecords))
+		for _, record := range node.records {
+			records = append(records, record.nodeRecord(false))
+		}
+		for _, record := range node.wildcardRecords {
+			records = append(records, record.nodeRecord(true))
+		}
+		change.Nodes = append(change.Nodes, RecordNode{Name: name, Records: records})
+	})
+	return change
+}
+
+func (n *cacheNode) walk(name string, f func(name string, node *cacheNode)) {
+	f(name, n)
+	for label, child := range n.children {
+		childName := label + "."
+		if name != "." {
+			childName += name
+		}
+		child.walk(childName, f)
+	}
+}
+
+// Matches PostgresStorage.Resolve: records at the node of the question name
+// of the question type or CNAME, otherwise wildcard records of the question
+// type at the closest ancestor, excluding the root.
+func (n *cacheNode) resolve(q DNSQuestion) []cachedRecord {
+	node := n
+	var wildcard []cachedRecord
+	labels := dns.SplitDomainName(q.Name)
+	for i := len(labels) - 1; 0 <= i; i-- {
+		if node != n {
+			if matching := filterType(node.wildcardRecords, q.Qtype, false); 0 < len(matching) {
+				wildcard = matching
+			}
+		}
+		node = node.children[labels[i]]
+		if node == nil {
+			return wildcard
+		}
+	}
+	if matching := filterType(node.records, q.Qtype, true); 0 < len(matching) {
+		return matching
+	}
+	return wildcard
+}
+
+func (n *cacheNode) lookup(name string) *cacheNode {
+	node := n
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 <= i && node != nil; i-- {
+		node = node.children[labels[i]]
+	}
+	return node
+}
+
+// Whether wildcard records of any type exist at an ancestor of the name,
+// excluding the root.
+func (n *cacheNode) wildcardCovers(name string) bool {
+	node := n
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 < i; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			return false
+		}
+		if 0 < len(node.wildcardRecords) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether wildcard records exist at the node or any records below it.
+func (n *cacheNode) hasRecordsBelow() bool {
+	if 0 < len(n.wildcardRecords) {
+		return true
+	}
+	for _, child := range n.children {
+		if 0 < len(child.records) || child.hasRecordsBelow() {
+			return true
+		}
+	}
+	return false
+}
+
+func filterType(records []cachedRecord, qtype uint16, includeCNAME bool) []cachedRecord {
+	matching := []cachedRecord{}
+	for _, record := range records {
+		if record.rtype == qtype || (includeCNAME && record.rtype == dns.TypeCNAME) {
+			matching = append(matching, record)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/database/queries"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+func createTestCache(t *testing.T, fallback Storage, records []string) *CachedStorage {
+	meter := noop.NewMeterProvider().Meter("test")
+	hits, _ := meter.Int64Counter("hits")
+	misses, _ := meter.Int64Counter("misses")
+	s := &CachedStorage{
+		Storage:  fallback,
+		logger:   zap.NewNop(),
+		hits:     hits,
+		misses:   misses,
+		root:     newCacheNode(),
+		zones:    map[string]Zone{},
+		synced:   true,
+		watchers: map[chan RecordsChange]struct{}{},
+	}
+	for i, content := range records {
+		s.root.add(testCacheRow(t, i+1, content))
+	}
+	return s
+}
+
+// Records are created in the example.com. zone.
+func testCacheRow(t *testing.T, id int, content string) queries.Record {
+	rr, err := dns.NewRR(content)
+	if err != nil {
+		t.Fatalf("failed to parse RR: %v", err)
+	}
+	name, isWildcard := nodeName("example.com.", rr)
+	return queries.Record{
+		ID:         int32(id),
+		Zone:       "example.com.",
+		Content:    rr.String(),
+		Name:       name,
+		IsWildcard: isWildcard,
+		Type:       int32(rr.Header().Rrtype),
+	}
+}
+
+func TestCacheResolve(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+		"foo 3600 IN A 127.0.0.2",
+		"bar 3600 IN CNAME example.net.",
+		"*.foo 3600 IN A 127.0.0.3",
+		"* 3600 IN A 127.0.0.4",
+		"* 3600 IN TXT hello",
+		"*.a.b 3600 IN A 127.0.0.5",
+	})
+	tests := []struct {
+		name     string
+		qtype    uint16
+		expected []string
+	}{
+		{"foo.example.com.", dns.TypeA, []string{"foo.example.com.\t3600\tIN\tA\t127.0.0.1", "foo.example.com.\t3600\tIN\tA\t127.0.0.2"}},
+		{"bar.example.com.", dns.TypeAAAA, []string{"bar.example.com.\t3600\tIN\tCNAME\texample.net."}},
+		{"x.foo.example.com.", dns.TypeA, []string{"x.foo.example.com.\t3600\tIN\tA\t127.0.0.3"}},
+		{"x.y.foo.example.com.", dns.TypeA, []string{"x.y.foo.example.com.\t3600\tIN\tA\t127.0.0.3"}},
+		{"baz.example.com.", dns.TypeA, []string{"baz.example.com.\t3600\tIN\tA\t127.0.0.4"}},
+		// No TXT record at foo, the closest wildcard with TXT records answers.
+		{"foo.example.com.", dns.TypeTXT, []string{"foo.example.com.\t3600\tIN\tTXT\t\"hello\""}},
+		{"c.a.b.example.com.", dns.TypeA, []string{"c.a.b.example.com.\t3600\tIN\tA\t127.0.0.5"}},
+		// Wildcards do not match their own node.
+		{"a.b.example.com.", dns.TypeA, []string{"a.b.example.com.\t3600\tIN\tA\t127.0.0.4"}},
+	}
+	for _, test := range tests {
+		response, err := s.Resolve(context.Background(), DNSQuestion{Name: test.name, Qtype: test.qtype})
+		if err != nil {
+			t.Fatalf("expected no error resolving %s, got %v", test.name, err)
+		}
+		if len(response.Answer) != len(test.expected) {
+			t.Fatalf("expected %d answers for %s, got %v", len(test.expected), test.name, response.Answer)
+		}
+		for i := range test.expected {
+			if response.Answer[i] != test.expected[i] {
+				t.Errorf("expected answer %d for %s to be '%s', got '%s'", i, test.name, test.expected[i], response.Answer[i])
+			}
+		}
+	}
+}
+
+func TestCacheResolveNotFound(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+	})
+	_, err := s.Resolve(context.Background(), DNSQuestion{Name: "foo.example.com.", Qtype: dns.TypeAAAA})
+	if err != ResolveRecordNotFoundError {
+		t.Fatalf("expected ResolveRecordNotFoundError, got %v", err)
+	}
+	_, err = s.Resolve(context.Background(), DNSQuestion{Name: "bar.example.com.", Qtype: dns.TypeA})
+	if err != ResolveRecordNotFoundError {
+		t.Fatalf("expected ResolveRecordNotFoundError, got %v", err)
+	}
+}
+
+func TestCacheRefreshNode(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+		"*.foo 3600 IN A 127.0.0.2",
+	})
+	// Mimics refresh after the A record at foo is updated.
+	s.root.clear("foo.example.com.")
+	s.root.add(testCacheRow(t, 1, "foo 3600 IN A 127.0.0.3"))
+	response, err := s.Resolve(context.Background(), DNSQuestion{Name: "foo.example.com.", Qtype: dns.TypeA})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(response.Answer) != 1 || response.Answer[0] != "foo.example.com.\t3600\tIN\tA\t127.0.0.3" {
+		t.Errorf("expected the updated record, got %v", response.Answer)
+	}
+	// Clearing the node also clears its wildcard records.
+	_, err = s.Resolve(context.Background(), DNSQuestion{Name: "x.foo.example.com.", Qtype: dns.TypeA})
+	if err != ResolveRecordNotFoundError {
+		t.Fatalf("expected ResolveRecordNotFoundError, got %v", err)
+	}
+}
+
+func TestCacheNotSyncedFallback(t *testing.T) {
+	fallbackErr := errors.New("fallback")
+	s := createTestCache(t, &MockErrorStorage{Error: fallbackErr}, []string{
+		"foo 3600 IN A 127.0.0.1",
+	})
+	s.unsync()
+	_, err := s.Resolve(context.Background(), DNSQuestion{Name: "foo.example.com.", Qtype: dns.TypeA})
+	if err != fallbackErr {
+		t.Fatalf("expected the fallback storage error, got %v", err)
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+		"*.foo 3600 IN A 127.0.0.2",
+		"bar 3600 IN CNAME example.net.",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	snapshot := <-changes
+	if !snapshot.Snapshot {
+		t.Fatalf("expected the first change to be a snapshot")
+	}
+	nodes := map[string][]NodeRecord{}
+	for _, node := range snapshot.Nodes {
+		nodes[node.Name] = node.Records
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %v", snapshot.Nodes)
+	}
+	if len(nodes["foo.example.com."]) != 2 || len(nodes["bar.example.com."]) != 1 {
+		t.Errorf("expected 2 records at foo and 1 at bar, got %v", snapshot.Nodes)
+	}
+	for _, record := range nodes["foo.example.com."] {
+		if record.Wildcard != (record.Content == "*.foo.\t3600\tIN\tA\t127.0.0.2") {
+			t.Errorf("expected only the wildcard record to be marked wildcard, got %+v", record)
+		}
+	}
+
+	// Watchers are dropped when the cache loses sync.
+	s.unsync()
+	if _, ok := <-changes; ok {
+		t.Errorf("expected changes to be closed")
+	}
+}
+
+func TestCacheWatchNotSynced(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+	})
+	s.unsync()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("expected no snapshot before the cache is in sync, got %+v", change)
+	default:
+	}
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Errorf("expected changes to be closed after cancelling")
+	}
+}
+
+func TestCacheResolveNegative(t *testing.T) {
+	s := createTestCache(t, &MockErrorStorage{Error: errors.New("fallback")}, []string{
+		"foo 3600 IN A 127.0.0.1",
+		"*.wild 3600 IN A 127.0.0.2",
+		"a.b 3600 IN A 127.0.0.3",
+	})
+	s.zones["example.com."] = Zone{Name: "example.com.", DefaultTTL: 300, SOASerial: 1}
+	soa := "example.com.\t300\tIN\tSOA\texample.com. hostmaster.example.com. 1 7200 3600 1209600 300"
+	tests := []struct {
+		name          string
+		qtype         uint16
+		authoritative bool
+		notFound      bool
+		nxdomain      bool
+	}{
+		{name: "foo.example.com.", qtype: dns.TypeAAAA},
+		{name: "x.wild.example.com.", qtype: dns.TypeTXT},
+		{name: "b.example.com.", qtype: dns.TypeA, notFound: true},
+		{name: "b.example.com.", qtype: dns.TypeA, authoritative: true},
+		{name: "wild.example.com.", qtype: dns.TypeA, authoritative: true},
+		{name: "bar.example.com.", qtype: dns.TypeA, notFound: true},
+		{name: "bar.example.com.", qtype: dns.TypeA, authoritative: true, nxdomain: true},
+		{name: "foo.example.net.", qtype: dns.TypeA, authoritative: true, notFound: true},
+	}
+	for _, test := range tests {
+		zone := s.zones["example.com."]
+		zone.Authoritative = test.authoritative
+		s.zones["example.com."] = zone
+		response, err := s.Resolve(context.Background(), DNSQuestion{Name: test.name, Qtype: test.qtype})
+		if test.notFound {
+			if err != ResolveRecordNotFoundError {
+				t.Errorf("%s %d: expected ResolveRecordNotFoundError, got %v", test.name, test.qtype, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s %d: expected no error, got %v", test.name, test.qtype, err)
+		}
+		if len(response.Answer) != 0 {
+			t.Errorf("%s %d: expected no answer, got %v", test.name, test.qtype, response.Answer)
+		}
+		if len(response.NS) != 1 || response.NS[0] != soa {
+			t.Errorf("%s %d: expected the zone SOA, got %v", test.name, test.qtype, response.NS)
+		}
+		if response.NXDomain != test.nxdomain {
+			t.Errorf("%s %d: expected NXDomain to be %t", test.name, test.qtype, test.nxdomain)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sneakybugs/corewarden/api/database/queries"
+)
+
+var ErrFilterRuleNotFound = errors.New("filter rule not found")
+var ErrFilterPauseNotFound = errors.New("active filter pause not found")
+
+func (s *PostgresStorage) CreateFilterRule(ctx context.Context, p FilterRuleCreateParameters) (FilterRule, error) {
+	r, err := s.queries.CreateFilterRule(ctx, queries.CreateFilterRuleParams{
+		Rule:        p.Rule,
+		ClientGroup: p.Group,
+		Comment:     p.Comment,
+	})
+	if err != nil {
+		return FilterRule{}, ErrServer
+	}
+	return filterRuleFromRow(r), nil
+}
+
+func (s *PostgresStorage) ReadFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	r, err := s.queries.ReadFilterRule(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FilterRule{}, ErrFilterRuleNotFound
+		}
+		return FilterRule{}, ErrServer
+	}
+	return filterRuleFromRow(r), nil
+}
+
+func (s *PostgresStorage) UpdateFilterRule(ctx context.Context, p FilterRuleUpdateParameters) (FilterRule, error) {
+	r, err := s.queries.UpdateFilterRule(ctx, queries.UpdateFilterRuleParams{
+		Rule:        p.Rule,
+		ClientGroup: p.Group,
+		Comment:     p.Comment,
+		ID:          int32(p.ID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FilterRule{}, ErrFilterRuleNotFound
+		}
+		return FilterRule{}, ErrServer
+	}
+	return filterRuleFromRow(r), nil
+}
+
+func (s *PostgresStorage) DeleteFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	r, err := s.queries.DeleteFilterRule(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FilterRule{}, ErrFilterRuleNotFound
+		}
+		return FilterRule{}, ErrServer
+	}
+	return filterRuleFromRow(r), nil
+}
+
+func (s *PostgresStorage) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	r, err := s.queries.ListFilterRules(ctx)
+	if err != nil {
+		return []FilterRule{}, ErrServer
+	}
+	rules := make([]FilterRule, len(r))
+	for i, rule := range r {
+		rules[i] = filterRuleFromRow(rule)
+	}
+	return rules, nil
+}
+
+func filterRuleFromRow(r queries.FilterRule) FilterRule {
+	return FilterRule{
+		ID:         int(r.ID),
+		Rule:       r.Rule,
+		Group:      r.ClientGroup,
+		Comment:    r.Comment,
+		CreatedAt:  r.CreatedAt.Time,
+		ModifiedOn: r.ModifiedOn.Time,
+	}
+}
+
+func (s *PostgresStorage) CreateFilterPause(ctx context.Context, p FilterPauseCreateParameters) (FilterPause, error) {
+	r, err := s.queries.CreateFilterPause(ctx, queries.CreateFilterPauseParams{
+		ClientGroup: p.Group,
+		Until:       pgtype.Timestamptz{Time: p.Until, Valid: true},
+		CreatedBy:   p.CreatedBy,
+		Comment:     p.Comment,
+	})
+	if err != nil {
+		return FilterPause{}, ErrServer
+	}
+	return filterPauseFromRow(r), nil
+}
+
+// Ends the active pause now, ended pauses are kept.
+func (s *PostgresStorage) EndFilterPause(ctx context.Context, p FilterPauseEndParameters) (FilterPause, error) {
+	r, err := s.queries.EndFilterPause(ctx, queries.EndFilterPauseParams{
+		EndedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		EndedBy: p.EndedBy,
+		ID:      int32(p.ID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FilterPause{}, ErrFilterPauseNotFound
+		}
+		return FilterPause{}, ErrServer
+	}
+	return filterPauseFromRow(r), nil
+}
+
+func (s *PostgresStorage) ListActiveFilterPauses(ctx context.Context) ([]FilterPause, error) {
+	r, err := s.queries.ListActiveFilterPauses(ctx, pgtype.Timestamptz{Time: time.Now(), Valid: true})
+	if err != nil {
+		return []FilterPause{}, ErrServer
+	}
+	pauses := make([]FilterPause, len(r))
+	for i, pause := range r {
+		pauses[i] = filterPauseFromRow(pause)
+	}
+	return pauses, nil
+}
+
+func filterPauseFromRow(r queries.FilterPause) FilterPause {
+	return FilterPause{
+		ID:        int(r.ID),
+		Group:     r.ClientGroup,
+		Until:     r.Until.Time,
+		CreatedBy: r.CreatedBy,
+		CreatedAt: r.CreatedAt.Time,
+		EndedBy:   r.EndedBy,
+		Comment:   r.Comment,
+	}
+}
+
+type FilterRuleCreateParameters struct {
+	// Adblock style rule or hosts line.
+	Rule string
+	// Client group the rule applies to, empty for all groups.
+	Group   string
+	Comment string
+}
+
+type FilterRuleUpdateParameters struct {
+	ID int
+	// Adblock style rule or hosts line.
+	Rule string
+	// Client group the rule applies to, empty for all groups.
+	Group   string
+	Comment string
+}
+
+type FilterRule struct {
+	ID         int
+	Rule       string
+	Group      string
+	Comment    string
+	CreatedAt  time.Time
+	ModifiedOn time.Time
+}
+
+type FilterPauseCreateParameters struct {
+	// Client group the pause applies to, empty for all groups.
+	Group string
+	Until time.Time
+	// Subject creating the pause.
+	CreatedBy string
+	Comment   string
+}
+
+type FilterPauseEndParameters struct {
+	ID int
+	// Subject ending the pause.
+	EndedBy string
+}
+
+type FilterPause struct {
+	ID        int
+	Group     string
+	Until     time.Time
+	CreatedBy string
+	CreatedAt time.Time
+	// Empty unless the pause was ended early.
+	EndedBy string
+	Comment string
+}
@@ -2,12 +2,24 @@ package storage
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 type MockStorage struct {
-	nextID  int
-	records []Record
+	nextID           int
+	records          []Record
+	nextZoneID       int
+	zones            []Zone
+	nextFilterRuleID int
+	filterRules      []FilterRule
+	nextPauseID      int
+	filterPauses     []FilterPause
+	nextQueryLogID   int64
+	queryLog         []QueryLogEntry
 }
 
 func (s *MockStorage) Resolve(ctx context.Context, q DNSQuestion) (DNSResponse, error) {
@@ -72,6 +84,437 @@ func (s *MockStorage) ListRecords(ctx context.Context, zone string) ([]Record, e
 	return records, nil
 }
 
+func (s *MockStorage) ListRecordsPage(ctx context.Context, p RecordListParameters) (RecordPage, error) {
+	cursor, err := decodeRecordCursor(p.Cursor, p.Sort)
+	if err != nil {
+		return RecordPage{}, err
+	}
+	type namedRecord struct {
+		record Record
+		name   string
+	}
+	matching := []namedRecord{}
+	for _, r := range s.records {
+		if r.Zone != p.Zone {
+			continue
+		}
+		rr, err := dns.NewRR(r.RR)
+		if err != nil {
+			return RecordPage{}, ErrServer
+		}
+		name, isWildcard := nodeName(dns.Fqdn(r.Zone), rr)
+		if p.Name != "" && name != dns.Fqdn(p.Name) {
+			continue
+		}
+		if p.Type != 0 && rr.Header().Rrtype != p.Type {
+			continue
+		}
+		if p.IsWildcard != nil && isWildcard != *p.IsWildcard {
+			continue
+		}
+		if p.Comment != "" && !strings.Contains(strings.ToLower(r.Comment), strings.ToLower(p.Comment)) {
+			continue
+		}
+		if r.ModifiedOn.Before(p.ModifiedSince) {
+			continue
+		}
+		matching = append(matching, namedRecord{record: r, name: name})
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if p.Sort == RecordSortName && matching[i].name != matching[j].name {
+			return matching[i].name < matching[j].name
+		}
+		return matching[i].record.ID < matching[j].record.ID
+	})
+
+	page := RecordPage{Records: []Record{}}
+	for _, m := range matching {
+		if p.Sort == RecordSortName {
+			if m.name < cursor.Name || (m.name == cursor.Name && m.record.ID <= cursor.ID) {
+				continue
+			}
+		} else if m.record.ID <= cursor.ID {
+			continue
+		}
+		if p.PageSize != 0 && len(page.Records) == p.PageSize {
+			last := page.Records[len(page.Records)-1]
+			lastRR, _ := dns.NewRR(last.RR)
+			lastName, _ := nodeName(dns.Fqdn(last.Zone), lastRR)
+			page.NextCursor = encodeRecordCursor(recordCursor{
+				Sort: p.Sort,
+				ID:   last.ID,
+				Name: lastName,
+			})
+			break
+		}
+		page.Records = append(page.Records, m.record)
+	}
+	return page, nil
+}
+
+func (s *MockStorage) ApplyChangeset(ctx context.Context, operations []ChangesetOperation, authorize ChangesetAuthorizer) ([]Record, error) {
+	// Restore previous state on failure to mimic a transaction rollback.
+	nextID := s.nextID
+	previous := make([]Record, len(s.records))
+	copy(previous, s.records)
+	records := make([]Record, len(operations))
+	for i, op := range operations {
+		var err error
+		if op.Action == ChangesetUpdate || op.Action == ChangesetDelete {
+			var existing Record
+			existing, err = s.ReadRecord(ctx, op.ID)
+			if err == nil {
+				err = authorize(i, existing)
+			}
+			if err != nil {
+				s.nextID = nextID
+				s.records = previous
+				return []Record{}, &ChangesetOperationError{Index: i, Err: err}
+			}
+		}
+		switch op.Action {
+		case ChangesetCreate:
+			records[i], err = s.CreateRecord(ctx, RecordCreateParameters{
+				Zone:    op.Zone,
+				RR:      op.RR,
+				Comment: op.Comment,
+			})
+		case ChangesetUpdate:
+			records[i], err = s.UpdateRecord(ctx, RecordUpdateParameters{
+				ID:      op.ID,
+				Zone:    op.Zone,
+				RR:      op.RR,
+				Comment: op.Comment,
+			})
+		case ChangesetDelete:
+			records[i], err = s.DeleteRecord(ctx, op.ID)
+		default:
+			err = ErrChangesetAction
+		}
+		if err != nil {
+			s.nextID = nextID
+			s.records = previous
+			return []Record{}, &ChangesetOperationError{Index: i, Err: err}
+		}
+	}
+	return records, nil
+}
+
+func (s *MockStorage) CreateZone(ctx context.Context, p ZoneCreateParameters) (Zone, error) {
+	for _, z := range s.zones {
+		if z.Name == p.Name {
+			return Zone{}, ErrZoneExists
+		}
+	}
+	defaultTTL := p.DefaultTTL
+	if defaultTTL == 0 {
+		defaultTTL = DefaultZoneTTL
+	}
+	zone := Zone{
+		ID:            s.nextZoneID,
+		Name:          p.Name,
+		DefaultTTL:    defaultTTL,
+		SOASerial:     1,
+		Nameservers:   p.Nameservers,
+		Authoritative: p.Authoritative,
+		Comment:       p.Comment,
+		CreatedAt:     time.Now(),
+		ModifiedOn:    time.Now(),
+	}
+	s.zones = append(s.zones, zone)
+	s.nextZoneID++
+	return zone, nil
+}
+
+func (s *MockStorage) ReadZone(ctx context.Context, name string) (Zone, error) {
+	for _, z := range s.zones {
+		if z.Name == name {
+			return z, nil
+		}
+	}
+	return Zone{}, ErrZoneNotFound
+}
+
+func (s *MockStorage) UpdateZone(ctx context.Context, p ZoneUpdateParameters) (Zone, error) {
+	defaultTTL := p.DefaultTTL
+	if defaultTTL == 0 {
+		defaultTTL = DefaultZoneTTL
+	}
+	for i, z := range s.zones {
+		if z.Name == p.Name {
+			s.zones[i].DefaultTTL = defaultTTL
+			s.zones[i].Nameservers = p.Nameservers
+			s.zones[i].Authoritative = p.Authoritative
+			s.zones[i].Comment = p.Comment
+			s.zones[i].SOASerial++
+			s.zones[i].ModifiedOn = time.Now()
+			return s.zones[i], nil
+		}
+	}
+	return Zone{}, ErrZoneNotFound
+}
+
+func (s *MockStorage) DeleteZone(ctx context.Context, name string) (Zone, error) {
+	for _, r := range s.records {
+		if r.Zone == name {
+			return Zone{}, ErrZoneNotEmpty
+		}
+	}
+	for i, z := range s.zones {
+		if z.Name == name {
+			s.zones = append(s.zones[:i], s.zones[i+1:]...)
+			return z, nil
+		}
+	}
+	return Zone{}, ErrZoneNotFound
+}
+
+func (s *MockStorage) ListZones(ctx context.Context) ([]Zone, error) {
+	zones := make([]Zone, len(s.zones))
+	copy(zones, s.zones)
+	return zones, nil
+}
+
+func (s *MockStorage) ImportZone(ctx context.Context, p ZoneImportParameters) (ZoneImportResult, error) {
+	// Restore previous state on failure to mimic a transaction rollback.
+	nextID := s.nextID
+	previous := make([]Record, len(s.records))
+	copy(previous, s.records)
+
+	imported := map[string]bool{}
+	for _, r := range p.Records {
+		imported[normalizeRR(r.RR)] = true
+	}
+	result := ZoneImportResult{}
+	present := map[string]bool{}
+	kept := []Record{}
+	for _, r := range s.records {
+		if r.Zone == p.Zone {
+			content := normalizeRR(r.RR)
+			if p.Replace && !imported[content] {
+				result.Deleted++
+				continue
+			}
+			present[content] = true
+		}
+		kept = append(kept, r)
+	}
+	s.records = kept
+	for i, r := range p.Records {
+		content := normalizeRR(r.RR)
+		if present[content] {
+			result.Unchanged++
+			continue
+		}
+		if _, err := dns.NewRR(r.RR); err != nil {
+			s.nextID = nextID
+			s.records = previous
+			return ZoneImportResult{}, &ZoneImportRecordError{Index: i, Err: err}
+		}
+		_, _ = s.CreateRecord(ctx, RecordCreateParameters{
+			Zone:    p.Zone,
+			RR:      r.RR,
+			Comment: r.Comment,
+		})
+		present[content] = true
+		result.Created++
+	}
+	return result, nil
+}
+
+// Sends a snapshot of the records, changes are not sent.
+func (s *MockStorage) Watch(ctx context.Context) (<-chan RecordsChange, error) {
+	nodes := map[string]*RecordNode{}
+	snapshot := RecordsChange{Snapshot: true, Nodes: []RecordNode{}, Zones: []RecordZone{}}
+	for _, record := range s.records {
+		rr, err := dns.NewRR(record.RR)
+		if err != nil {
+			return nil, err
+		}
+		name, isWildcard := nodeName(record.Zone, rr)
+		if _, ok := nodes[name]; !ok {
+			nodes[name] = &RecordNode{Name: name}
+		}
+		nodes[name].Records = append(nodes[name].Records, NodeRecord{
+			Type:     rr.Header().Rrtype,
+			Wildcard: isWildcard,
+			Content:  record.RR,
+		})
+	}
+	for _, node := range nodes {
+		snapshot.Nodes = append(snapshot.Nodes, *node)
+	}
+	for _, zone := range s.zones {
+		snapshot.Zones = append(snapshot.Zones, recordZone(zone))
+	}
+	ch := make(chan RecordsChange, 1)
+	ch <- snapshot
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (s *MockStorage) CreateFilterRule(ctx context.Context, p FilterRuleCreateParameters) (FilterRule, error) {
+	rule := FilterRule{
+		ID:         s.nextFilterRuleID,
+		Rule:       p.Rule,
+		Group:      p.Group,
+		Comment:    p.Comment,
+		CreatedAt:  time.Now(),
+		ModifiedOn: time.Now(),
+	}
+	s.filterRules = append(s.filterRules, rule)
+	s.nextFilterRuleID++
+	return rule, nil
+}
+
+func (s *MockStorage) ReadFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	for _, r := range s.filterRules {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return FilterRule{}, ErrFilterRuleNotFound
+}
+
+func (s *MockStorage) UpdateFilterRule(ctx context.Context, p FilterRuleUpdateParameters) (FilterRule, error) {
+	for i, r := range s.filterRules {
+		if r.ID == p.ID {
+			s.filterRules[i].Rule = p.Rule
+			s.filterRules[i].Group = p.Group
+			s.filterRules[i].Comment = p.Comment
+			s.filterRules[i].ModifiedOn = time.Now()
+			return s.filterRules[i], nil
+		}
+	}
+	return FilterRule{}, ErrFilterRuleNotFound
+}
+
+func (s *MockStorage) DeleteFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	for i, r := range s.filterRules {
+		if r.ID == id {
+			s.filterRules = append(s.filterRules[:i], s.filterRules[i+1:]...)
+			return r, nil
+		}
+	}
+	return FilterRule{}, ErrFilterRuleNotFound
+}
+
+func (s *MockStorage) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	rules := make([]FilterRule, len(s.filterRules))
+	copy(rules, s.filterRules)
+	return rules, nil
+}
+
+func (s *MockStorage) CreateFilterPause(ctx context.Context, p FilterPauseCreateParameters) (FilterPause, error) {
+	pause := FilterPause{
+		ID:        s.nextPauseID,
+		Group:     p.Group,
+		Until:     p.Until,
+		CreatedBy: p.CreatedBy,
+		CreatedAt: time.Now(),
+		Comment:   p.Comment,
+	}
+	s.filterPauses = append(s.filterPauses, pause)
+	s.nextPauseID++
+	return pause, nil
+}
+
+func (s *MockStorage) EndFilterPause(ctx context.Context, p FilterPauseEndParameters) (FilterPause, error) {
+	now := time.Now()
+	for i, pause := range s.filterPauses {
+		if pause.ID == p.ID && pause.Until.After(now) {
+			s.filterPauses[i].Until = now
+			s.filterPauses[i].EndedBy = p.EndedBy
+			return s.filterPauses[i], nil
+		}
+	}
+	return FilterPause{}, ErrFilterPauseNotFound
+}
+
+func (s *MockStorage) ListActiveFilterPauses(ctx context.Context) ([]FilterPause, error) {
+	now := time.Now()
+	pauses := []FilterPause{}
+	for _, pause := range s.filterPauses {
+		if pause.Until.After(now) {
+			pauses = append(pauses, pause)
+		}
+	}
+	return pauses, nil
+}
+
+func (s *MockStorage) AppendQueryLog(ctx context.Context, entries []QueryLogEntry) error {
+	for _, entry := range entries {
+		entry.ID = s.nextQueryLogID
+		s.queryLog = append(s.queryLog, entry)
+		s.nextQueryLogID++
+	}
+	return nil
+}
+
+func (s *MockStorage) ListQueryLog(ctx context.Context, p QueryLogListParameters) (QueryLogPage, error) {
+	cursor, err := decodeQueryLogCursor(p.Cursor)
+	if err != nil {
+		return QueryLogPage{}, err
+	}
+	matching := []QueryLogEntry{}
+	for _, e := range s.queryLog {
+		if p.Client != "" && e.Client != p.Client && e.ClientID != p.Client {
+			continue
+		}
+		if p.Name != "" && e.Name != dns.Fqdn(p.Name) {
+			continue
+		}
+		if p.Type != 0 && e.Type != p.Type {
+			continue
+		}
+		if p.Rcode != nil && e.Rcode != *p.Rcode {
+			continue
+		}
+		if p.Blocklist != "" && e.Blocklist != p.Blocklist {
+			continue
+		}
+		if e.Time.Before(p.Since) || (!p.Until.IsZero() && !e.Time.Before(p.Until)) {
+			continue
+		}
+		matching = append(matching, e)
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if !matching[i].Time.Equal(matching[j].Time) {
+			return matching[i].Time.After(matching[j].Time)
+		}
+		return matching[i].ID > matching[j].ID
+	})
+
+	page := QueryLogPage{Entries: []QueryLogEntry{}}
+	for _, e := range matching {
+		if !cursor.Time.IsZero() && (e.Time.After(cursor.Time) || (e.Time.Equal(cursor.Time) && cursor.ID <= e.ID)) {
+			continue
+		}
+		if len(page.Entries) == p.PageSize {
+			last := page.Entries[len(page.Entries)-1]
+			page.NextCursor = encodeQueryLogCursor(queryLogCursor{Time: last.Time, ID: last.ID})
+			break
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	return page, nil
+}
+
+func (s *MockStorage) DeleteQueryLogBefore(ctx context.Context, before time.Time) (int, error) {
+	kept := []QueryLogEntry{}
+	for _, e := range s.queryLog {
+		if !e.Time.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := len(s.queryLog) - len(kept)
+	s.queryLog = kept
+	return deleted, nil
+}
+
 type MockErrorStorage struct {
 	Error error
 }
@@ -99,10 +542,90 @@ func (s *MockErrorStorage) ListRecords(ctx context.Context, zone string) ([]Reco
 	return []Record{}, s.Error
 }
 
+func (s *MockErrorStorage) ListRecordsPage(ctx context.Context, p RecordListParameters) (RecordPage, error) {
+	return RecordPage{}, s.Error
+}
+
+func (s *MockErrorStorage) ApplyChangeset(ctx context.Context, operations []ChangesetOperation, authorize ChangesetAuthorizer) ([]Record, error) {
+	return []Record{}, s.Error
+}
+
+func (s *MockErrorStorage) CreateZone(ctx context.Context, p ZoneCreateParameters) (Zone, error) {
+	return Zone{}, s.Error
+}
+
+func (s *MockErrorStorage) ReadZone(ctx context.Context, name string) (Zone, error) {
+	return Zone{}, s.Error
+}
+
+func (s *MockErrorStorage) UpdateZone(ctx context.Context, p ZoneUpdateParameters) (Zone, error) {
+	return Zone{}, s.Error
+}
+
+func (s *MockErrorStorage) DeleteZone(ctx context.Context, name string) (Zone, error) {
+	return Zone{}, s.Error
+}
+
+func (s *MockErrorStorage) ListZones(ctx context.Context) ([]Zone, error) {
+	return []Zone{}, s.Error
+}
+
+func (s *MockErrorStorage) ImportZone(ctx context.Context, p ZoneImportParameters) (ZoneImportResult, error) {
+	return ZoneImportResult{}, s.Error
+}
+
+func (s *MockErrorStorage) Watch(ctx context.Context) (<-chan RecordsChange, error) {
+	return nil, s.Error
+}
+
+func (s *MockErrorStorage) CreateFilterRule(ctx context.Context, p FilterRuleCreateParameters) (FilterRule, error) {
+	return FilterRule{}, s.Error
+}
+
+func (s *MockErrorStorage) ReadFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	return FilterRule{}, s.Error
+}
+
+func (s *MockErrorStorage) UpdateFilterRule(ctx context.Context, p FilterRuleUpdateParameters) (FilterRule, error) {
+	return FilterRule{}, s.Error
+}
+
+func (s *MockErrorStorage) DeleteFilterRule(ctx context.Context, id int) (FilterRule, error) {
+	return FilterRule{}, s.Error
+}
+
+func (s *MockErrorStorage) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	return []FilterRule{}, s.Error
+}
+
 type MockStorageOptions struct {
 	ReturnError error
 }
 
+func (s *MockErrorStorage) CreateFilterPause(ctx context.Context, p FilterPauseCreateParameters) (FilterPause, error) {
+	return FilterPause{}, s.Error
+}
+
+func (s *MockErrorStorage) EndFilterPause(ctx context.Context, p FilterPauseEndParameters) (FilterPause, error) {
+	return FilterPause{}, s.Error
+}
+
+func (s *MockErrorStorage) ListActiveFilterPauses(ctx context.Context) ([]FilterPause, error) {
+	return []FilterPause{}, s.Error
+}
+
+func (s *MockErrorStorage) AppendQueryLog(ctx context.Context, entries []QueryLogEntry) error {
+	return s.Error
+}
+
+func (s *MockErrorStorage) ListQueryLog(ctx context.Context, p QueryLogListParameters) (QueryLogPage, error) {
+	return QueryLogPage{}, s.Error
+}
+
+func (s *MockErrorStorage) DeleteQueryLogBefore(ctx context.Context, before time.Time) (int, error) {
+	return 0, s.Error
+}
+
 func NewMockService(o MockStorageOptions) Storage {
 	if o.ReturnError != nil {
 		return &MockErrorStorage{
@@ -110,7 +633,15 @@ func NewMockService(o MockStorageOptions) Storage {
 		}
 	}
 	return &MockStorage{
-		nextID:  1,
-		records: []Record{},
+		nextID:           1,
+		records:          []Record{},
+		nextZoneID:       1,
+		zones:            []Zone{},
+		nextFilterRuleID: 1,
+		filterRules:      []FilterRule{},
+		nextPauseID:      1,
+		filterPauses:     []FilterPause{},
+		nextQueryLogID:   1,
+		queryLog:         []QueryLogEntry{},
 	}
 }
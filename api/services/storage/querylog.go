@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/database/queries"
+)
+
+func (s *PostgresStorage) AppendQueryLog(ctx context.Context, entries []QueryLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	p := queries.CreateQueryLogEntriesParams{
+		Times:        make([]pgtype.Timestamptz, len(entries)),
+		Clients:      make([]string, len(entries)),
+		ClientIds:    make([]string, len(entries)),
+		ClientGroups: make([]string, len(entries)),
+		Names:        make([]string, len(entries)),
+		Types:        make([]int32, len(entries)),
+		Rcodes:       make([]int32, len(entries)),
+		Blocklists:   make([]string, len(entries)),
+		Durations:    make([]int64, len(entries)),
+	}
+	for i, entry := range entries {
+		p.Times[i] = pgtype.Timestamptz{Time: entry.Time, Valid: true}
+		p.Clients[i] = entry.Client
+		p.ClientIds[i] = entry.ClientID
+		p.ClientGroups[i] = entry.Group
+		p.Names[i] = entry.Name
+		p.Types[i] = int32(entry.Type)
+		p.Rcodes[i] = int32(entry.Rcode)
+		p.Blocklists[i] = entry.Blocklist
+		p.Durations[i] = entry.Duration.Microseconds()
+	}
+	if err := s.queries.CreateQueryLogEntries(ctx, p); err != nil {
+		return ErrServer
+	}
+	return nil
+}
+
+// Returns a page of query log entries matching the filters, newest first. The
+// next page is listed by passing QueryLogPage.NextCursor as p.Cursor.
+func (s *PostgresStorage) ListQueryLog(ctx context.Context, p QueryLogListParameters) (QueryLogPage, error) {
+	cursor, err := decodeQueryLogCursor(p.Cursor)
+	if err != nil {
+		return QueryLogPage{}, err
+	}
+	var client pgtype.Text
+	if p.Client != "" {
+		client = pgtype.Text{String: p.Client, Valid: true}
+	}
+	var name pgtype.Text
+	if p.Name != "" {
+		name = pgtype.Text{String: dns.Fqdn(p.Name), Valid: true}
+	}
+	var qtype pgtype.Int4
+	if p.Type != 0 {
+		qtype = pgtype.Int4{Int32: int32(p.Type), Valid: true}
+	}
+	var rcode pgtype.Int4
+	if p.Rcode != nil {
+		rcode = pgtype.Int4{Int32: int32(*p.Rcode), Valid: true}
+	}
+	var blocklist pgtype.Text
+	if p.Blocklist != "" {
+		blocklist = pgtype.Text{String: p.Blocklist, Valid: true}
+	}
+	var since pgtype.Timestamptz
+	if !p.Since.IsZero() {
+		since = pgtype.Timestamptz{Time: p.Since, Valid: true}
+	}
+	var until pgtype.Timestamptz
+	if !p.Until.IsZero() {
+		until = pgtype.Timestamptz{Time: p.Until, Valid: true}
+	}
+	var beforeTime pgtype.Timestamptz
+	if !cursor.Time.IsZero() {
+		beforeTime = pgtype.Timestamptz{Time: cursor.Time, Valid: true}
+	}
+
+	// One extra entry is fetched to know whether there is a next page.
+	rows, err := s.queries.ListQueryLog(ctx, queries.ListQueryLogParams{
+		Client:     client,
+		Name:       name,
+		Type:       qtype,
+		Rcode:      rcode,
+		Blocklist:  blocklist,
+		Since:      since,
+		Until:      until,
+		BeforeTime: beforeTime,
+		BeforeID:   cursor.ID,
+		PageSize:   int32(p.PageSize + 1),
+	})
+	if err != nil {
+		return QueryLogPage{}, ErrServer
+	}
+
+	page := QueryLogPage{}
+	if p.PageSize < len(rows) {
+		rows = rows[:p.PageSize]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeQueryLogCursor(queryLogCursor{
+			Time: last.Time.Time,
+			ID:   last.ID,
+		})
+	}
+	page.Entries = make([]QueryLogEntry, len(rows))
+	for i, row := range rows {
+		page.Entries[i] = QueryLogEntry{
+			ID:        row.ID,
+			Time:      row.Time.Time,
+			Client:    row.Client,
+			ClientID:  row.ClientID,
+			Group:     row.ClientGroup,
+			Name:      row.Name,
+			Type:      uint16(row.Type),
+			Rcode:     int(row.Rcode),
+			Blocklist: row.Blocklist,
+			Duration:  time.Duration(row.Duration) * time.Microsecond,
+		}
+	}
+	return page, nil
+}
+
+func (s *PostgresStorage) DeleteQueryLogBefore(ctx context.Context, before time.Time) (int, error) {
+	deleted, err := s.queries.DeleteQueryLogBefore(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return 0, ErrServer
+	}
+	return int(deleted), nil
+}
+
+// Position after the last entry of a page.
+type queryLogCursor struct {
+	Time time.Time `json:"t"`
+	ID   int64     `json:"i"`
+}
+
+func encodeQueryLogCursor(c queryLogCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// An empty cursor starts from the newest entry.
+func decodeQueryLogCursor(cursor string) (queryLogCursor, error) {
+	if cursor == "" {
+		return queryLogCursor{}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return queryLogCursor{}, ErrInvalidCursor
+	}
+	var c queryLogCursor
+	if err = json.Unmarshal(data, &c); err != nil || c.Time.IsZero() {
+		return queryLogCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Query answered by a CoreDNS server.
+type QueryLogEntry struct {
+	// Zero until stored.
+	ID   int64
+	Time time.Time
+	// Client IP address.
+	Client string
+	// Client ID from the client ID EDNS0 option, empty when not sent.
+	ClientID string
+	// Client group of the filterlist plugin, empty when not filtered.
+	Group string
+	// Fully qualified query name.
+	Name  string
+	Type  uint16
+	Rcode int
+	// List of the rule blocking the query, empty when not blocked.
+	Blocklist string
+	Duration  time.Duration
+}
+
+type QueryLogListParameters struct {
+	// Client IP address or client ID.
+	Client string
+	// Fully qualified query name.
+	Name string
+	// Any type when zero.
+	Type uint16
+	// Any rcode when nil.
+	Rcode     *int
+	Blocklist string
+	// Inclusive, unbounded when zero.
+	Since time.Time
+	// Exclusive, unbounded when zero.
+	Until time.Time
+	// Must be positive.
+	PageSize int
+	Cursor   string
+}
+
+type QueryLogPage struct {
+	// Newest first.
+	Entries []QueryLogEntry
+	// Empty on the last page.
+	NextCursor string
+}
@@ -11,13 +11,16 @@ import (
 	"github.com/jackc/pgx/v5/stdlib"
 	migrate "github.com/rubenv/sql-migrate"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
 type Options struct {
 	ConnectionString string
+	// Serve Resolve from an in-memory record cache.
+	CacheRecords bool
 }
 
-func NewService(lc fx.Lifecycle, options Options, rc *health.ReadinessChecks) (Storage, error) {
+func NewService(lc fx.Lifecycle, options Options, rc *health.ReadinessChecks, l *zap.Logger) (Storage, error) {
 	pool, err := pgxpool.New(context.Background(), options.ConnectionString)
 	if err != nil {
 		return nil, err
@@ -45,7 +48,39 @@ func NewService(lc fx.Lifecycle, options Options, rc *health.ReadinessChecks) (S
 	rc.Add(&readinessCheck{
 		db: db,
 	})
-	return &PostgresStorage{queries: queries.New(pool), pool: pool}, nil
+	s := &PostgresStorage{queries: queries.New(pool), pool: pool}
+	if !options.CacheRecords {
+		return s, nil
+	}
+
+	cs, err := newCachedStorage(s, l)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(
+		fx.Hook{
+			OnStart: func(context.Context) error {
+				go func() {
+					cs.run(ctx)
+					close(done)
+				}()
+				return nil
+			},
+			// Stopped before the pool is closed, hooks are stopped in reverse order.
+			OnStop: func(stopCtx context.Context) error {
+				cancel()
+				select {
+				case <-done:
+					return nil
+				case <-stopCtx.Done():
+					return stopCtx.Err()
+				}
+			},
+		},
+	)
+	return cs, nil
 }
 
 type readinessCheck struct {
@@ -2,11 +2,16 @@ package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/miekg/dns"
 	"github.com/sneakybugs/corewarden/api/database/queries"
@@ -21,11 +26,53 @@ type Storage interface {
 	UpdateRecord(ctx context.Context, p RecordUpdateParameters) (Record, error)
 	DeleteRecord(ctx context.Context, id int) (Record, error)
 	ListRecords(ctx context.Context, zone string) ([]Record, error)
+	ListRecordsPage(ctx context.Context, p RecordListParameters) (RecordPage, error)
+	ApplyChangeset(ctx context.Context, operations []ChangesetOperation, authorize ChangesetAuthorizer) ([]Record, error)
+	CreateZone(ctx context.Context, p ZoneCreateParameters) (Zone, error)
+	ReadZone(ctx context.Context, name string) (Zone, error)
+	UpdateZone(ctx context.Context, p ZoneUpdateParameters) (Zone, error)
+	DeleteZone(ctx context.Context, name string) (Zone, error)
+	ListZones(ctx context.Context) ([]Zone, error)
+	ImportZone(ctx context.Context, p ZoneImportParameters) (ZoneImportResult, error)
+	CreateFilterRule(ctx context.Context, p FilterRuleCreateParameters) (FilterRule, error)
+	ReadFilterRule(ctx context.Context, id int) (FilterRule, error)
+	UpdateFilterRule(ctx context.Context, p FilterRuleUpdateParameters) (FilterRule, error)
+	DeleteFilterRule(ctx context.Context, id int) (FilterRule, error)
+	ListFilterRules(ctx context.Context) ([]FilterRule, error)
+	CreateFilterPause(ctx context.Context, p FilterPauseCreateParameters) (FilterPause, error)
+	EndFilterPause(ctx context.Context, p FilterPauseEndParameters) (FilterPause, error)
+	ListActiveFilterPauses(ctx context.Context) ([]FilterPause, error)
+	AppendQueryLog(ctx context.Context, entries []QueryLogEntry) error
+	ListQueryLog(ctx context.Context, p QueryLogListParameters) (QueryLogPage, error)
+	// Deletes the query log entries older than before, returns the count of
+	// deleted entries.
+	DeleteQueryLogBefore(ctx context.Context, before time.Time) (int, error)
+	// Sends a snapshot of the records served by Resolve followed by changes
+	// to them. The channel is closed when ctx is done or the changes can no
+	// longer be followed, watch again to receive a new snapshot.
+	Watch(ctx context.Context) (<-chan RecordsChange, error)
 }
 
 var ErrRecordNotFound = errors.New("record not found")
 var ErrServer = errors.New("server error")
 var ErrCNAMEArgument = errors.New("CNAME must be the only record at a node")
+var ErrChangesetAction = errors.New("unknown changeset action")
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrWatchUnsupported = errors.New("watching records requires the record cache")
+
+// Wraps the error of the operation failing a changeset.
+type ChangesetOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *ChangesetOperationError) Error() string {
+	return fmt.Sprintf("changeset operation %d: %v", e.Index, e.Err)
+}
+
+func (e *ChangesetOperationError) Unwrap() error {
+	return e.Err
+}
 
 var ResolveServerError = status.Error(
 	codes.Internal,
@@ -75,7 +122,7 @@ func (s *PostgresStorage) Resolve(ctx context.Context, q DNSQuestion) (DNSRespon
 		return DNSResponse{}, ResolveServerError
 	}
 	if len(r) == 0 {
-		return DNSResponse{}, ResolveRecordNotFoundError
+		return s.resolveNegative(ctx, q.Name, subdomains)
 	}
 	maxLength := 0
 	answer := []string{}
@@ -98,6 +145,49 @@ func (s *PostgresStorage) Resolve(ctx context.Context, q DNSQuestion) (DNSRespon
 	return DNSResponse{Answer: answer}, nil
 }
 
+// Answers a question without matching records, see negativeResponse.
+// Subdomains are the ancestors of the name, excluding the root.
+func (s *PostgresStorage) resolveNegative(ctx context.Context, name string, subdomains []string) (DNSResponse, error) {
+	z, err := s.queries.ReadClosestZone(ctx, append([]string{name}, subdomains...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DNSResponse{}, ResolveRecordNotFoundError
+		}
+		return DNSResponse{}, ResolveServerError
+	}
+	atNode, err := s.queries.AnyRecordsExistAtNode(ctx, name)
+	if err != nil {
+		return DNSResponse{}, ResolveServerError
+	}
+	wildcard, err := s.queries.AnyWildcardRecordsExistAtNodes(ctx, subdomains)
+	if err != nil {
+		return DNSResponse{}, ResolveServerError
+	}
+	return negativeResponse(zoneFromRow(z), atNode || wildcard, func() (bool, error) {
+		return s.queries.AnyRecordsExistBelowNode(ctx, name)
+	})
+}
+
+// Names with records of other types, or matching wildcard records of other
+// types, get NODATA. Other names in authoritative zones get NODATA when
+// records exist below them and NXDOMAIN otherwise, while in other zones they
+// are not found so that they can be forwarded.
+// Negative answers carry the SOA of the closest zone for negative caching.
+func negativeResponse(zone Zone, exists bool, existsBelow func() (bool, error)) (DNSResponse, error) {
+	soa := ZoneSOA(zone).String()
+	if exists {
+		return DNSResponse{NS: []string{soa}}, nil
+	}
+	if !zone.Authoritative {
+		return DNSResponse{}, ResolveRecordNotFoundError
+	}
+	below, err := existsBelow()
+	if err != nil {
+		return DNSResponse{}, ResolveServerError
+	}
+	return DNSResponse{NS: []string{soa}, NXDomain: !below}, nil
+}
+
 func replaceName(rr string, newName string) (string, error) {
 	parsed, err := dns.NewRR(rr)
 	if err != nil {
@@ -112,62 +202,90 @@ type DNSQuestion struct {
 	Qtype uint16
 }
 
+// Negative answers have no answer records and the zone SOA in NS, they are
+// NODATA unless NXDomain is set.
 type DNSResponse struct {
-	Answer []string
-	NS     []string
-	Extra  []string
+	Answer   []string
+	NS       []string
+	Extra    []string
+	NXDomain bool
 }
 
-func (s *PostgresStorage) CreateRecord(ctx context.Context, p RecordCreateParameters) (Record, error) {
-	rr, err := dns.NewRR(p.RR)
-	if err != nil {
-		return Record{}, fmt.Errorf("failed to parse RR: %v", err)
-	}
+type RecordsChange struct {
+	// Nodes replace all nodes when true, otherwise only the nodes with the
+	// same names.
+	Snapshot bool
+	Nodes    []RecordNode
+	// Replace the zones with the same names, snapshots include all zones.
+	Zones []RecordZone
+}
 
-	zoneFqdn := dns.Fqdn(p.Zone)
-	rrName := dns.Fqdn(rr.Header().Name)
-	fullName := rrName + zoneFqdn
-	isWildcard := false
-	if rrName == "." {
-		fullName = zoneFqdn
-	} else if strings.HasPrefix(fullName, "*.") {
-		fullName = fullName[2:]
-		isWildcard = true
+type RecordNode struct {
+	// Fully qualified, without the leading "*." label for wildcard records.
+	Name string
+	// Empty when all records at the node were deleted.
+	Records []NodeRecord
+}
+
+// Zone settings needed to answer negative responses.
+type RecordZone struct {
+	Name          string
+	Authoritative bool
+	// Empty when the zone was deleted.
+	SOA string
+}
+
+func recordZone(zone Zone) RecordZone {
+	return RecordZone{
+		Name:          zone.Name,
+		Authoritative: zone.Authoritative,
+		SOA:           ZoneSOA(zone).String(),
 	}
+}
+
+type NodeRecord struct {
+	Type     uint16
+	Wildcard bool
+	// Owner name is relative to the zone.
+	Content string
+}
 
-	// RFC 1034 section 3.6.2: "If a CNAME RR is present at a node, no other data should be present"
+func (s *PostgresStorage) Watch(ctx context.Context) (<-chan RecordsChange, error) {
+	return nil, ErrWatchUnsupported
+}
+
+func (s *PostgresStorage) CreateRecord(ctx context.Context, p RecordCreateParameters) (Record, error) {
 	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Record{}, err
+	}
 	defer func() {
 		_ = tx.Rollback(ctx)
 	}()
+	r, err := createRecord(ctx, s.queries.WithTx(tx), p)
 	if err != nil {
 		return Record{}, err
 	}
-	if _, ok := rr.(*dns.CNAME); ok {
-		anyExist, err := s.queries.AnyRecordsExistAtNode(ctx, queries.AnyRecordsExistAtNodeParams{
-			Zone: zoneFqdn,
-			Name: fullName,
-		})
-		if err != nil {
-			return Record{}, err
-		}
-		if anyExist {
-			return Record{}, ErrCNAMEArgument
-		}
-	} else {
-		cnameExists, err := s.queries.CNAMERecordExistsAtNode(ctx, queries.CNAMERecordExistsAtNodeParams{
-			Zone: zoneFqdn,
-			Name: fullName,
-		})
-		if err != nil {
-			return Record{}, err
-		}
-		if cnameExists {
-			return Record{}, ErrCNAMEArgument
-		}
+	return r, tx.Commit(ctx)
+}
+
+func createRecord(ctx context.Context, q *queries.Queries, p RecordCreateParameters) (Record, error) {
+	rr, err := dns.NewRR(p.RR)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to parse RR: %v", err)
 	}
 
-	r, err := s.queries.CreateRecord(ctx, queries.CreateRecordParams{
+	zoneFqdn := dns.Fqdn(p.Zone)
+	if err = requireZone(ctx, q, zoneFqdn); err != nil {
+		return Record{}, err
+	}
+	fullName, isWildcard := nodeName(zoneFqdn, rr)
+	// Record IDs start at 1, no existing record is excluded.
+	if err = checkCNAMEExclusivity(ctx, q, rr, zoneFqdn, fullName, 0); err != nil {
+		return Record{}, err
+	}
+
+	r, err := q.CreateRecord(ctx, queries.CreateRecordParams{
 		Zone:       zoneFqdn,
 		Content:    p.RR,
 		Name:       fullName,
@@ -178,6 +296,9 @@ func (s *PostgresStorage) CreateRecord(ctx context.Context, p RecordCreateParame
 	if err != nil {
 		return Record{}, fmt.Errorf("faild to create record: %v", err)
 	}
+	if err = q.IncrementZoneSerial(ctx, zoneFqdn); err != nil {
+		return Record{}, err
+	}
 	return Record{
 		ID:         int(r.ID),
 		Zone:       r.Zone,
@@ -185,8 +306,54 @@ func (s *PostgresStorage) CreateRecord(ctx context.Context, p RecordCreateParame
 		Comment:    r.Comment,
 		CreatedAt:  r.CreatedAt.Time,
 		ModifiedOn: r.ModifiedOn.Time,
-	}, tx.Commit(ctx)
+	}, nil
 }
+
+// Returns the fully qualified name of the node the RR is at, with the leading
+// "*." label removed for wildcard records.
+func nodeName(zoneFqdn string, rr dns.RR) (string, bool) {
+	rrName := dns.Fqdn(rr.Header().Name)
+	if rrName == "." {
+		return zoneFqdn, false
+	}
+	fullName := rrName + zoneFqdn
+	if strings.HasPrefix(fullName, "*.") {
+		return fullName[2:], true
+	}
+	return fullName, false
+}
+
+// RFC 1034 section 3.6.2: "If a CNAME RR is present at a node, no other data should be present"
+// The record with ID excludeID is ignored, for updating records in place.
+func checkCNAMEExclusivity(ctx context.Context, q *queries.Queries, rr dns.RR, zoneFqdn string, fullName string, excludeID int32) error {
+	if _, ok := rr.(*dns.CNAME); ok {
+		anyExist, err := q.AnyOtherRecordsExistAtNode(ctx, queries.AnyOtherRecordsExistAtNodeParams{
+			Zone: zoneFqdn,
+			Name: fullName,
+			ID:   excludeID,
+		})
+		if err != nil {
+			return err
+		}
+		if anyExist {
+			return ErrCNAMEArgument
+		}
+		return nil
+	}
+	cnameExists, err := q.OtherCNAMERecordExistsAtNode(ctx, queries.OtherCNAMERecordExistsAtNodeParams{
+		Zone: zoneFqdn,
+		Name: fullName,
+		ID:   excludeID,
+	})
+	if err != nil {
+		return err
+	}
+	if cnameExists {
+		return ErrCNAMEArgument
+	}
+	return nil
+}
+
 func (s *PostgresStorage) ReadRecord(ctx context.Context, id int) (Record, error) {
 	r, err := s.queries.ReadRecord(ctx, int32(id))
 	if err != nil {
@@ -203,23 +370,40 @@ func (s *PostgresStorage) ReadRecord(ctx context.Context, id int) (Record, error
 }
 
 func (s *PostgresStorage) UpdateRecord(ctx context.Context, p RecordUpdateParameters) (Record, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	r, err := updateRecord(ctx, s.queries.WithTx(tx), p)
+	if err != nil {
+		return Record{}, err
+	}
+	return r, tx.Commit(ctx)
+}
+
+func updateRecord(ctx context.Context, q *queries.Queries, p RecordUpdateParameters) (Record, error) {
 	rr, err := dns.NewRR(p.RR)
 	if err != nil {
 		return Record{}, fmt.Errorf("failed to parse RR: %v", err)
 	}
 
+	existing, err := q.ReadRecord(ctx, int32(p.ID))
+	if err != nil {
+		return Record{}, ErrRecordNotFound
+	}
 	zoneFqdn := dns.Fqdn(p.Zone)
-	rrName := dns.Fqdn(rr.Header().Name)
-	fullName := rrName + zoneFqdn
-	isWildcard := false
-	if rrName == "." {
-		fullName = zoneFqdn
-	} else if strings.HasPrefix(fullName, "*.") {
-		fullName = fullName[2:]
-		isWildcard = true
+	if err = requireZone(ctx, q, zoneFqdn); err != nil {
+		return Record{}, err
+	}
+	fullName, isWildcard := nodeName(zoneFqdn, rr)
+	if err = checkCNAMEExclusivity(ctx, q, rr, zoneFqdn, fullName, int32(p.ID)); err != nil {
+		return Record{}, err
 	}
 
-	r, err := s.queries.UpdateRecord(ctx, queries.UpdateRecordParams{
+	r, err := q.UpdateRecord(ctx, queries.UpdateRecordParams{
 		ID:         int32(p.ID),
 		Zone:       zoneFqdn,
 		Content:    p.RR,
@@ -231,6 +415,15 @@ func (s *PostgresStorage) UpdateRecord(ctx context.Context, p RecordUpdateParame
 	if err != nil {
 		return Record{}, ErrRecordNotFound
 	}
+	if err = q.IncrementZoneSerial(ctx, zoneFqdn); err != nil {
+		return Record{}, err
+	}
+	if existing.Zone != zoneFqdn {
+		// Record moved between zones, both zones changed.
+		if err = q.IncrementZoneSerial(ctx, existing.Zone); err != nil {
+			return Record{}, err
+		}
+	}
 	return Record{
 		ID:         int(r.ID),
 		Zone:       r.Zone,
@@ -242,10 +435,28 @@ func (s *PostgresStorage) UpdateRecord(ctx context.Context, p RecordUpdateParame
 }
 
 func (s *PostgresStorage) DeleteRecord(ctx context.Context, id int) (Record, error) {
-	r, err := s.queries.DeleteRecord(ctx, int32(id))
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	r, err := deleteRecord(ctx, s.queries.WithTx(tx), id)
+	if err != nil {
+		return Record{}, err
+	}
+	return r, tx.Commit(ctx)
+}
+
+func deleteRecord(ctx context.Context, q *queries.Queries, id int) (Record, error) {
+	r, err := q.DeleteRecord(ctx, int32(id))
 	if err != nil {
 		return Record{}, ErrRecordNotFound
 	}
+	if err = q.IncrementZoneSerial(ctx, r.Zone); err != nil {
+		return Record{}, err
+	}
 	return Record{
 		ID:         int(r.ID),
 		Zone:       r.Zone,
@@ -256,6 +467,66 @@ func (s *PostgresStorage) DeleteRecord(ctx context.Context, id int) (Record, err
 	}, nil
 }
 
+// Applies all operations in order in a single transaction.
+// Either all operations are applied or none of them are.
+// The records changed by update and delete operations are locked and passed
+// to authorize within the transaction, so they cannot change after being
+// authorized.
+// Returns the created, updated, and deleted records in the order of operations.
+func (s *PostgresStorage) ApplyChangeset(ctx context.Context, operations []ChangesetOperation, authorize ChangesetAuthorizer) ([]Record, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return []Record{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	q := s.queries.WithTx(tx)
+	records := make([]Record, len(operations))
+	for i, op := range operations {
+		if op.Action == ChangesetUpdate || op.Action == ChangesetDelete {
+			existing, err := q.ReadRecordForUpdate(ctx, int32(op.ID))
+			if err != nil {
+				return []Record{}, &ChangesetOperationError{Index: i, Err: ErrRecordNotFound}
+			}
+			err = authorize(i, Record{
+				ID:         int(existing.ID),
+				Zone:       existing.Zone,
+				RR:         existing.Content,
+				Comment:    existing.Comment,
+				CreatedAt:  existing.CreatedAt.Time,
+				ModifiedOn: existing.ModifiedOn.Time,
+			})
+			if err != nil {
+				return []Record{}, &ChangesetOperationError{Index: i, Err: err}
+			}
+		}
+		switch op.Action {
+		case ChangesetCreate:
+			records[i], err = createRecord(ctx, q, RecordCreateParameters{
+				Zone:    op.Zone,
+				RR:      op.RR,
+				Comment: op.Comment,
+			})
+		case ChangesetUpdate:
+			records[i], err = updateRecord(ctx, q, RecordUpdateParameters{
+				ID:      op.ID,
+				Zone:    op.Zone,
+				RR:      op.RR,
+				Comment: op.Comment,
+			})
+		case ChangesetDelete:
+			records[i], err = deleteRecord(ctx, q, op.ID)
+		default:
+			err = ErrChangesetAction
+		}
+		if err != nil {
+			return []Record{}, &ChangesetOperationError{Index: i, Err: err}
+		}
+	}
+	return records, tx.Commit(ctx)
+}
+
 func (s *PostgresStorage) ListRecords(ctx context.Context, zone string) ([]Record, error) {
 	r, err := s.queries.ListRecords(ctx, zone)
 	if err != nil {
@@ -275,6 +546,156 @@ func (s *PostgresStorage) ListRecords(ctx context.Context, zone string) ([]Recor
 	return records, nil
 }
 
+// Returns a page of records in the zone matching the filters, sorted by
+// p.Sort. The next page is listed by passing RecordPage.NextCursor as p.Cursor.
+func (s *PostgresStorage) ListRecordsPage(ctx context.Context, p RecordListParameters) (RecordPage, error) {
+	cursor, err := decodeRecordCursor(p.Cursor, p.Sort)
+	if err != nil {
+		return RecordPage{}, err
+	}
+	var name pgtype.Text
+	if p.Name != "" {
+		name = pgtype.Text{String: dns.Fqdn(p.Name), Valid: true}
+	}
+	var rtype pgtype.Int4
+	if p.Type != 0 {
+		rtype = pgtype.Int4{Int32: int32(p.Type), Valid: true}
+	}
+	var isWildcard pgtype.Bool
+	if p.IsWildcard != nil {
+		isWildcard = pgtype.Bool{Bool: *p.IsWildcard, Valid: true}
+	}
+	var comment pgtype.Text
+	if p.Comment != "" {
+		comment = pgtype.Text{String: p.Comment, Valid: true}
+	}
+	var modifiedSince pgtype.Timestamptz
+	if !p.ModifiedSince.IsZero() {
+		modifiedSince = pgtype.Timestamptz{Time: p.ModifiedSince, Valid: true}
+	}
+
+	// One extra record is fetched to know whether there is a next page.
+	pageSize := int32(p.PageSize + 1)
+	if p.PageSize == 0 {
+		pageSize = math.MaxInt32
+	}
+	var rows []queries.Record
+	switch p.Sort {
+	case RecordSortName:
+		rows, err = s.queries.ListRecordsPageByName(ctx, queries.ListRecordsPageByNameParams{
+			Zone:          dns.Fqdn(p.Zone),
+			Name:          name,
+			Type:          rtype,
+			IsWildcard:    isWildcard,
+			Comment:       comment,
+			ModifiedSince: modifiedSince,
+			AfterName:     cursor.Name,
+			AfterID:       int32(cursor.ID),
+			PageSize:      pageSize,
+		})
+	default:
+		rows, err = s.queries.ListRecordsPageByID(ctx, queries.ListRecordsPageByIDParams{
+			Zone:          dns.Fqdn(p.Zone),
+			Name:          name,
+			Type:          rtype,
+			IsWildcard:    isWildcard,
+			Comment:       comment,
+			ModifiedSince: modifiedSince,
+			AfterID:       int32(cursor.ID),
+			PageSize:      pageSize,
+		})
+	}
+	if err != nil {
+		return RecordPage{}, ErrServer
+	}
+
+	page := RecordPage{}
+	if p.PageSize != 0 && p.PageSize < len(rows) {
+		rows = rows[:p.PageSize]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeRecordCursor(recordCursor{
+			Sort: p.Sort,
+			ID:   int(last.ID),
+			Name: last.Name,
+		})
+	}
+	page.Records = make([]Record, len(rows))
+	for i, record := range rows {
+		page.Records[i] = Record{
+			ID:         int(record.ID),
+			Zone:       record.Zone,
+			RR:         record.Content,
+			Comment:    record.Comment,
+			CreatedAt:  record.CreatedAt.Time,
+			ModifiedOn: record.ModifiedOn.Time,
+		}
+	}
+	return page, nil
+}
+
+// Position after the last record of a page.
+type recordCursor struct {
+	Sort RecordSort `json:"s"`
+	ID   int        `json:"i"`
+	Name string     `json:"n,omitempty"`
+}
+
+func encodeRecordCursor(c recordCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// An empty cursor starts from the first page.
+func decodeRecordCursor(cursor string, sort RecordSort) (recordCursor, error) {
+	if cursor == "" {
+		return recordCursor{Sort: sort}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return recordCursor{}, ErrInvalidCursor
+	}
+	var c recordCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return recordCursor{}, ErrInvalidCursor
+	}
+	// Cursors are only valid for the sort order they were created with.
+	if c.Sort != sort {
+		return recordCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+type RecordSort string
+
+const (
+	RecordSortID   RecordSort = "id"
+	RecordSortName RecordSort = "name"
+)
+
+type RecordListParameters struct {
+	Zone string
+	// Fully qualified name of the node, wildcard records are at the node
+	// without the leading "*." label.
+	Name string
+	// Any type when zero.
+	Type uint16
+	// Both wildcard and non-wildcard records when nil.
+	IsWildcard *bool
+	// Case insensitive comment substring.
+	Comment       string
+	ModifiedSince time.Time
+	Sort          RecordSort
+	// All matching records are listed in a single page when zero.
+	PageSize int
+	Cursor   string
+}
+
+type RecordPage struct {
+	Records []Record
+	// Empty on the last page.
+	NextCursor string
+}
+
 type RecordCreateParameters struct {
 	Zone    string
 	RR      string
@@ -296,3 +717,25 @@ type Record struct {
 	CreatedAt  time.Time
 	ModifiedOn time.Time
 }
+
+type ChangesetAction string
+
+const (
+	ChangesetCreate ChangesetAction = "create"
+	ChangesetUpdate ChangesetAction = "update"
+	ChangesetDelete ChangesetAction = "delete"
+)
+
+// Authorizes changing the existing record of the update or delete operation
+// at index. No operation is applied when it returns an error.
+type ChangesetAuthorizer func(index int, existing Record) error
+
+type ChangesetOperation struct {
+	Action ChangesetAction
+	// Record ID, used by update and delete operations.
+	ID int
+	// Used by create and update operations.
+	Zone    string
+	RR      string
+	Comment string
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/database/queries"
+)
+
+var ErrZoneNotFound = errors.New("zone not found")
+var ErrZoneExists = errors.New("zone already exists")
+var ErrZoneNotEmpty = errors.New("zone has records")
+
+// Wraps the error importing the record at Index of ZoneImportParameters.Records.
+type ZoneImportRecordError struct {
+	Index int
+	Err   error
+}
+
+func (e *ZoneImportRecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+func (e *ZoneImportRecordError) Unwrap() error {
+	return e.Err
+}
+
+// Postgres unique_violation error code.
+const uniqueViolationCode = "23505"
+
+const DefaultZoneTTL = 3600
+
+func (s *PostgresStorage) CreateZone(ctx context.Context, p ZoneCreateParameters) (Zone, error) {
+	defaultTTL := p.DefaultTTL
+	if defaultTTL == 0 {
+		defaultTTL = DefaultZoneTTL
+	}
+	z, err := s.queries.CreateZone(ctx, queries.CreateZoneParams{
+		Name:          dns.Fqdn(p.Name),
+		DefaultTtl:    int32(defaultTTL),
+		Nameservers:   fqdns(p.Nameservers),
+		Authoritative: p.Authoritative,
+		Comment:       p.Comment,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return Zone{}, ErrZoneExists
+		}
+		return Zone{}, ErrServer
+	}
+	return zoneFromRow(z), nil
+}
+
+func (s *PostgresStorage) ReadZone(ctx context.Context, name string) (Zone, error) {
+	z, err := s.queries.ReadZone(ctx, dns.Fqdn(name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Zone{}, ErrZoneNotFound
+		}
+		return Zone{}, ErrServer
+	}
+	return zoneFromRow(z), nil
+}
+
+// Updating a zone increments its SOA serial.
+func (s *PostgresStorage) UpdateZone(ctx context.Context, p ZoneUpdateParameters) (Zone, error) {
+	defaultTTL := p.DefaultTTL
+	if defaultTTL == 0 {
+		defaultTTL = DefaultZoneTTL
+	}
+	z, err := s.queries.UpdateZone(ctx, queries.UpdateZoneParams{
+		Name:          dns.Fqdn(p.Name),
+		DefaultTtl:    int32(defaultTTL),
+		Nameservers:   fqdns(p.Nameservers),
+		Authoritative: p.Authoritative,
+		Comment:       p.Comment,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Zone{}, ErrZoneNotFound
+		}
+		return Zone{}, ErrServer
+	}
+	return zoneFromRow(z), nil
+}
+
+// Only empty zones can be deleted, records must be deleted first.
+func (s *PostgresStorage) DeleteZone(ctx context.Context, name string) (Zone, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Zone{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	q := s.queries.WithTx(tx)
+	name = dns.Fqdn(name)
+	anyExist, err := q.AnyRecordsExistInZone(ctx, name)
+	if err != nil {
+		return Zone{}, ErrServer
+	}
+	if anyExist {
+		return Zone{}, ErrZoneNotEmpty
+	}
+	z, err := q.DeleteZone(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Zone{}, ErrZoneNotFound
+		}
+		return Zone{}, ErrServer
+	}
+	return zoneFromRow(z), tx.Commit(ctx)
+}
+
+func (s *PostgresStorage) ListZones(ctx context.Context) ([]Zone, error) {
+	z, err := s.queries.ListZones(ctx)
+	if err != nil {
+		return []Zone{}, ErrServer
+	}
+	zones := make([]Zone, len(z))
+	for i, zone := range z {
+		zones[i] = zoneFromRow(zone)
+	}
+	return zones, nil
+}
+
+// Imports records into the zone in a single transaction.
+// Records with the same content as an existing record are left unchanged,
+// when replacing existing records missing from the import are deleted.
+func (s *PostgresStorage) ImportZone(ctx context.Context, p ZoneImportParameters) (ZoneImportResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return ZoneImportResult{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	q := s.queries.WithTx(tx)
+	zoneFqdn := dns.Fqdn(p.Zone)
+	if err = requireZone(ctx, q, zoneFqdn); err != nil {
+		return ZoneImportResult{}, err
+	}
+	existing, err := q.ListRecords(ctx, zoneFqdn)
+	if err != nil {
+		return ZoneImportResult{}, ErrServer
+	}
+
+	imported := map[string]bool{}
+	for _, r := range p.Records {
+		imported[normalizeRR(r.RR)] = true
+	}
+	result := ZoneImportResult{}
+	present := map[string]bool{}
+	// Deletions are applied first so that imported CNAME records do not
+	// conflict with the records they replace.
+	for _, r := range existing {
+		content := normalizeRR(r.Content)
+		if p.Replace && !imported[content] {
+			if _, err = deleteRecord(ctx, q, int(r.ID)); err != nil {
+				return ZoneImportResult{}, err
+			}
+			result.Deleted++
+			continue
+		}
+		present[content] = true
+	}
+	for i, r := range p.Records {
+		content := normalizeRR(r.RR)
+		if present[content] {
+			result.Unchanged++
+			continue
+		}
+		_, err = createRecord(ctx, q, RecordCreateParameters{
+			Zone:    zoneFqdn,
+			RR:      r.RR,
+			Comment: r.Comment,
+		})
+		if err != nil {
+			return ZoneImportResult{}, &ZoneImportRecordError{Index: i, Err: err}
+		}
+		present[content] = true
+		result.Created++
+	}
+	return result, tx.Commit(ctx)
+}
+
+// Formats the RR in presentation format for comparing record content.
+func normalizeRR(content string) string {
+	rr, err := dns.NewRR(content)
+	if err != nil || rr == nil {
+		return content
+	}
+	return rr.String()
+}
+
+// Returns ErrZoneNotFound when the zone does not exist.
+func requireZone(ctx context.Context, q *queries.Queries, zone string) error {
+	_, err := q.ReadZone(ctx, zone)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrZoneNotFound
+	}
+	return err
+}
+
+func zoneFromRow(z queries.Zone) Zone {
+	return Zone{
+		ID:            int(z.ID),
+		Name:          z.Name,
+		DefaultTTL:    int(z.DefaultTtl),
+		SOASerial:     uint32(z.SoaSerial),
+		Nameservers:   z.Nameservers,
+		Authoritative: z.Authoritative,
+		Comment:       z.Comment,
+		CreatedAt:     z.CreatedAt.Time,
+		ModifiedOn:    z.ModifiedOn.Time,
+	}
+}
+
+// Generated from the zone settings, refresh, retry and expire use the
+// RFC 1912 recommendations. The minimum is the default TTL, so that negative
+// answers are cached like records (RFC 2308).
+func ZoneSOA(zone Zone) *dns.SOA {
+	mname := zone.Name
+	if 0 < len(zone.Nameservers) {
+		mname = zone.Nameservers[0]
+	}
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   zone.Name,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(zone.DefaultTTL),
+		},
+		Ns:      mname,
+		Mbox:    "hostmaster." + zone.Name,
+		Serial:  zone.SOASerial,
+		Refresh: 7200,
+		Retry:   3600,
+		Expire:  1209600,
+		Minttl:  uint32(zone.DefaultTTL),
+	}
+}
+
+func fqdns(names []string) []string {
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = dns.Fqdn(name)
+	}
+	return result
+}
+
+type ZoneCreateParameters struct {
+	Name string
+	// DefaultZoneTTL is used when zero.
+	DefaultTTL  int
+	Nameservers []string
+	// Answer NXDOMAIN for names without records instead of forwarding them.
+	Authoritative bool
+	Comment       string
+}
+
+type ZoneUpdateParameters struct {
+	Name string
+	// DefaultZoneTTL is used when zero.
+	DefaultTTL  int
+	Nameservers []string
+	// Answer NXDOMAIN for names without records instead of forwarding them.
+	Authoritative bool
+	Comment       string
+}
+
+type Zone struct {
+	ID         int
+	Name       string
+	DefaultTTL int
+	// Incremented on every change to the zone or its records.
+	SOASerial     uint32
+	Nameservers   []string
+	Authoritative bool
+	Comment       string
+	CreatedAt     time.Time
+	ModifiedOn    time.Time
+}
+
+type ZoneImportRecord struct {
+	// Owner name is relative to the zone, like RecordCreateParameters.RR.
+	RR      string
+	Comment string
+}
+
+type ZoneImportParameters struct {
+	Zone    string
+	Records []ZoneImportRecord
+	// Merge into the existing records when false.
+	Replace bool
+}
+
+type ZoneImportResult struct {
+	Created   int
+	Deleted   int
+	Unchanged int
+}
@@ -0,0 +1,36 @@
+package zones
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+type service struct {
+	enforcer enforcer.RequestEnforcer
+	// Import and export read and edit the records of a zone.
+	recordsEnforcer enforcer.RequestEnforcer
+	handler         ZonesStorage
+	logger          *zap.Logger
+}
+
+func Register(r *chi.Mux, e enforcer.Enforcer, s storage.Storage, l *zap.Logger, a auth.Service) {
+	sz := service{
+		enforcer:        enforcer.NewRequestEnforcer(e, "zones"),
+		recordsEnforcer: enforcer.NewRequestEnforcer(e, "records"),
+		handler:         s,
+		logger:          l,
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(a.Middleware())
+		r.Get("/v1/zones", sz.HandleList())
+		r.Post("/v1/zones", sz.HandleCreate())
+		r.Get("/v1/zones/{zone}", sz.HandleRead())
+		r.Put("/v1/zones/{zone}", sz.HandleUpdate())
+		r.Delete("/v1/zones/{zone}", sz.HandleDelete())
+		r.Get("/v1/zones/{zone}/export", sz.HandleExport())
+		r.Post("/v1/zones/{zone}/import", sz.HandleImport())
+	})
+}
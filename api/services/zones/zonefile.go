@@ -0,0 +1,280 @@
+package zones
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+// RFC 4027 media type for master files.
+const zoneFileContentType = "text/dns"
+
+// Parsing stops after this many errors.
+const maxZoneFileErrors = 100
+
+const (
+	ImportModeMerge   = "merge"
+	ImportModeReplace = "replace"
+)
+
+func (s service) HandleExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authorizeZoneParam(w, r, s.recordsEnforcer)
+		if !ok {
+			return
+		}
+		zone, err := s.handler.ReadZone(r.Context(), name)
+		if err != nil {
+			s.logger.Error("failed to read zone", zap.Error(err))
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		records, err := s.handler.ListRecords(r.Context(), name)
+		if err != nil {
+			s.logger.Error("failed to list records", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		zoneFile, err := renderZoneFile(zone, records)
+		if err != nil {
+			s.logger.Error("failed to render zone file", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", zoneFileContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(zoneFile))
+	}
+}
+
+func (s service) HandleImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authorizeZoneParam(w, r, s.recordsEnforcer)
+		if !ok {
+			return
+		}
+		data := &ZoneImportRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		zone, err := s.handler.ReadZone(r.Context(), name)
+		if err != nil {
+			s.logger.Error("failed to read zone", zap.Error(err))
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		records, fieldErrors := parseZoneFile(zone, data.Content)
+		if 0 < len(fieldErrors) {
+			s.logger.Error("failed to parse zone file", zap.String("zone", name), zap.Int("errors", len(fieldErrors)))
+			rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+				Fields: fieldErrors,
+			})
+			return
+		}
+		result, err := s.handler.ImportZone(r.Context(), storage.ZoneImportParameters{
+			Zone:    name,
+			Records: records,
+			Replace: data.Mode == ImportModeReplace,
+		})
+		if err != nil {
+			s.logger.Error("failed to import zone", zap.String("zone", name), zap.Error(err))
+			var recordErr *storage.ZoneImportRecordError
+			if errors.As(err, &recordErr) {
+				message := "invalid record"
+				if errors.Is(err, storage.ErrCNAMEArgument) {
+					message = "CNAME record must be the only record in the node"
+				}
+				rest.RenderError(w, r, &rest.BadRequestErrorResponse{
+					Fields: []rest.KeyError{
+						{
+							Key:     "content",
+							Message: fmt.Sprintf("%s: %s", records[recordErr.Index].RR, message),
+						},
+					},
+				})
+				return
+			}
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info("imported zone",
+			zap.String("zone", name),
+			zap.String("mode", data.Mode),
+			zap.Int("created", result.Created),
+			zap.Int("deleted", result.Deleted),
+		)
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, ZoneImportResponse{
+			Created:   result.Created,
+			Deleted:   result.Deleted,
+			Unchanged: result.Unchanged,
+		})
+	}
+}
+
+type ZoneImportRequest struct {
+	// RFC 1035 master file.
+	Content string `json:"content"`
+	// ImportModeMerge when empty.
+	Mode string `json:"mode,omitempty"`
+}
+
+func (zi *ZoneImportRequest) Bind(r *http.Request) error {
+	fieldErrors := []rest.KeyError{}
+	if zi.Content == "" {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "content",
+			Message: "required",
+		})
+	}
+	if zi.Mode == "" {
+		zi.Mode = ImportModeMerge
+	}
+	if zi.Mode != ImportModeMerge && zi.Mode != ImportModeReplace {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "mode",
+			Message: "must be one of 'merge' or 'replace'",
+		})
+	}
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
+
+type ZoneImportResponse struct {
+	Created   int `json:"created"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Renders the zone as an RFC 1035 master file, starting with an SOA record
+// generated from the zone settings. Record comments are kept as line comments.
+func renderZoneFile(zone storage.Zone, records []storage.Record) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ORIGIN %s\n", zone.Name)
+	fmt.Fprintf(&b, "$TTL %d\n", zone.DefaultTTL)
+	b.WriteString(storage.ZoneSOA(zone).String())
+	b.WriteString("\n")
+	for _, record := range records {
+		rr, err := dns.NewRR(record.RR)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse record %d: %v", record.ID, err)
+		}
+		// Stored owner names are relative to the zone.
+		if rr.Header().Name == "." {
+			rr.Header().Name = zone.Name
+		} else {
+			rr.Header().Name += zone.Name
+		}
+		b.WriteString(rr.String())
+		if record.Comment != "" {
+			b.WriteString(" ; ")
+			b.WriteString(strings.Join(strings.Fields(record.Comment), " "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// Matches the position suffix of dns.ParseError messages.
+var parseErrorLinePattern = regexp.MustCompile(`^(?:dns: )?(.*) at line: (\d+):\d+$`)
+
+// Counts the lines read by a dns.ZoneParser. The parser reads a record up to
+// its closing newline, so the count is the last line of the previous record.
+type lineCountingReader struct {
+	*strings.Reader
+	lines int
+}
+
+func (r *lineCountingReader) ReadByte() (byte, error) {
+	c, err := r.Reader.ReadByte()
+	if err == nil && c == '\n' {
+		r.lines++
+	}
+	return c, err
+}
+
+// Returns the line number of the first line after index start holding a
+// record, skipping blank lines, comments and directives.
+func recordLine(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !strings.HasPrefix(line, ";") && !strings.HasPrefix(line, "$") {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
+
+// Parses an RFC 1035 master file into records with owner names relative to
+// the zone. SOA records are skipped, the SOA is generated from zone settings.
+// Records outside the zone are all reported, parsing stops at the first syntax
+// error as the following lines depend on the parser state. Errors have keys in
+// the form "content:<line>".
+func parseZoneFile(zone storage.Zone, content string) ([]storage.ZoneImportRecord, []rest.KeyError) {
+	records := []storage.ZoneImportRecord{}
+	fieldErrors := []rest.KeyError{}
+	lines := strings.Split(content, "\n")
+	r := &lineCountingReader{Reader: strings.NewReader(content)}
+	zp := dns.NewZoneParser(r, zone.Name, "")
+	zp.SetDefaultTTL(uint32(zone.DefaultTTL))
+	previousEnd := 0
+	for rr, ok := zp.Next(); ok && len(fieldErrors) < maxZoneFileErrors; rr, ok = zp.Next() {
+		line := recordLine(lines, previousEnd)
+		previousEnd = r.lines
+		if _, isSOA := rr.(*dns.SOA); isSOA {
+			continue
+		}
+		name := dns.CanonicalName(rr.Header().Name)
+		if !dns.IsSubDomain(zone.Name, name) {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     fmt.Sprintf("content:%d", line),
+				Message: fmt.Sprintf("%s is not in zone %s", name, zone.Name),
+			})
+			continue
+		}
+		rr.Header().Name = strings.TrimSuffix(name, zone.Name)
+		if rr.Header().Name == "" {
+			rr.Header().Name = "."
+		}
+		records = append(records, storage.ZoneImportRecord{
+			RR:      rr.String(),
+			Comment: strings.TrimSpace(strings.TrimPrefix(zp.Comment(), ";")),
+		})
+	}
+	if err := zp.Err(); err != nil && len(fieldErrors) < maxZoneFileErrors {
+		key := "content"
+		message := err.Error()
+		if match := parseErrorLinePattern.FindStringSubmatch(message); match != nil {
+			key = "content:" + match[2]
+			message = match[1]
+		}
+		fieldErrors = append(fieldErrors, rest.KeyError{Key: key, Message: message})
+	}
+	return records, fieldErrors
+}
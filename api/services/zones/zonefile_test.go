@@ -0,0 +1,196 @@
+package zones
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+)
+
+func TestImportExportZone(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	response := importTestZone(t, h, "merge", `$TTL 300
+@	IN	A	127.0.0.1 ; apex
+www	IN	CNAME	example.com.
+*.wild.example.com.	600	IN	TXT	"hello"
+`)
+	if response.Created != 3 {
+		t.Errorf("Expected 3 created records, got %d", response.Created)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones/example.com./export", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	if contentType := w.Result().Header.Get("Content-Type"); contentType != "text/dns" {
+		t.Errorf("Expected Content-Type to be 'text/dns', got '%s'", contentType)
+	}
+	expected := "$ORIGIN example.com.\n" +
+		"$TTL 3600\n" +
+		"example.com.\t3600\tIN\tSOA\texample.com. hostmaster.example.com. 1 7200 3600 1209600 3600\n" +
+		"example.com.\t300\tIN\tA\t127.0.0.1 ; apex\n" +
+		"www.example.com.\t300\tIN\tCNAME\texample.com.\n" +
+		"*.wild.example.com.\t600\tIN\tTXT\t\"hello\"\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected export to be:\n%s\ngot:\n%s", expected, w.Body.String())
+	}
+
+	// Importing the export again changes nothing.
+	response = importTestZone(t, h, "replace", w.Body.String())
+	if response.Created != 0 || response.Deleted != 0 || response.Unchanged != 3 {
+		t.Errorf("Expected 3 unchanged records, got %+v", response)
+	}
+}
+
+func TestImportZoneReplace(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	importTestZone(t, h, "merge", "foo IN A 127.0.0.1\nbar IN A 127.0.0.2\n")
+	response := importTestZone(t, h, "replace", "bar IN A 127.0.0.2\nbaz IN A 127.0.0.3\n")
+	if response.Created != 1 || response.Deleted != 1 || response.Unchanged != 1 {
+		t.Errorf("Expected 1 created, 1 deleted and 1 unchanged record, got %+v", response)
+	}
+	response = importTestZone(t, h, "merge", "foo IN A 127.0.0.1\n")
+	if response.Created != 1 || response.Deleted != 0 {
+		t.Errorf("Expected 1 created and 0 deleted records, got %+v", response)
+	}
+}
+
+func TestImportZoneParseErrors(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	body, _ := json.Marshal(ZoneImportRequest{
+		Content: "foo IN A 127.0.0.1\nbar IN A nope\nbaz IN A 127.0.0.3\nqux IN AAAA 127.0.0.4\n",
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/zones/example.com./import", strings.NewReader(string(body)))
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Parsing stops at the first syntax error.
+	expectedKeys := []string{"content:2"}
+	if len(response.Fields) != len(expectedKeys) {
+		t.Fatalf("Expected fields to be of length %d, got %v", len(expectedKeys), response.Fields)
+	}
+	for i, key := range expectedKeys {
+		if response.Fields[i].Key != key {
+			t.Errorf("Expected fields[%d].key to be '%s', got '%s'", i, key, response.Fields[i].Key)
+		}
+	}
+}
+
+func TestImportZoneOutOfZone(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	body, _ := json.Marshal(ZoneImportRequest{
+		Content: "foo.example.net. IN A 127.0.0.1\n",
+		Mode:    "replace",
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/zones/example.com./import", strings.NewReader(string(body)))
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	zone := storage.Zone{Name: "example.com.", DefaultTTL: 300}
+	content := "$ORIGIN sub.example.com.\n" +
+		"$TTL 60\n" +
+		"www IN A 127.0.0.1\n" +
+		"\n" +
+		"foo.example.net. IN A 127.0.0.2\n" +
+		"; comment\n" +
+		"  IN A 127.0.0.3\n" +
+		"bar IN A nope\n" +
+		"baz IN AAAA 127.0.0.4\n"
+	records, fieldErrors := parseZoneFile(zone, content)
+	if len(records) != 1 || records[0].RR != "www.sub.\t60\tIN\tA\t127.0.0.1" {
+		t.Errorf("Expected the www.sub record, got %v", records)
+	}
+	expectedKeys := []string{"content:5", "content:7", "content:8"}
+	if len(fieldErrors) != len(expectedKeys) {
+		t.Fatalf("Expected fields to be of length %d, got %v", len(expectedKeys), fieldErrors)
+	}
+	for i, key := range expectedKeys {
+		if fieldErrors[i].Key != key {
+			t.Errorf("Expected fields[%d].key to be '%s', got '%s'", i, key, fieldErrors[i].Key)
+		}
+	}
+}
+
+func TestImportZoneNotFound(t *testing.T) {
+	h := createTestHandler(nil)
+	body, _ := json.Marshal(ZoneImportRequest{
+		Content: "foo IN A 127.0.0.1\n",
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/zones/example.com./import", strings.NewReader(string(body)))
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestExportZoneForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "bob", "example.net.")
+	w := httptest.NewRecorder()
+	// Bob can manage the zone but not its records.
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones/example.net./export", nil)
+	auth.MockLogin(r, "bob")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func importTestZone(t *testing.T, h http.Handler, mode string, content string) ZoneImportResponse {
+	body, err := json.Marshal(ZoneImportRequest{
+		Content: content,
+		Mode:    mode,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/zones/example.com./import", strings.NewReader(string(body)))
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 importing test zone, got %d: %s", w.Result().StatusCode, w.Body.String())
+	}
+	var response ZoneImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return response
+}
@@ -0,0 +1,295 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/zap"
+)
+
+type ZonesStorage interface {
+	CreateZone(ctx context.Context, p storage.ZoneCreateParameters) (storage.Zone, error)
+	ReadZone(ctx context.Context, name string) (storage.Zone, error)
+	UpdateZone(ctx context.Context, p storage.ZoneUpdateParameters) (storage.Zone, error)
+	DeleteZone(ctx context.Context, name string) (storage.Zone, error)
+	ListZones(ctx context.Context) ([]storage.Zone, error)
+	ListRecords(ctx context.Context, zone string) ([]storage.Record, error)
+	ImportZone(ctx context.Context, p storage.ZoneImportParameters) (storage.ZoneImportResult, error)
+}
+
+func (s service) HandleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := &ZoneCreateRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		ok, err := s.enforcer.IsAuthorized(r, data.Name)
+		if err != nil {
+			s.logger.Error("failed to enforce action", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		if !ok {
+			s.logger.Error("unauthorized", zap.Error(err))
+			rest.RenderError(w, r, &rest.ForbiddenError)
+			return
+		}
+		zone, err := s.handler.CreateZone(r.Context(), storage.ZoneCreateParameters{
+			Name:          data.Name,
+			DefaultTTL:    data.DefaultTTL,
+			Nameservers:   data.Nameservers,
+			Authoritative: data.Authoritative,
+			Comment:       data.Comment,
+		})
+		if err != nil {
+			if errors.Is(err, storage.ErrZoneExists) {
+				s.logger.Error("failed to create zone because it already exists", zap.String("zone", data.Name))
+				rest.RenderError(w, r, &rest.ConflictError)
+				return
+			}
+			s.logger.Error("failed to create zone", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		s.logger.Info("created zone", zap.String("zone", zone.Name))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, newZoneResponse(zone))
+	}
+}
+
+type ZoneCreateRequest struct {
+	Name string `json:"name"`
+	// Zero uses the storage default TTL.
+	DefaultTTL    int      `json:"defaultTtl,omitempty"`
+	Nameservers   []string `json:"nameservers,omitempty"`
+	Authoritative bool     `json:"authoritative,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+}
+
+func (zc *ZoneCreateRequest) Bind(r *http.Request) error {
+	fieldErrors := []rest.KeyError{}
+	if zc.Name == "" {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "name",
+			Message: "required",
+		})
+	} else if !dns.IsFqdn(zc.Name) {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "name",
+			Message: "must end with '.'",
+		})
+	}
+	fieldErrors = append(fieldErrors, validateSettings(zc.DefaultTTL, zc.Nameservers)...)
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
+
+type ZoneUpdateRequest struct {
+	// Zero uses the storage default TTL.
+	DefaultTTL    int      `json:"defaultTtl,omitempty"`
+	Nameservers   []string `json:"nameservers,omitempty"`
+	Authoritative bool     `json:"authoritative,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+}
+
+func (zu *ZoneUpdateRequest) Bind(r *http.Request) error {
+	fieldErrors := validateSettings(zu.DefaultTTL, zu.Nameservers)
+	if 0 < len(fieldErrors) {
+		return &rest.BadRequestErrorResponse{
+			Fields: fieldErrors,
+		}
+	}
+	return nil
+}
+
+func validateSettings(defaultTTL int, nameservers []string) []rest.KeyError {
+	fieldErrors := []rest.KeyError{}
+	if defaultTTL < 0 {
+		fieldErrors = append(fieldErrors, rest.KeyError{
+			Key:     "defaultTtl",
+			Message: "must not be negative",
+		})
+	}
+	for i, ns := range nameservers {
+		if _, ok := dns.IsDomainName(ns); !ok || !dns.IsFqdn(ns) {
+			fieldErrors = append(fieldErrors, rest.KeyError{
+				Key:     fmt.Sprintf("nameservers[%d]", i),
+				Message: "must be a domain name ending with '.'",
+			})
+		}
+	}
+	return fieldErrors
+}
+
+func (s service) HandleRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authorizeZoneParam(w, r, s.enforcer)
+		if !ok {
+			return
+		}
+		zone, err := s.handler.ReadZone(r.Context(), name)
+		if err != nil {
+			s.logger.Error("failed to read zone", zap.Error(err))
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newZoneResponse(zone))
+	}
+}
+
+func (s service) HandleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authorizeZoneParam(w, r, s.enforcer)
+		if !ok {
+			return
+		}
+		data := &ZoneUpdateRequest{}
+		if err := render.Bind(r, data); err != nil {
+			s.logger.Error("failed to bind body", zap.Error(err))
+			rest.RenderError(w, r, err)
+			return
+		}
+		zone, err := s.handler.UpdateZone(r.Context(), storage.ZoneUpdateParameters{
+			Name:          name,
+			DefaultTTL:    data.DefaultTTL,
+			Nameservers:   data.Nameservers,
+			Authoritative: data.Authoritative,
+			Comment:       data.Comment,
+		})
+		if err != nil {
+			s.logger.Error("failed updating zone", zap.Error(err))
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newZoneResponse(zone))
+	}
+}
+
+func (s service) HandleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authorizeZoneParam(w, r, s.enforcer)
+		if !ok {
+			return
+		}
+		zone, err := s.handler.DeleteZone(r.Context(), name)
+		if err != nil {
+			s.logger.Error("failed deleting zone", zap.Error(err))
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				rest.RenderError(w, r, &rest.NotFoundError)
+				return
+			}
+			if errors.Is(err, storage.ErrZoneNotEmpty) {
+				rest.RenderError(w, r, &rest.ConflictError)
+				return
+			}
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, newZoneResponse(zone))
+	}
+}
+
+func (s service) HandleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		zones, err := s.handler.ListZones(r.Context())
+		if err != nil {
+			s.logger.Error("failed to list zones", zap.Error(err))
+			rest.RenderError(w, r, &rest.InternalServerError)
+			return
+		}
+		// Only zones the subject is authorized to read are listed.
+		response := []ZoneResponse{}
+		for _, zone := range zones {
+			ok, err := s.enforcer.IsAuthorized(r, zone.Name)
+			if err != nil {
+				s.logger.Error("failed to enforce action", zap.Error(err))
+				rest.RenderError(w, r, &rest.InternalServerError)
+				return
+			}
+			if ok {
+				response = append(response, newZoneResponse(zone))
+			}
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, response)
+	}
+}
+
+// Reads the zone URL parameter and enforces authorization on it with e.
+// Renders an error response and returns false when the request must not proceed.
+func (s service) authorizeZoneParam(w http.ResponseWriter, r *http.Request, e enforcer.RequestEnforcer) (string, bool) {
+	name := chi.URLParam(r, "zone")
+	if !dns.IsFqdn(name) {
+		s.logger.Error("zone URL parameter is not FQDN", zap.String("zone", name))
+		rest.RenderError(w, r, &rest.NotFoundError)
+		return "", false
+	}
+	ok, err := e.IsAuthorized(r, name)
+	if err != nil {
+		s.logger.Error("failed to enforce action", zap.Error(err))
+		rest.RenderError(w, r, &rest.InternalServerError)
+		return "", false
+	}
+	if !ok {
+		s.logger.Error("unauthorized", zap.String("zone", name))
+		rest.RenderError(w, r, &rest.ForbiddenError)
+		return "", false
+	}
+	return name, true
+}
+
+type ZoneResponse struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	DefaultTTL    int       `json:"defaultTtl"`
+	SOASerial     uint32    `json:"soaSerial"`
+	Nameservers   []string  `json:"nameservers"`
+	Authoritative bool      `json:"authoritative"`
+	Comment       string    `json:"comment,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedOn     time.Time `json:"updatedOn"`
+}
+
+func newZoneResponse(z storage.Zone) ZoneResponse {
+	nameservers := z.Nameservers
+	if nameservers == nil {
+		nameservers = []string{}
+	}
+	return ZoneResponse{
+		ID:            z.ID,
+		Name:          z.Name,
+		DefaultTTL:    z.DefaultTTL,
+		SOASerial:     z.SOASerial,
+		Nameservers:   nameservers,
+		Authoritative: z.Authoritative,
+		Comment:       z.Comment,
+		CreatedAt:     z.CreatedAt,
+		UpdatedOn:     z.ModifiedOn,
+	}
+}
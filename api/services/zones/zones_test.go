@@ -0,0 +1,324 @@
+package zones
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pb33f/libopenapi"
+	validator "github.com/pb33f/libopenapi-validator"
+	"github.com/sneakybugs/corewarden/api/services/auth"
+	"github.com/sneakybugs/corewarden/api/services/enforcer"
+	"github.com/sneakybugs/corewarden/api/services/logger"
+	"github.com/sneakybugs/corewarden/api/services/rest"
+	"github.com/sneakybugs/corewarden/api/services/storage"
+	"go.uber.org/fx"
+)
+
+func TestCreateZone(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/zones",
+		strings.NewReader(`{"name": "example.com.", "nameservers": ["ns1.example.com."], "comment": "test"}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Result().StatusCode)
+	}
+	var response ZoneResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Name != "example.com." {
+		t.Errorf("Expected name to be 'example.com.', got '%s'", response.Name)
+	}
+	if response.DefaultTTL != storage.DefaultZoneTTL {
+		t.Errorf("Expected defaultTtl to be %d, got %d", storage.DefaultZoneTTL, response.DefaultTTL)
+	}
+	if len(response.Nameservers) != 1 || response.Nameservers[0] != "ns1.example.com." {
+		t.Errorf("Expected nameservers to be ['ns1.example.com.'], got %v", response.Nameservers)
+	}
+}
+
+func TestCreateZoneExists(t *testing.T) {
+	h := createTestHandler(nil)
+	for _, expectedStatus := range []int{http.StatusCreated, http.StatusConflict} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/v1/zones",
+			strings.NewReader(`{"name": "example.com."}`),
+		)
+		auth.MockLogin(r, "alice")
+		r.Header.Add("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		validateResponseBody(t, r, w.Result())
+		if w.Result().StatusCode != expectedStatus {
+			t.Errorf("Expected status %d, got %d", expectedStatus, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestCreateZoneBadRequest(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/zones",
+		strings.NewReader(`{"name": "example.com", "defaultTtl": -1, "nameservers": ["ns1.example.com.", "ns2"]}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Result().StatusCode)
+	}
+	var response rest.BadRequestErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedKeys := []string{"name", "defaultTtl", "nameservers[1]"}
+	if len(response.Fields) != len(expectedKeys) {
+		t.Fatalf("Expected fields to of length %d, got %d", len(expectedKeys), len(response.Fields))
+	}
+	for i, key := range expectedKeys {
+		if response.Fields[i].Key != key {
+			t.Errorf("Expected fields[%d].key to be '%s', got '%s'", i, key, response.Fields[i].Key)
+		}
+	}
+}
+
+func TestCreateZoneForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/zones",
+		strings.NewReader(`{"name": "example.net."}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestReadZone(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones/example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response ZoneResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Name != "example.com." {
+		t.Errorf("Expected name to be 'example.com.', got '%s'", response.Name)
+	}
+}
+
+func TestReadZoneNotFound(t *testing.T) {
+	h := createTestHandler(nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones/example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestReadZoneForbidden(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "bob", "example.net.")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones/example.net.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestUpdateZone(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPut,
+		"/v1/zones/example.com.",
+		strings.NewReader(`{"defaultTtl": 300, "nameservers": ["ns1.example.com.", "ns2.example.com."], "authoritative": true}`),
+	)
+	auth.MockLogin(r, "alice")
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response ZoneResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.DefaultTTL != 300 {
+		t.Errorf("Expected defaultTtl to be 300, got %d", response.DefaultTTL)
+	}
+	if len(response.Nameservers) != 2 {
+		t.Errorf("Expected nameservers length to be 2, got %d", len(response.Nameservers))
+	}
+	if !response.Authoritative {
+		t.Errorf("Expected authoritative to be true")
+	}
+	if response.SOASerial != 2 {
+		t.Errorf("Expected soaSerial to be 2, got %d", response.SOASerial)
+	}
+}
+
+func TestDeleteZone(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/v1/zones/example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/v1/zones/example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deletion, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestDeleteZoneNotEmpty(t *testing.T) {
+	h := createTestHandler(storage.ErrZoneNotEmpty)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/v1/zones/example.com.", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestListZones(t *testing.T) {
+	h := createTestHandler(nil)
+	createTestZone(t, h, "alice", "example.com.")
+	createTestZone(t, h, "bob", "example.net.")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/zones", nil)
+	auth.MockLogin(r, "alice")
+	h.ServeHTTP(w, r)
+	validateResponseBody(t, r, w.Result())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Result().StatusCode)
+	}
+	var response []ZoneResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 1 {
+		t.Fatalf("Expected response length to be 1, got %d", len(response))
+	}
+	if response[0].Name != "example.com." {
+		t.Errorf("Expected response[0].name to be 'example.com.', got '%s'", response[0].Name)
+	}
+}
+
+func createTestZone(t *testing.T, h http.Handler, subject string, name string) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/zones",
+		strings.NewReader(`{"name": "`+name+`"}`),
+	)
+	auth.MockLogin(r, subject)
+	r.Header.Add("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating test zone, got %d", w.Result().StatusCode)
+	}
+}
+
+func createTestHandler(returnError error) http.Handler {
+	var handler *chi.Mux
+	_ = fx.New(
+		fx.Supply(
+			storage.MockStorageOptions{
+				ReturnError: returnError,
+			},
+			enforcer.CasbinEnforcerOptions{
+				PolicyFile: "test_policy.csv",
+			},
+			logger.Options{
+				DevelopmentMode: true,
+			},
+		),
+		fx.Provide(
+			logger.NewService,
+			rest.NewMockService,
+			storage.NewMockService,
+			enforcer.NewCasbinEnforcer,
+			auth.NewMockAuthenticator,
+			auth.NewService,
+		),
+		fx.Invoke(
+			Register,
+		),
+		fx.Populate(
+			&handler,
+		),
+		fx.NopLogger,
+	)
+	return handler
+}
+
+var docValidator validator.Validator
+
+func validateResponseBody(t *testing.T, r *http.Request, w *http.Response) {
+	if docValidator == nil {
+		apiSpec, err := os.ReadFile("../../openapi.yaml")
+		if err != nil {
+			t.Fatalf("Failed reading OpenAPI spec: %v\n", err)
+		}
+		document, err := libopenapi.NewDocument(apiSpec)
+		if err != nil {
+			t.Fatalf("Failed to parse OpenAPI spec: %v\n", err)
+		}
+		validator, validationErrs := validator.NewValidator(document)
+		if validationErrs != nil {
+			t.Fatalf("Failed to create validator: %v\n", validationErrs)
+		}
+		docValidator = validator
+	}
+	valid, errs := docValidator.ValidateHttpResponse(r, w)
+	if !valid {
+		t.Fatalf("Request body failed OpenAPI spec validation: %v", errs)
+	}
+}
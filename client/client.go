@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -31,7 +33,8 @@ type Client interface {
 	ReadRecord(id int) (Record, error)
 	UpdateRecord(params UpdateRecordParams) (Record, error)
 	DeleteRecord(id int) (Record, error)
-	ListRecords(zone string) ([]Record, error)
+	ListRecords(params ListRecordsParams) iter.Seq2[Record, error]
+	ApplyChangeset(params ApplyChangesetParams) ([]Record, error)
 }
 
 type APIClient struct {
@@ -78,6 +81,49 @@ type UpdateRecordParams struct {
 	Comment string `json:"comment"`
 }
 
+const (
+	ChangesetCreate = "create"
+	ChangesetUpdate = "update"
+	ChangesetDelete = "delete"
+)
+
+// ID is required for update and delete operations.
+// Zone and RR are required for create and update operations.
+type ChangesetOperation struct {
+	Action  string `json:"action"`
+	ID      int    `json:"id,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+	RR      string `json:"content,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Operations are applied in order in a single transaction.
+type ApplyChangesetParams struct {
+	Operations []ChangesetOperation `json:"operations"`
+}
+
+const (
+	ListRecordsSortID   = "id"
+	ListRecordsSortName = "name"
+)
+
+// Zero valued fields are not sent and the API defaults are used.
+type ListRecordsParams struct {
+	Zone string `param:"zone"`
+	// Wildcard records are at the node without the leading "*." label.
+	Name string `param:"name"`
+	Type string `param:"type"`
+	// Nil lists both wildcard and non-wildcard records.
+	Wildcard *bool `param:"wildcard"`
+	// Case insensitive comment substring.
+	Comment       string    `param:"comment"`
+	ModifiedSince time.Time `param:"modifiedSince"`
+	Sort          string    `param:"sort"`
+	// Page size used while iterating, does not limit the total number of records.
+	// All records are listed in a single request when zero.
+	Limit int `param:"limit"`
+}
+
 // Every field on the struct is available in the URL template.
 // The struct is JSON marshalled into the request body.
 func paramsToRequest(method string, url string, params any, credentials Credentials) (req *http.Request, err error) {
@@ -102,11 +148,13 @@ func paramsToRequest(method string, url string, params any, credentials Credenti
 	query := req.URL.Query()
 	for _, field := range reflect.VisibleFields(paramsValue.Type()) {
 		if paramName := field.Tag.Get("param"); paramName != "" {
-			fieldValue := paramsValue.FieldByName(field.Name)
-			if fieldValue.Kind() != reflect.String {
-				return nil, fmt.Errorf("param tagged field '%s' must be of type string", field.Name)
+			value, ok, err := paramValue(paramsValue.FieldByIndex(field.Index))
+			if err != nil {
+				return nil, fmt.Errorf("param tagged field '%s': %w", field.Name, err)
+			}
+			if ok {
+				query.Set(paramName, value)
 			}
-			query.Set(paramName, fieldValue.String())
 		}
 	}
 	req.URL.RawQuery = query.Encode()
@@ -114,6 +162,28 @@ func paramsToRequest(method string, url string, params any, credentials Credenti
 	return
 }
 
+// Formats a param tagged field as a query parameter.
+// Returns false for zero values which are left out of the query.
+func paramValue(v reflect.Value) (string, bool, error) {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), !t.IsZero(), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), v.String() != "", nil
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), v.Int() != 0, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "", false, nil
+		}
+		if v.Elem().Kind() == reflect.Bool {
+			return strconv.FormatBool(v.Elem().Bool()), true, nil
+		}
+	}
+	return "", false, fmt.Errorf("unsupported type %s", v.Type())
+}
+
 type APIError struct {
 	parameterErr error
 	status       int
@@ -184,9 +254,14 @@ func parseErrorResponse(res *http.Response, params any) (error, error) {
 		}
 
 		// Map body JSON field errors to struct fields.
+		// Nested keys such as "operations[0].zone" are kept as is.
 		for _, keyErr := range parsedResponse.Fields {
+			key, ok := fieldNamesMap[keyErr.Key]
+			if !ok {
+				key = keyErr.Key
+			}
 			paramErr.FieldErrors = append(paramErr.FieldErrors, ParameterErrorField{
-				Key:     fieldNamesMap[keyErr.Key],
+				Key:     key,
 				Message: keyErr.Message,
 			})
 
@@ -417,18 +492,95 @@ func (c *APIClient) DeleteRecord(id int) (Record, error) {
 	}, nil
 }
 
-func (c *APIClient) ListRecords(zone string) ([]Record, error) {
-	params := struct {
-		Zone string `param:"zone"`
-	}{
-		Zone: zone,
+// Iterates over all records matching the params, fetching pages as needed.
+// Iteration stops after yielding the first error.
+func (c *APIClient) ListRecords(params ListRecordsParams) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		pageParams := listRecordsPageParams{ListRecordsParams: params}
+		for {
+			page, nextCursor, err := c.listRecordsPage(pageParams)
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			for _, record := range page {
+				if !yield(record, nil) {
+					return
+				}
+			}
+			if nextCursor == "" {
+				return
+			}
+			pageParams.Cursor = nextCursor
+		}
 	}
+}
+
+type listRecordsPageParams struct {
+	ListRecordsParams
+	Cursor string `param:"cursor"`
+}
+
+func (c *APIClient) listRecordsPage(params listRecordsPageParams) ([]Record, string, error) {
 	req, err := paramsToRequest(
 		"GET",
 		fmt.Sprintf("%s/records", c.endpoint),
 		params,
 		c.credentials,
 	)
+	if err != nil {
+		return []Record{}, "", err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return []Record{}, "", err
+	}
+	apiErr, parsingErr := parseErrorResponse(res, params)
+	if parsingErr != nil {
+		return []Record{}, "", parsingErr
+	}
+	if apiErr != nil {
+		return []Record{}, "", apiErr
+	}
+	nextCursor := res.Header.Get(rest.NextCursorHeader)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return []Record{}, "", err
+	}
+
+	var parsedRecords []records.RecordResponse
+	if err = json.Unmarshal(body, &parsedRecords); err != nil {
+		return []Record{}, "", err
+	}
+
+	records := make([]Record, len(parsedRecords))
+
+	for i, record := range parsedRecords {
+		rr, err := dns.NewRR(record.Content)
+		if err != nil {
+			return []Record{}, "", err
+		}
+		records[i] = Record{
+			ID:        record.ID,
+			Zone:      record.Zone,
+			RR:        rr,
+			Comment:   record.Comment,
+			CreatedAt: record.CreatedAt,
+			UpdatedOn: record.UpdatedOn,
+		}
+	}
+
+	return records, nextCursor, nil
+}
+
+func (c *APIClient) ApplyChangeset(params ApplyChangesetParams) ([]Record, error) {
+	req, err := paramsToRequest(
+		"POST",
+		fmt.Sprintf("%s/changesets", c.endpoint),
+		params,
+		c.credentials,
+	)
 	if err != nil {
 		return []Record{}, err
 	}
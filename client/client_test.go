@@ -409,9 +409,12 @@ func TestListRecords(t *testing.T) {
 			ClientSecret: "secret",
 		},
 	}
-	r, err := c.ListRecords("example.com.")
-	if err != nil {
-		t.Fatalf("Expected no error, got %v\n", err)
+	r := []Record{}
+	for record, err := range c.ListRecords(ListRecordsParams{Zone: "example.com."}) {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v\n", err)
+		}
+		r = append(r, record)
 	}
 	if len(r) != 1 {
 		t.Errorf("Expected response length to be 1, got %d\n", len(r))
@@ -445,7 +448,9 @@ func TestListRecordsParamError(t *testing.T) {
 			ClientSecret: "secret",
 		},
 	}
-	_, err := c.ListRecords("example.com.")
+	var err error
+	for _, err = range c.ListRecords(ListRecordsParams{Zone: "example.com."}) {
+	}
 	if err == nil {
 		t.Fatalf("Expected an error, got nil\n")
 	}
@@ -480,7 +485,9 @@ func TestListRecordsUnauthorized(t *testing.T) {
 			ClientSecret: "secret",
 		},
 	}
-	_, err := c.ListRecords("example.com.")
+	var err error
+	for _, err = range c.ListRecords(ListRecordsParams{Zone: "example.com."}) {
+	}
 	if err == nil {
 		t.Fatalf("Expected an error, got nil\n")
 	}
@@ -497,6 +504,156 @@ func TestListRecordsUnauthorized(t *testing.T) {
 	validateRequest(t, m.LastRequest)
 }
 
+func TestListRecordsPages(t *testing.T) {
+	firstPage := createRecordListResponse(t, 1, "example.com.", "@ IN A 127.0.0.1", "")
+	firstPage.Header.Set(rest.NextCursorHeader, "next")
+	m := MockSequenceHTTPClient{
+		Responses: []*http.Response{
+			firstPage,
+			createRecordListResponse(t, 2, "example.com.", "@ IN A 127.0.0.2", ""),
+		},
+	}
+	c := APIClient{
+		httpClient: &m,
+		endpoint:   "https://localhost:3080/v1",
+		credentials: Credentials{
+			ClientID:     "example",
+			ClientSecret: "secret",
+		},
+	}
+	wildcard := false
+	ids := []int{}
+	for record, err := range c.ListRecords(ListRecordsParams{
+		Zone:          "example.com.",
+		Type:          "A",
+		Wildcard:      &wildcard,
+		ModifiedSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sort:          ListRecordsSortName,
+		Limit:         1,
+	}) {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v\n", err)
+		}
+		ids = append(ids, record.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("Expected record IDs to be [1 2], got %v\n", ids)
+	}
+	if len(m.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d\n", len(m.Requests))
+	}
+	first := m.Requests[0].URL.Query()
+	if first.Has("cursor") {
+		t.Errorf("Expected first request to have no cursor, got '%s'\n", first.Get("cursor"))
+	}
+	if first.Has("name") {
+		t.Errorf("Expected empty name to not be sent, got '%s'\n", first.Get("name"))
+	}
+	if first.Get("wildcard") != "false" {
+		t.Errorf("Expected wildcard to be 'false', got '%s'\n", first.Get("wildcard"))
+	}
+	if first.Get("modifiedSince") != "2024-01-01T00:00:00Z" {
+		t.Errorf("Expected modifiedSince to be '2024-01-01T00:00:00Z', got '%s'\n", first.Get("modifiedSince"))
+	}
+	if first.Get("limit") != "1" {
+		t.Errorf("Expected limit to be '1', got '%s'\n", first.Get("limit"))
+	}
+	second := m.Requests[1].URL.Query()
+	if second.Get("cursor") != "next" {
+		t.Errorf("Expected second request cursor to be 'next', got '%s'\n", second.Get("cursor"))
+	}
+	if second.Get("sort") != "name" {
+		t.Errorf("Expected second request sort to be 'name', got '%s'\n", second.Get("sort"))
+	}
+	for _, req := range m.Requests {
+		validateRequest(t, req)
+	}
+}
+
+func TestApplyChangeset(t *testing.T) {
+	m := MockHTTPClient{
+		Response: createRecordListResponse(t, 1, "example.com.", "@ IN A 127.0.0.1", "example"),
+		Error:    nil,
+	}
+	c := APIClient{
+		httpClient: &m,
+		endpoint:   "https://localhost:3080/v1",
+		credentials: Credentials{
+			ClientID:     "example",
+			ClientSecret: "secret",
+		},
+	}
+	r, err := c.ApplyChangeset(ApplyChangesetParams{
+		Operations: []ChangesetOperation{
+			{
+				Action: ChangesetDelete,
+				ID:     2,
+			},
+			{
+				Action:  ChangesetCreate,
+				Zone:    "example.com.",
+				RR:      "@ IN A 127.0.0.1",
+				Comment: "example",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if len(r) != 1 {
+		t.Errorf("Expected response length to be 1, got %d\n", len(r))
+	}
+	if r[0].Zone != "example.com." {
+		t.Errorf("Expected zone to be 'example.com.', got '%s'\n", r[0].Zone)
+	}
+	assertRREquals(t, r[0].RR, "@ IN A 127.0.0.1")
+	validateRequest(t, m.LastRequest)
+}
+
+func TestApplyChangesetParamError(t *testing.T) {
+	m := MockAPIErrorHTTPClient{
+		Error: &rest.BadRequestErrorResponse{
+			Fields: []rest.KeyError{
+				{
+					Key:     "operations[0].content",
+					Message: "CNAME record must be the only record in the node",
+				},
+			},
+		},
+	}
+	c := APIClient{
+		httpClient: &m,
+		endpoint:   "https://localhost:3080/v1",
+		credentials: Credentials{
+			ClientID:     "example",
+			ClientSecret: "secret",
+		},
+	}
+	_, err := c.ApplyChangeset(ApplyChangesetParams{
+		Operations: []ChangesetOperation{
+			{
+				Action: ChangesetCreate,
+				Zone:   "example.com.",
+				RR:     "@ IN CNAME example.net.",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected an error, got nil\n")
+	}
+	var apiErr *APIParameterError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected err to be an APIError\n")
+	}
+	if len(apiErr.FieldErrors) != 1 {
+		t.Fatalf("Expected FieldErrors length to be 1, got %d\n", len(apiErr.FieldErrors))
+	}
+	if apiErr.FieldErrors[0].Key != "operations[0].content" {
+		t.Fatalf("Expected FieldErrors[0].Key to be 'operations[0].content', got '%s'\n", apiErr.FieldErrors[0].Key)
+	}
+	validateRequest(t, m.LastRequest)
+}
+
 type MockHTTPClient struct {
 	LastRequest *http.Request
 	Response    *http.Response
@@ -508,6 +665,18 @@ func (c *MockHTTPClient) Do(r *http.Request) (*http.Response, error) {
 	return c.Response, c.Error
 }
 
+// Responds with the responses in order.
+type MockSequenceHTTPClient struct {
+	Requests  []*http.Request
+	Responses []*http.Response
+}
+
+func (c *MockSequenceHTTPClient) Do(r *http.Request) (*http.Response, error) {
+	res := c.Responses[len(c.Requests)]
+	c.Requests = append(c.Requests, r)
+	return res, nil
+}
+
 type MockAPIErrorHTTPClient struct {
 	LastRequest *http.Request
 	Error       error
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var envReplacer = strings.NewReplacer("-", "_")
+
+type Command struct {
+	Cmd *cobra.Command
+	Cfg *viper.Viper
+}
+
+// Decision of the check endpoint of the filterlist plugin.
+type decision struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Client      string    `json:"client"`
+	ClientID    string    `json:"clientId"`
+	Group       string    `json:"group"`
+	Action      string    `json:"action"`
+	Rule        string    `json:"rule"`
+	List        string    `json:"list"`
+	Allowlisted bool      `json:"allowlisted"`
+	AuditRule   string    `json:"auditRule"`
+	AuditList   string    `json:"auditList"`
+	Override    *override `json:"override"`
+}
+
+type override struct {
+	Nxdomain bool     `json:"nxdomain"`
+	Answer   []string `json:"answer"`
+}
+
+func CreateRootCommand() Command {
+	cfg := viper.NewWithOptions(
+		viper.EnvKeyReplacer(envReplacer),
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check NAME",
+		Short: "Explain how CoreDNS answers a name",
+		Long: "Asks the check endpoint of the filterlist plugin which rule and\n" +
+			"list decide the answer to a name, and whether a record override\n" +
+			"of the API server answers it instead.\n\n" +
+			"Flags can be set through environment variables prefixed\n" +
+			"with 'CHECK_', all uppercase, with '-' replaced by '_'.\n" +
+			"For example:\n" +
+			"  --endpoint flag becomes CHECK_ENDPOINT",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			query.Set("name", args[0])
+			query.Set("type", cfg.GetString("type"))
+			if client := cfg.GetString("client"); client != "" {
+				query.Set("client", client)
+			}
+			if clientID := cfg.GetString("client-id"); clientID != "" {
+				query.Set("client_id", clientID)
+			}
+			body, err := get(strings.TrimSuffix(cfg.GetString("endpoint"), "/") + "/check?" + query.Encode())
+			if err != nil {
+				return err
+			}
+			if cfg.GetBool("json") {
+				_, err = cmd.OutOrStdout().Write(body)
+				return err
+			}
+			var d decision
+			if err := json.Unmarshal(body, &d); err != nil {
+				return fmt.Errorf("invalid response: %w", err)
+			}
+			return printDecision(cmd.OutOrStdout(), d)
+		},
+	}
+
+	cmd.Flags().String("endpoint", "", "Check endpoint of the filterlist plugin (default http://localhost:9154)")
+	_ = cfg.BindPFlag("endpoint", cmd.Flags().Lookup("endpoint"))
+	cfg.SetDefault("endpoint", "http://localhost:9154")
+
+	cmd.Flags().StringP("type", "t", "", "Query type (default A)")
+	_ = cfg.BindPFlag("type", cmd.Flags().Lookup("type"))
+	cfg.SetDefault("type", "A")
+
+	cmd.Flags().String("client", "", "Client IP matched against the groups and $client rules")
+	_ = cfg.BindPFlag("client", cmd.Flags().Lookup("client"))
+
+	cmd.Flags().String("client-id", "", "Client ID matched against the groups and $client rules")
+	_ = cfg.BindPFlag("client-id", cmd.Flags().Lookup("client-id"))
+
+	cmd.Flags().Bool("json", false, "Print the decision as JSON")
+	_ = cfg.BindPFlag("json", cmd.Flags().Lookup("json"))
+
+	cobra.OnInitialize(func() {
+		cfg.SetEnvPrefix("check")
+		cfg.AutomaticEnv()
+	})
+
+	return Command{
+		Cmd: cmd,
+		Cfg: cfg,
+	}
+}
+
+func get(url string) ([]byte, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("check failed with status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func printDecision(w io.Writer, d decision) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	line := func(key string, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", key, value)
+		}
+	}
+	line("name", d.Name)
+	line("type", d.Type)
+	line("client", d.Client)
+	line("client id", d.ClientID)
+	line("group", d.Group)
+	line("action", d.Action)
+	line("rule", d.Rule)
+	line("list", d.List)
+	if d.Allowlisted {
+		line("allowlisted", "yes")
+	}
+	line("audit rule", d.AuditRule)
+	line("audit list", d.AuditList)
+	if d.Override != nil {
+		if d.Override.Nxdomain {
+			line("override", "NXDOMAIN")
+		}
+		for _, rr := range d.Override.Answer {
+			line("override", rr)
+		}
+		if !d.Override.Nxdomain && len(d.Override.Answer) == 0 {
+			line("override", "NODATA")
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func runCheck(t *testing.T, endpoint string, args ...string) (string, error) {
+	t.Helper()
+	c := CreateRootCommand()
+	out := &bytes.Buffer{}
+	c.Cmd.SetOut(out)
+	c.Cmd.SetErr(&bytes.Buffer{})
+	c.Cmd.SetArgs(append([]string{"--endpoint", endpoint}, args...))
+	err := c.Cmd.Execute()
+	return out.String(), err
+}
+
+func TestCheck(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/check" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"ads.example.com.","type":"AAAA","client":"192.0.2.1","group":"kids","action":"block","rule":"||ads.example.com^","list":"https://example.com/ads.txt","allowlisted":false}`))
+	}))
+	defer server.Close()
+
+	out, err := runCheck(t, server.URL+"/", "ads.example.com", "-t", "AAAA", "--client", "192.0.2.1", "--client-id", "tablet")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if query.Get("name") != "ads.example.com" || query.Get("type") != "AAAA" || query.Get("client") != "192.0.2.1" || query.Get("client_id") != "tablet" {
+		t.Errorf("Expected the name, type, client and client ID to be sent, got %v", query)
+	}
+	for _, expected := range []string{"action:  block\n", "rule:    ||ads.example.com^\n", "list:    https://example.com/ads.txt\n"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected output to contain %q, got %q", expected, out)
+		}
+	}
+	if strings.Contains(out, "allowlisted") || strings.Contains(out, "override") {
+		t.Errorf("Expected empty fields to be skipped, got %q", out)
+	}
+
+	out, err = runCheck(t, server.URL, "ads.example.com", "--json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if query.Get("type") != "A" || query.Has("client") || query.Has("client_id") {
+		t.Errorf("Expected type A without client, got %v", query)
+	}
+	if !strings.HasPrefix(out, `{"name":"ads.example.com."`) {
+		t.Errorf("Expected the JSON response, got %q", out)
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid name", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := runCheck(t, server.URL, "example..com")
+	if err == nil || !strings.Contains(err.Error(), "status 400: invalid name") {
+		t.Errorf("Expected the status and message of the error, got %v", err)
+	}
+}
+
+func TestPrintDecision(t *testing.T) {
+	cases := []struct {
+		name     string
+		override *override
+		expected []string
+	}{
+		{"nxdomain", &override{Nxdomain: true}, []string{"override:  NXDOMAIN\n"}},
+		{"nodata", &override{}, []string{"override:  NODATA\n"}},
+		{"answer", &override{Answer: []string{"a.example.com.\t60\tIN\tA\t192.0.2.1", "a.example.com.\t60\tIN\tA\t192.0.2.2"}}, []string{
+			"override:  a.example.com.  60  IN  A  192.0.2.1\n",
+			"override:  a.example.com.  60  IN  A  192.0.2.2\n",
+		}},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		d := decision{Name: "a.example.com.", Type: "A", Action: "override", Override: c.override}
+		if err := printDecision(out, d); err != nil {
+			t.Fatalf("%s: expected no error, got %v", c.name, err)
+		}
+		for _, expected := range c.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("%s: expected output to contain %q, got %q", c.name, expected, out.String())
+			}
+		}
+		if c.name != "nodata" && strings.Contains(out.String(), "NODATA") {
+			t.Errorf("%s: expected no NODATA line, got %q", c.name, out.String())
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sneakybugs/corewarden/coredns/check/cmd"
+)
+
+func main() {
+	rootCmd := cmd.CreateRootCommand()
+	if err := rootCmd.Cmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
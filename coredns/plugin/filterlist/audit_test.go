@@ -0,0 +1,87 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestFilterlistAudit(t *testing.T) {
+	group := &Group{
+		Name:       "audit",
+		Blocklists: []string{"https://example.com/ads.txt", "https://example.com/aggressive.txt"},
+		Allowlists: []string{"https://example.com/allow.txt"},
+		Services:   []string{"tiktok"},
+		Audit:      []string{"https://example.com/aggressive.txt", "service:tiktok"},
+	}
+	b := newEngineBuilder()
+	err := b.setLists(group, []FetchedList{
+		{URL: group.Blocklists[0], Content: "||ads.example.com^"},
+		{URL: group.Blocklists[1], Content: "||cdn.example.com^\n||ads.example.com^\n||allowed.example.com^"},
+		{URL: group.Allowlists[0], Content: AllowlistRules("allowed.example.com")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{
+		Next:   test.NextHandler(dns.RcodeSuccess, nil),
+		Groups: []*Group{group},
+		Logger: zap.NewNop(),
+	}
+	tests := []struct {
+		qname      string
+		blocked    bool
+		wouldBlock string
+	}{
+		{qname: "ads.example.com.", blocked: true},
+		{qname: "cdn.example.com.", wouldBlock: "https://example.com/aggressive.txt"},
+		{qname: "www.tiktok.com.", wouldBlock: "service:tiktok"},
+		{qname: "allowed.example.com."},
+		{qname: "example.com."},
+	}
+	for i, tc := range tests {
+		var before float64
+		if tc.wouldBlock != "" {
+			before = testutil.ToFloat64(requestsWouldBlock.WithLabelValues("audit", tc.wouldBlock, "A"))
+		}
+		req := new(dns.Msg)
+		req.SetQuestion(tc.qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := fl.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		// The next handler answers without records.
+		if blocked := rec.Msg != nil && len(rec.Msg.Answer) != 0; blocked != tc.blocked {
+			t.Errorf("Test %d: expected blocked to be %t, got %t", i, tc.blocked, blocked)
+		}
+		if tc.wouldBlock != "" {
+			if after := testutil.ToFloat64(requestsWouldBlock.WithLabelValues("audit", tc.wouldBlock, "A")); after != before+1 {
+				t.Errorf("Test %d: expected the would block counter of %s to increase", i, tc.wouldBlock)
+			}
+		}
+	}
+	if v := testutil.ToFloat64(requestsBlocked.WithLabelValues("audit", "https://example.com/aggressive.txt", "A")); v != 0 {
+		t.Errorf("expected no requests blocked by the audited list, got %v", v)
+	}
+}
+
+func TestFilterlistOnlyAudited(t *testing.T) {
+	group := &Group{
+		Name:     defaultGroupName,
+		Services: []string{"tiktok"},
+		Audit:    []string{"service:tiktok"},
+	}
+	if err := newEngineBuilder().buildAll([]*Group{group}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if group.Engine.Load() == nil || group.auditEngine.Load() == nil {
+		t.Fatalf("expected the engine and the audit engine to be built")
+	}
+	assertBlocked(t, group, map[string]bool{"tiktok.com": false})
+}
@@ -0,0 +1,79 @@
+package filterlist
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// TTL of block responses, including the negative caching TTL of the SOA.
+const defaultBlockTTL = 604800
+
+type BlockMode string
+
+const (
+	// Answers A queries with 0.0.0.0, AAAA queries with :: and other
+	// queries with NODATA. Used when empty.
+	BlockModeNullIP BlockMode = "null_ip"
+	// Answers every query with NXDOMAIN.
+	BlockModeNXDomain BlockMode = "nxdomain"
+	// Answers every query with REFUSED.
+	BlockModeRefused BlockMode = "refused"
+	// Answers A and AAAA queries with the configured addresses, for example
+	// of a block page, and other queries with NODATA.
+	BlockModeCustomIP BlockMode = "custom_ip"
+)
+
+// Builds the response to a blocked request.
+func (fl FilterList) blockResponse(state request.Request) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Rcode = dns.RcodeSuccess
+	switch fl.BlockMode {
+	case BlockModeNXDomain:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{fl.blockSOA(state)}
+		return m
+	case BlockModeRefused:
+		m.Rcode = dns.RcodeRefused
+		return m
+	}
+
+	ipv4, ipv6 := net.IPv4zero, net.IPv6zero
+	if fl.BlockMode == BlockModeCustomIP {
+		ipv4, ipv6 = nil, nil
+		for _, ip := range fl.BlockIPs {
+			if ip.To4() != nil {
+				ipv4 = ip
+			} else {
+				ipv6 = ip
+			}
+		}
+	}
+	hdr := dns.RR_Header{Name: state.QName(), Ttl: fl.BlockTTL, Class: dns.ClassINET, Rrtype: state.QType()}
+	switch {
+	case state.QType() == dns.TypeA && ipv4 != nil:
+		m.Answer = []dns.RR{&dns.A{Hdr: hdr, A: ipv4.To4()}}
+	case state.QType() == dns.TypeAAAA && ipv6 != nil:
+		m.Answer = []dns.RR{&dns.AAAA{Hdr: hdr, AAAA: ipv6}}
+	default:
+		// NODATA, the SOA allows negative caching for the block TTL.
+		m.Ns = []dns.RR{fl.blockSOA(state)}
+	}
+	return m
+}
+
+// SOA of negative block responses, owned by the blocked name.
+func (fl FilterList) blockSOA(state request.Request) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: state.QName(), Ttl: fl.BlockTTL, Class: dns.ClassINET, Rrtype: dns.TypeSOA},
+		Ns:      "blocked.invalid.",
+		Mbox:    "hostmaster.blocked.invalid.",
+		Serial:  1,
+		Refresh: 1800,
+		Retry:   900,
+		Expire:  604800,
+		Minttl:  fl.BlockTTL,
+	}
+}
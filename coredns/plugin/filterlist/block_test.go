@@ -0,0 +1,93 @@
+package filterlist
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+func TestBlockModes(t *testing.T) {
+	engine, err := CreateEngine([]string{"||example.com^"})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	tests := []struct {
+		mode         BlockMode
+		ips          []net.IP
+		qtype        uint16
+		expectedCode int
+		answer       dns.RR
+		soa          bool
+	}{
+		{mode: "", qtype: dns.TypeA, expectedCode: dns.RcodeSuccess, answer: test.A("example.com. 300 IN A 0.0.0.0")},
+		{mode: BlockModeNullIP, qtype: dns.TypeAAAA, expectedCode: dns.RcodeSuccess, answer: test.AAAA("example.com. 300 IN AAAA ::")},
+		{mode: BlockModeNullIP, qtype: dns.TypeMX, expectedCode: dns.RcodeSuccess, soa: true},
+		{mode: BlockModeNXDomain, qtype: dns.TypeA, expectedCode: dns.RcodeNameError, soa: true},
+		{mode: BlockModeRefused, qtype: dns.TypeA, expectedCode: dns.RcodeRefused},
+		{
+			mode:         BlockModeCustomIP,
+			ips:          []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+			qtype:        dns.TypeA,
+			expectedCode: dns.RcodeSuccess,
+			answer:       test.A("example.com. 300 IN A 192.0.2.1"),
+		},
+		{
+			mode:         BlockModeCustomIP,
+			ips:          []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+			qtype:        dns.TypeAAAA,
+			expectedCode: dns.RcodeSuccess,
+			answer:       test.AAAA("example.com. 300 IN AAAA 2001:db8::1"),
+		},
+		{
+			mode:         BlockModeCustomIP,
+			ips:          []net.IP{net.ParseIP("192.0.2.1")},
+			qtype:        dns.TypeAAAA,
+			expectedCode: dns.RcodeSuccess,
+			soa:          true,
+		},
+	}
+	for i, tc := range tests {
+		fl := FilterList{
+			Groups:    []*Group{newEngineGroup(defaultGroupName, engine)},
+			BlockMode: tc.mode,
+			BlockTTL:  300,
+			BlockIPs:  tc.ips,
+			Logger:    zap.NewNop(),
+		}
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		code, err := fl.ServeDNS(context.TODO(), rec, req)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, but got %v", i, err)
+		}
+		if code != tc.expectedCode || rec.Msg.Rcode != tc.expectedCode {
+			t.Errorf("Test %d: expected status code %d, but got %d", i, tc.expectedCode, code)
+		}
+		if tc.answer == nil && len(rec.Msg.Answer) > 0 {
+			t.Errorf("Test %d: expected no answer RR, got %s", i, rec.Msg.Answer[0])
+		}
+		if tc.answer != nil && (len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].String() != tc.answer.String()) {
+			t.Errorf("Test %d: expected answer %s, got %v", i, tc.answer, rec.Msg.Answer)
+		}
+		if !tc.soa {
+			if len(rec.Msg.Ns) > 0 {
+				t.Errorf("Test %d: expected no authority RR, got %s", i, rec.Msg.Ns[0])
+			}
+			continue
+		}
+		if len(rec.Msg.Ns) != 1 {
+			t.Errorf("Test %d: expected an SOA in authority, got %v", i, rec.Msg.Ns)
+			continue
+		}
+		soa, ok := rec.Msg.Ns[0].(*dns.SOA)
+		if !ok || soa.Hdr.Ttl != 300 || soa.Minttl != 300 {
+			t.Errorf("Test %d: expected an SOA with TTL 300, got %s", i, rec.Msg.Ns[0])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package filterlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Last successfully fetched copy of a list.
+type cachedList struct {
+	URL     string `json:"url"`
+	Content string `json:"content"`
+	// Validators of the fetched copy, sent when fetching the list again.
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// Keeps the last successfully fetched copies of the lists, persisted to a
+// directory when configured, so that filtering starts with the last good
+// copies before the lists are fetched again.
+type listCache struct {
+	// Empty when the copies are kept only in memory.
+	dir   string
+	mu    sync.Mutex
+	lists map[string]*cachedList
+}
+
+func newListCache(dir string) *listCache {
+	return &listCache{dir: dir, lists: map[string]*cachedList{}}
+}
+
+// Returns the cached copy of the list, reading it from the directory when it
+// is not in memory. Returns nil when the list was never fetched.
+func (c *listCache) get(url string) (*cachedList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if list, ok := c.lists[url]; ok {
+		return list, nil
+	}
+	if c.dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.path(url))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := &cachedList{}
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("failed to parse cached list %s: %w", url, err)
+	}
+	if list.URL != url {
+		return nil, fmt.Errorf("cached list %s has mismatching url %s", url, list.URL)
+	}
+	c.lists[url] = list
+	return list, nil
+}
+
+// Replaces the cached copy of the list. The file is replaced atomically, so
+// that a crash never leaves a partially written copy.
+func (c *listCache) put(list *cachedList) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists[list.URL] = list
+	if c.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, ".list-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path(list.URL))
+}
+
+// Cache files are named by the hash of the URL, as URLs are not valid file
+// names.
+func (c *listCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Returns the cached copies of the blocklists followed by the allowlists,
+// converted to exception rules. Lists without a cached copy are empty, and
+// unreadable lists are failed.
+func (c *listCache) contents(blocklistURLs []string, allowlistURLs []string) ([]FetchedList, error) {
+	lists := []FetchedList{}
+	errs := []error{}
+	for i, url := range append(append([]string{}, blocklistURLs...), allowlistURLs...) {
+		cached, err := c.get(url)
+		list := FetchedList{URL: url, Err: err}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if cached != nil {
+			list.Content = cached.Content
+			list.Time = cached.FetchedAt
+			if len(blocklistURLs) <= i {
+				list.Content = AllowlistRules(cached.Content)
+			}
+		}
+		lists = append(lists, list)
+	}
+	return lists, errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package filterlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := newListCache(dir)
+	list := &cachedList{URL: "https://example.com/list.txt", Content: "||example.com^", ETag: `"v1"`}
+	if err := cache.put(list); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".json" {
+		t.Errorf("expected only the cached list file, got %v", entries)
+	}
+
+	// Lists are loaded from the directory after restarts.
+	cache = newListCache(dir)
+	cached, err := cache.get(list.URL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cached == nil || *cached != *list {
+		t.Errorf("expected cached list %v, got %v", list, cached)
+	}
+	cached, err = cache.get("https://example.com/other.txt")
+	if err != nil || cached != nil {
+		t.Errorf("expected no cached list and no error, got %v %v", cached, err)
+	}
+}
+
+func TestListCacheContents(t *testing.T) {
+	dir := t.TempDir()
+	cache := newListCache(dir)
+	for _, list := range []*cachedList{
+		{URL: "https://example.com/block.txt", Content: "||example.com^"},
+		{URL: "https://example.com/allow.txt", Content: "foo.example.com"},
+	} {
+		if err := cache.put(list); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := os.WriteFile(cache.path("https://example.com/broken.txt"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cache = newListCache(dir)
+	contents, err := cache.contents(
+		[]string{"https://example.com/block.txt", "https://example.com/missing.txt", "https://example.com/broken.txt"},
+		[]string{"https://example.com/allow.txt"},
+	)
+	if err == nil {
+		t.Errorf("expected an error for the broken list")
+	}
+	expected := []string{"||example.com^", "", "", "@@||foo.example.com^\n"}
+	if len(contents) != len(expected) {
+		t.Fatalf("expected %d lists, got %d", len(expected), len(contents))
+	}
+	for i := range expected {
+		if contents[i].Content != expected[i] {
+			t.Errorf("expected list %d content %q, got %q", i, expected[i], contents[i].Content)
+		}
+	}
+	if contents[2].Err == nil {
+		t.Errorf("expected the broken list to be failed")
+	}
+
+	group := &Group{Name: defaultGroupName, Blocklists: []string{"https://example.com/block.txt"}}
+	b := newEngineBuilder()
+	lists, _ := cache.contents(group.Blocklists, nil)
+	b.preloadLists(group, lists)
+	if err := b.buildAll([]*Group{group}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertBlocked(t, group, map[string]bool{"example.com": true, "example.net": false})
+}
@@ -0,0 +1,116 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+func TestFilterlistCatalogs(t *testing.T) {
+	group := &Group{
+		Name:       defaultGroupName,
+		Blocklists: []string{"https://example.com/list.txt"},
+		Allowlists: []string{"https://example.com/allow.txt"},
+		Services:   []string{"reddit", "tiktok"},
+		SafeSearch: []string{"google", "youtube"},
+	}
+	b := newEngineBuilder()
+	// Safe search is enforced even for allowlisted domains.
+	lists := []FetchedList{
+		{URL: group.Blocklists[0], Content: "||ads.example.com^\n||google.com^$dnsrewrite=192.0.2.1"},
+		{URL: group.Allowlists[0], Content: AllowlistRules("google.com\nold.reddit.com")},
+	}
+	if err := b.setLists(group, lists); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	upstream := mockUpstream{answers: map[string]dns.RR{
+		"forcesafesearch.google.com.": test.A("forcesafesearch.google.com. 300 IN A 216.239.38.120"),
+	}}
+	fl := FilterList{
+		Groups:   []*Group{group},
+		BlockTTL: 300,
+		Upstream: &upstream,
+		Logger:   zap.NewNop(),
+	}
+	tests := []struct {
+		qname  string
+		passed bool
+		answer []string
+	}{
+		{
+			qname:  "www.google.com",
+			answer: []string{"www.google.com.\t300\tIN\tCNAME\tforcesafesearch.google.com.", "forcesafesearch.google.com.\t300\tIN\tA\t216.239.38.120"},
+		},
+		{
+			qname:  "google.co.uk",
+			answer: []string{"google.co.uk.\t300\tIN\tCNAME\tforcesafesearch.google.com.", "forcesafesearch.google.com.\t300\tIN\tA\t216.239.38.120"},
+		},
+		{qname: "www.youtube.com", answer: []string{"www.youtube.com.\t300\tIN\tCNAME\trestrict.youtube.com."}},
+		{qname: "mail.google.com", passed: true},
+		// Engines not enforced are passed through.
+		{qname: "www.bing.com", passed: true},
+		{qname: "www.tiktok.com", answer: []string{"www.tiktok.com.\t300\tIN\tA\t0.0.0.0"}},
+		{qname: "reddit.com", answer: []string{"reddit.com.\t300\tIN\tA\t0.0.0.0"}},
+		{qname: "old.reddit.com", passed: true},
+		{qname: "ads.example.com", answer: []string{"ads.example.com.\t300\tIN\tA\t0.0.0.0"}},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn(tc.qname), dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := fl.ServeDNS(context.TODO(), rec, req)
+		// Passed through requests fail without a next plugin.
+		if tc.passed {
+			if err == nil {
+				t.Errorf("Test %d: expected request to be passed through, got %v", i, rec.Msg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, but got %v", i, err)
+			continue
+		}
+		answer := []string{}
+		for _, rr := range rec.Msg.Answer {
+			answer = append(answer, rr.String())
+		}
+		if len(answer) != len(tc.answer) {
+			t.Errorf("Test %d: expected answer %v, got %v", i, tc.answer, answer)
+			continue
+		}
+		for j := range answer {
+			if answer[j] != tc.answer[j] {
+				t.Errorf("Test %d: expected answer %v, got %v", i, tc.answer, answer)
+				break
+			}
+		}
+	}
+}
+
+func TestCatalogListNames(t *testing.T) {
+	group := &Group{
+		Blocklists: []string{"https://example.com/list.txt"},
+		Services:   []string{"reddit", "tiktok"},
+	}
+	expected := []string{"https://example.com/list.txt", "local", "api", "service:reddit", "service:tiktok", "safesearch"}
+	for i, name := range expected {
+		if listName := group.listName(uint64(i)); listName != name {
+			t.Errorf("expected list %d to be named %q, got %q", i, name, listName)
+		}
+	}
+}
+
+func TestCatalogEntries(t *testing.T) {
+	for _, service := range serviceNames() {
+		if _, err := CreateEngine([]string{serviceRules(service)}); err != nil || countRules(serviceRules(service)) == 0 {
+			t.Errorf("expected rules of service %s, got error %v", service, err)
+		}
+	}
+	if _, err := CreateEngine([]string{safeSearchRules(safeSearchEngineNames())}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
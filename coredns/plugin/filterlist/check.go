@@ -0,0 +1,238 @@
+package filterlist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+)
+
+// Timeout of looking up the records of a name in the API.
+const checkLookupTimeout = 5 * time.Second
+
+// Looks up the records the API owns, implemented by the injector plugin which
+// answers before this plugin.
+type overrideLookup interface {
+	Lookup(ctx context.Context, name string, qtype uint16) (*resolver.Response, error)
+}
+
+// Holds the override lookup of the injector plugin, which is only found once
+// all plugins are set up, after the plugin is added to the chain.
+type overrides struct {
+	lookup atomic.Pointer[overrideLookup]
+}
+
+func (o *overrides) set(lookup overrideLookup) {
+	o.lookup.Store(&lookup)
+}
+
+// Returns nil when o is nil or the injector plugin was not found.
+func (o *overrides) get() overrideLookup {
+	if o == nil {
+		return nil
+	}
+	lookup := o.lookup.Load()
+	if lookup == nil {
+		return nil
+	}
+	return *lookup
+}
+
+// Actions of a Decision.
+const (
+	// Answered with the records of the API by the injector plugin.
+	ActionOverride = "override"
+	// Passed to the next plugin without matching rules, or without a group.
+	ActionPass = "pass"
+	// Passed to the next plugin while blocking is paused.
+	ActionPaused = "paused"
+	// Passed to the next plugin by an exception rule.
+	ActionAllow   = "allow"
+	ActionBlock   = "block"
+	ActionRewrite = "rewrite"
+)
+
+// Explains how a request is answered.
+type Decision struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Client   string `json:"client,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+	// Empty when no group matches the client.
+	Group  string `json:"group,omitempty"`
+	Action string `json:"action"`
+	// Text of the rule deciding the action, empty without a matching rule.
+	Rule string `json:"rule,omitempty"`
+	// URL or name of the list of the rule.
+	List        string `json:"list,omitempty"`
+	Allowlisted bool   `json:"allowlisted"`
+	// Rule and list of an audited list that would block the request.
+	AuditRule string `json:"auditRule,omitempty"`
+	AuditList string `json:"auditList,omitempty"`
+	// Records of the API answering the request, nil when the API does not
+	// own the name.
+	Override *Override `json:"override,omitempty"`
+}
+
+type Override struct {
+	Nxdomain bool     `json:"nxdomain"`
+	Answer   []string `json:"answer"`
+}
+
+// Explains how the plugin chain answers the request of the client, without
+// response checks.
+func (fl FilterList) check(ctx context.Context, name string, qtype uint16, client clientInfo) (Decision, error) {
+	name = dns.CanonicalName(name)
+	decision := Decision{
+		Name:     name,
+		Type:     dns.Type(qtype).String(),
+		ClientID: client.id,
+		Action:   ActionPass,
+	}
+	if client.ip.IsValid() {
+		decision.Client = client.ip.String()
+	}
+	if lookup := fl.overrides.get(); lookup != nil {
+		ctx, cancel := context.WithTimeout(ctx, checkLookupTimeout)
+		defer cancel()
+		res, err := lookup.Lookup(ctx, name, qtype)
+		if err != nil {
+			return Decision{}, err
+		}
+		if res != nil {
+			decision.Action = ActionOverride
+			decision.Override = &Override{Nxdomain: res.Nxdomain, Answer: res.Answer}
+			return decision, nil
+		}
+	}
+
+	var group *Group
+	for _, g := range fl.Groups {
+		if g.matches(client) {
+			group = g
+			break
+		}
+	}
+	if group == nil {
+		return decision, nil
+	}
+	decision.Group = group.Name
+	engine := group.Engine.Load()
+	if engine == nil {
+		return decision, nil
+	}
+	if fl.pauses.paused(group.Name, time.Now()) {
+		decision.Action = ActionPaused
+		return decision, nil
+	}
+	request := &urlfilter.DNSRequest{
+		Hostname:         strings.TrimSuffix(name, "."),
+		ClientIP:         client.ip,
+		ClientName:       client.id,
+		SortedClientTags: group.ClientTags,
+		DNSType:          qtype,
+	}
+	result, ok := engine.MatchRequest(request)
+	if rewrites := group.trustedRewrites(result); len(rewrites) != 0 {
+		decision.Action = ActionRewrite
+		decision.Rule = rewrites[0].Text()
+		decision.List = group.listName(uint64(rewrites[0].GetFilterListID()))
+		return decision, nil
+	}
+	if ok {
+		if rule := matchingRule(result); rule != nil {
+			decision.Rule = rule.Text()
+			decision.List = group.listName(uint64(rule.GetFilterListID()))
+			decision.Action = ActionBlock
+			if network, ok := rule.(*rules.NetworkRule); ok && network.Whitelist {
+				decision.Action = ActionAllow
+				decision.Allowlisted = true
+			}
+		}
+	}
+	if auditEngine := group.auditEngine.Load(); auditEngine != nil && decision.Action != ActionBlock {
+		if result, ok := auditEngine.MatchRequest(request); ok {
+			if listID, blocked := getMatchingListID(result); blocked {
+				decision.AuditList = group.listName(listID)
+				decision.AuditRule = matchingRule(result).Text()
+			}
+		}
+	}
+	return decision, nil
+}
+
+// Returns the rule deciding the result, see getMatchingListID.
+func matchingRule(result *urlfilter.DNSResult) rules.Rule {
+	switch {
+	case result.NetworkRule != nil:
+		return result.NetworkRule
+	case result.HostRulesV4 != nil:
+		return result.HostRulesV4[0]
+	case result.HostRulesV6 != nil:
+		return result.HostRulesV6[0]
+	}
+	return nil
+}
+
+// Serves the decisions of the plugin on GET /check, with the name, type,
+// client and client_id query parameters. The type defaults to A. The status of
+// fetching the lists is served on GET /lists.
+func (fl *FilterList) checkHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /check", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		name := query.Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		if _, ok := dns.IsDomainName(name); !ok {
+			http.Error(w, "invalid name", http.StatusBadRequest)
+			return
+		}
+		qtype := dns.TypeA
+		if t := query.Get("type"); t != "" {
+			var ok bool
+			qtype, ok = dns.StringToType[strings.ToUpper(t)]
+			if !ok {
+				http.Error(w, "unknown type", http.StatusBadRequest)
+				return
+			}
+		}
+		client := clientInfo{id: query.Get("client_id")}
+		if ip := query.Get("client"); ip != "" {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				http.Error(w, "invalid client", http.StatusBadRequest)
+				return
+			}
+			client.ip = addr.Unmap()
+		}
+		decision, err := fl.check(r.Context(), name, qtype, client)
+		if err != nil {
+			fl.Logger.Error("failed to check request", zap.String("name", name), zap.Error(err))
+			http.Error(w, "failed to look up records in the API", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(decision)
+	})
+	mux.HandleFunc("GET /lists", func(w http.ResponseWriter, r *http.Request) {
+		statuses := fl.ListStatuses()
+		if statuses == nil {
+			statuses = []ListStatus{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(statuses)
+	})
+	return mux
+}
@@ -0,0 +1,230 @@
+package filterlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+)
+
+func TestCheck(t *testing.T) {
+	kids := &Group{
+		Name:       "kids",
+		Networks:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+		Blocklists: []string{"https://example.com/ads.txt", "https://example.com/new.txt"},
+		Allowlists: []string{"https://example.com/allow.txt"},
+		Rules:      []string{"||rewrite.example.com^$dnsrewrite=192.0.2.1", "0.0.0.0 hosts.example.com"},
+		Audit:      []string{"https://example.com/new.txt"},
+	}
+	guests := &Group{Name: "guests", ClientIDs: []string{"guest"}, Rules: []string{"||ads.example.com^"}}
+	b := newEngineBuilder()
+	if err := b.buildAll([]*Group{guests}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err := b.setLists(kids, []FetchedList{
+		{URL: kids.Blocklists[0], Content: "||ads.example.com^\n||allowed.example.com^"},
+		{URL: kids.Blocklists[1], Content: "||cdn.example.com^"},
+		{URL: kids.Allowlists[0], Content: AllowlistRules("allowed.example.com")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{
+		Groups: []*Group{kids, guests},
+		Logger: zap.NewNop(),
+		pauses: &pauses{},
+		overrides: newMockOverrides(mockOverrides{
+			"api.example.com.": &resolver.Response{Answer: []string{"api.example.com.\t300\tIN\tA\t192.0.2.2"}},
+		}),
+	}
+	fl.pauses.set([]*resolver.FilterPause{{Group: "guests", Until: time.Now().Add(time.Hour).Unix()}})
+	kidsClient := clientInfo{ip: netip.MustParseAddr("10.0.0.5")}
+	tests := []struct {
+		name     string
+		client   clientInfo
+		expected Decision
+	}{
+		{
+			name:     "ads.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionBlock, Group: "kids", Rule: "||ads.example.com^", List: "https://example.com/ads.txt"},
+		},
+		{
+			name:     "allowed.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionAllow, Group: "kids", Rule: "@@||allowed.example.com^", List: "https://example.com/allow.txt", Allowlisted: true},
+		},
+		{
+			name:     "hosts.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionBlock, Group: "kids", Rule: "0.0.0.0 hosts.example.com", List: "local"},
+		},
+		{
+			name:     "rewrite.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionRewrite, Group: "kids", Rule: "||rewrite.example.com^$dnsrewrite=192.0.2.1", List: "local"},
+		},
+		{
+			name:     "cdn.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionPass, Group: "kids", AuditRule: "||cdn.example.com^", AuditList: "https://example.com/new.txt"},
+		},
+		{
+			name:     "api.example.com",
+			client:   kidsClient,
+			expected: Decision{Action: ActionOverride, Override: &Override{Answer: []string{"api.example.com.\t300\tIN\tA\t192.0.2.2"}}},
+		},
+		{name: "ads.example.com", client: clientInfo{id: "guest"}, expected: Decision{Action: ActionPaused, Group: "guests"}},
+		{name: "ads.example.com", client: clientInfo{ip: netip.MustParseAddr("10.0.1.5")}, expected: Decision{Action: ActionPass}},
+	}
+	for i, tc := range tests {
+		decision, err := fl.check(context.Background(), tc.name, dns.TypeA, tc.client)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		tc.expected.Name = dns.Fqdn(tc.name)
+		tc.expected.Type = "A"
+		tc.expected.ClientID = tc.client.id
+		if tc.client.ip.IsValid() {
+			tc.expected.Client = tc.client.ip.String()
+		}
+		got, _ := json.Marshal(decision)
+		expected, _ := json.Marshal(tc.expected)
+		if string(got) != string(expected) {
+			t.Errorf("Test %d: expected decision %s, got %s", i, expected, got)
+		}
+	}
+}
+
+func TestCheckHandler(t *testing.T) {
+	engine, err := CreateEngine([]string{"||example.com^"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := &FilterList{
+		Groups: []*Group{newEngineGroup(defaultGroupName, engine)},
+		Logger: zap.NewNop(),
+	}
+	h := fl.checkHandler()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/check?name=ads.example.com&type=aaaa&client=192.0.2.1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var decision Decision
+	if err := json.Unmarshal(w.Body.Bytes(), &decision); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decision.Action != ActionBlock || decision.Type != "AAAA" || decision.Client != "192.0.2.1" || decision.Group != defaultGroupName {
+		t.Errorf("expected the request to be blocked, got %v", decision)
+	}
+
+	for _, query := range []string{"", "name=example..com", "name=example.com&type=FOO", "name=example.com&client=foo"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/check?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+
+	fl.overrides = newMockOverrides(mockOverrides{"error.example.com.": nil})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/check?name=error.example.com", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502, got %d", w.Code)
+	}
+}
+
+// Responses by name, a nil response fails the lookup.
+type mockOverrides map[string]*resolver.Response
+
+func newMockOverrides(lookup mockOverrides) *overrides {
+	o := &overrides{}
+	o.set(lookup)
+	return o
+}
+
+func (o mockOverrides) Lookup(ctx context.Context, name string, qtype uint16) (*resolver.Response, error) {
+	res, ok := o[name]
+	if ok && res == nil {
+		return nil, errors.New("lookup failed")
+	}
+	return res, nil
+}
+
+func TestCheckHandlerOverridesFoundAtStartup(t *testing.T) {
+	engine, err := CreateEngine([]string{"||api.example.com^"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := &FilterList{
+		Groups:    []*Group{newEngineGroup(defaultGroupName, engine)},
+		Logger:    zap.NewNop(),
+		overrides: &overrides{},
+	}
+	// The plugin is added to the chain and the handler created before the
+	// injector plugin is found at startup.
+	chained := *fl
+	h := fl.checkHandler()
+	fl.overrides.set(mockOverrides{
+		"api.example.com.": &resolver.Response{Answer: []string{"api.example.com.\t300\tIN\tA\t192.0.2.2"}},
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/check?name=api.example.com", nil))
+	var decision Decision
+	if err := json.Unmarshal(w.Body.Bytes(), &decision); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decision.Action != ActionOverride || decision.Override == nil || len(decision.Override.Answer) != 1 {
+		t.Errorf("expected the override to be reported, got %v", decision)
+	}
+	decision, err = chained.check(context.Background(), "api.example.com.", dns.TypeA, clientInfo{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decision.Action != ActionOverride {
+		t.Errorf("expected the chained plugin to report the override, got %v", decision)
+	}
+}
+
+func TestCheckHandlerLists(t *testing.T) {
+	group := &Group{
+		Name:       defaultGroupName,
+		Blocklists: []string{"https://example.com/first", "https://example.com/second"},
+	}
+	b := newEngineBuilder()
+	fetched := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := b.setLists(group, []FetchedList{
+		{URL: group.Blocklists[0], Content: "||first.example.com^", Time: fetched},
+		{URL: group.Blocklists[1], Err: errors.New("status 500"), Time: fetched},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := &FilterList{Groups: []*Group{group}, Logger: zap.NewNop(), builder: b}
+	w := httptest.NewRecorder()
+	fl.checkHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lists", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var statuses []ListStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Rules != 1 || !statuses[0].LastSuccess.Equal(fetched) {
+		t.Fatalf("expected the first list to be fetched, got %v", statuses)
+	}
+	if statuses[1].LastError != "status 500" || !statuses[1].LastSuccess.IsZero() {
+		t.Errorf("expected the second list to have failed, got %v", statuses[1])
+	}
+}
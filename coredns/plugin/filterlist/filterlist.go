@@ -2,9 +2,12 @@ package filterlist
 
 import (
 	"context"
-	"fmt"
 	"net"
+	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AdguardTeam/urlfilter"
@@ -13,56 +16,172 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/querylog"
 	"go.uber.org/zap"
 )
 
 const name = "filterlist"
-const ttl = 604800
 
 type FilterList struct {
-	Next   plugin.Handler
-	Engine *urlfilter.DNSEngine
-	Logger *zap.Logger
+	Next plugin.Handler
+	// Requests are filtered by the first matching group, requests not
+	// matching any group are passed through.
+	Groups []*Group
+	// EDNS0 local option code carrying client IDs.
+	ClientIDOption uint16
+	BlockMode      BlockMode
+	BlockTTL       uint32
+	// Addresses answered in BlockModeCustomIP, at most one IPv4 and one IPv6.
+	BlockIPs []net.IP
+	// Resolves the targets of CNAME rewrites.
+	Upstream Upstream
+	// Blocks responses with CNAME targets or addresses matching the lists.
+	CheckResponses bool
+	Logger         *zap.Logger
+	// Nil when the lists are not fetched.
+	builder *engineBuilder
+	// Nil when the most blocked domains are not counted.
+	topBlocked *topDomains
+	// Nil without the API server.
+	pauses *pauses
+	// Nil without the check endpoint.
+	overrides *overrides
 }
 
 func (fl FilterList) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
-	if fl.Engine == nil {
+	state := request.Request{W: w, Req: r}
+	group, client := fl.matchGroup(state)
+	if group == nil {
+		return plugin.NextOrFailure(fl.Name(), fl.Next, ctx, w, r)
+	}
+	engine := group.Engine.Load()
+	if engine == nil {
 		return plugin.NextOrFailure(fl.Name(), fl.Next, ctx, w, r)
 	}
 
-	state := request.Request{W: w, Req: r}
 	hostname := strings.TrimSuffix(state.Name(), ".")
 
-	requestsTotal.Add(1)
-	matchResult, ok := fl.Engine.MatchRequest(&urlfilter.DNSRequest{
-		Hostname: hostname,
-		DNSType:  state.QType(),
-	})
-	if !ok {
+	querylog.SetFilterResult(ctx, group.Name, "")
+	requestsTotal.WithLabelValues(group.Name).Inc()
+	if fl.pauses.paused(group.Name, time.Now()) {
+		requestsPaused.WithLabelValues(group.Name).Inc()
 		return plugin.NextOrFailure(fl.Name(), fl.Next, ctx, w, r)
 	}
-	listID, ok := getMatchingListID(matchResult)
-	if ok {
-		m := new(dns.Msg)
-		m.SetReply(r)
-		hdr := dns.RR_Header{Name: state.QName(), Ttl: ttl, Class: dns.ClassINET, Rrtype: dns.TypeA}
-		m.Answer = []dns.RR{&dns.A{Hdr: hdr, A: net.ParseIP("0.0.0.0").To4()}}
-		m.Rcode = dns.RcodeSuccess
-		requestsBlocked.Add(1)
-		fl.Logger.Info("request blocked",
+	matchResult, ok := engine.MatchRequest(&urlfilter.DNSRequest{
+		Hostname:         hostname,
+		ClientIP:         client.ip,
+		ClientName:       client.id,
+		SortedClientTags: group.ClientTags,
+		DNSType:          state.QType(),
+	})
+	// Rewrite rules are not reported as matches.
+	if rewrites := group.trustedRewrites(matchResult); len(rewrites) != 0 {
+		m, err := fl.rewriteResponse(ctx, state, rewrites)
+		if err != nil {
+			fl.Logger.Error("failed to rewrite request", zap.String("name", state.Name()), zap.Error(err))
+			return dns.RcodeServerFailure, err
+		}
+		requestsRewritten.WithLabelValues(group.Name).Inc()
+		fl.Logger.Info("request rewritten",
 			zap.String("name", state.Name()),
-			zap.Uint64("blocklist", listID),
+			zap.String("group", group.Name),
+			zap.String("rule", rewrites[0].Text()),
 		)
 		return m.Rcode, w.WriteMsg(m)
-
 	}
-	// Only DNS rewrite rules were matched.
-	// We ignore them, as they may lead to DNS hijack through blocklists.
+	if ok {
+		if listID, ok := getMatchingListID(matchResult); ok {
+			return fl.block(ctx, w, state, group, listID, "")
+		}
+	}
+	if auditEngine := group.auditEngine.Load(); auditEngine != nil {
+		fl.audit(auditEngine, state, group, client, hostname)
+	}
+	if fl.CheckResponses {
+		w = &responseChecker{
+			ResponseWriter: w,
+			ctx:            ctx,
+			fl:             fl,
+			state:          state,
+			group:          group,
+			engine:         engine,
+			client:         client,
+		}
+	}
 	return plugin.NextOrFailure(fl.Name(), fl.Next, ctx, w, r)
 }
 
+// Logs and counts requests the audited lists would block, the request is
+// still passed through.
+func (fl FilterList) audit(auditEngine *urlfilter.DNSEngine, state request.Request, group *Group, client clientInfo, hostname string) {
+	result, ok := auditEngine.MatchRequest(&urlfilter.DNSRequest{
+		Hostname:         hostname,
+		ClientIP:         client.ip,
+		ClientName:       client.id,
+		SortedClientTags: group.ClientTags,
+		DNSType:          state.QType(),
+	})
+	if !ok {
+		return
+	}
+	listID, ok := getMatchingListID(result)
+	if !ok {
+		return
+	}
+	list := group.listName(listID)
+	if !slices.Contains(group.Audit, list) {
+		return
+	}
+	requestsWouldBlock.WithLabelValues(group.Name, list, dns.Type(state.QType()).String()).Inc()
+	fl.Logger.Info("request would be blocked",
+		zap.String("name", state.Name()),
+		zap.String("group", group.Name),
+		zap.Uint64("blocklist", listID),
+		zap.String("list", list),
+	)
+}
+
+// Answers the request with a block response. The hop is the CNAME target or
+// address of the response matching the lists, empty when the question name
+// matched.
+func (fl FilterList) block(ctx context.Context, w dns.ResponseWriter, state request.Request, group *Group, listID uint64, hop string) (int, error) {
+	m := fl.blockResponse(state)
+	list := group.listName(listID)
+	querylog.SetFilterResult(ctx, group.Name, list)
+	requestsBlocked.WithLabelValues(group.Name, list, dns.Type(state.QType()).String()).Inc()
+	if fl.topBlocked != nil {
+		fl.topBlocked.add(state.Name())
+	}
+	fields := []zap.Field{
+		zap.String("name", state.Name()),
+		zap.String("group", group.Name),
+		zap.Uint64("blocklist", listID),
+		zap.String("list", list),
+	}
+	if hop != "" {
+		fields = append(fields, zap.String("hop", hop))
+	}
+	fl.Logger.Info("request blocked", fields...)
+	return m.Rcode, w.WriteMsg(m)
+}
+
+func (fl FilterList) matchGroup(state request.Request) (*Group, clientInfo) {
+	client := newClientInfo(state, fl.ClientIDOption)
+	for _, group := range fl.Groups {
+		if group.matches(client) {
+			return group, client
+		}
+	}
+	return nil, client
+}
+
+// Allowlist exception rules take precedence over blocking rules, and are
+// returned as the network rule of the result.
 func getMatchingListID(result *urlfilter.DNSResult) (uint64, bool) {
 	if result.NetworkRule != nil {
+		if result.NetworkRule.Whitelist {
+			return 0, false
+		}
 		return uint64(result.NetworkRule.GetFilterListID()), true
 	}
 	if result.HostRulesV4 != nil {
@@ -74,6 +193,14 @@ func getMatchingListID(result *urlfilter.DNSResult) (uint64, bool) {
 	return 0, false
 }
 
+// Returns the status of fetching the lists of every group.
+func (fl FilterList) ListStatuses() []ListStatus {
+	if fl.builder == nil {
+		return nil
+	}
+	return fl.builder.listStatuses(fl.Groups)
+}
+
 func (fl FilterList) Name() string {
 	return name
 }
@@ -94,35 +221,125 @@ func CreateEngine(ruleContents []string) (*urlfilter.DNSEngine, error) {
 	return urlfilter.NewDNSEngine(ruleStorage), nil
 }
 
-func CreateEngineFromRemote(
-	urls []string,
-	failuresUntilBackoff int,
-	backoffDuration time.Duration,
-	failuresUntilError int,
-) (*urlfilter.DNSEngine, error) {
-	lists := []string{}
-	anyFailed := false
-	for _, url := range urls {
-		fetcher := URLFetcher{
-			url: url,
+// Converts allowlist contents of plain domains, hosts, and adblock style
+// rules to exception rules.
+func AllowlistRules(content string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "#") {
+			continue
 		}
-		retrier := Retrier{
-			fetcher: fetcher,
-			sleeper: RealSleeper{},
+		if strings.HasPrefix(line, "@@") {
+			b.WriteString(line + "\n")
+			continue
 		}
-		res, err := retrier.FetchWithRetryAndBackoff(failuresUntilBackoff, backoffDuration, failuresUntilError)
-		if err != nil {
-			anyFailed = true
+		if strings.HasPrefix(line, "|") || strings.HasPrefix(line, "/") {
+			b.WriteString("@@" + line + "\n")
 			continue
 		}
-		lists = append(lists, res)
+		fields := strings.Fields(line)
+		// Hosts lines start with the address.
+		if _, err := netip.ParseAddr(fields[0]); err == nil {
+			fields = fields[1:]
+		}
+		for _, domain := range fields {
+			if strings.HasPrefix(domain, "#") {
+				break
+			}
+			b.WriteString("@@||" + domain + "^\n")
+		}
+	}
+	return b.String()
+}
+
+// Result of fetching a list.
+type FetchedList struct {
+	URL string
+	// Allowlists are converted to exception rules. Empty when fetching failed.
+	Content string
+	Err     error
+	// Failure to cache the fetched copy, which is still used.
+	CacheErr error
+	// Time of the fetch, or of the cached copy.
+	Time time.Time
+}
+
+// Options of fetching lists.
+type FetchOptions struct {
+	// Uses http.DefaultClient when nil.
+	Client *http.Client
+	// Maximum size of a list in bytes, unlimited when 0.
+	MaxSize int64
+	Retry   RetryPolicy
+}
+
+// Fetches the blocklists and allowlists concurrently, allowlists are converted
+// to exception rules. The results are indexed like blocklistURLs followed by
+// allowlistURLs. Cached copies are revalidated, and replaced when changed.
+func FetchLists(
+	cache *listCache,
+	blocklistURLs []string,
+	allowlistURLs []string,
+	options FetchOptions,
+) []FetchedList {
+	urls := append(append([]string{}, blocklistURLs...), allowlistURLs...)
+	lists := make([]FetchedList, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i] = fetchList(cache, url, len(blocklistURLs) <= i, options)
+		}()
+	}
+	wg.Wait()
+	return lists
+}
+
+func fetchList(
+	cache *listCache,
+	url string,
+	allowlist bool,
+	options FetchOptions,
+) FetchedList {
+	// Unreadable cached copies are reported when loading them at setup, and
+	// are replaced by the fetched copy.
+	cached, _ := cache.get(url)
+	fetcher := &URLFetcher{
+		url:     url,
+		client:  options.Client,
+		maxSize: options.MaxSize,
+		cached:  cached,
 	}
-	engine, err := CreateEngine(lists)
+	retrier := Retrier{
+		fetcher: fetcher,
+		sleeper: RealSleeper{},
+	}
+	res, err := retrier.FetchWithPolicy(options.Retry)
+	list := FetchedList{URL: url, Time: time.Now()}
 	if err != nil {
-		return nil, err
+		list.Err = err
+		return list
+	}
+	if fetcher.cached != cached {
+		list.CacheErr = cache.put(fetcher.cached)
+	}
+	if allowlist {
+		res = AllowlistRules(res)
 	}
-	if anyFailed {
-		return engine, fmt.Errorf("partially fetched lists, engine may still be used")
+	list.Content = res
+	return list
+}
+
+// Counts the rules and hosts lines of list contents, skipping comments.
+func countRules(content string) int {
+	count := 0
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "!") && !strings.HasPrefix(line, "#") {
+			count++
+		}
 	}
-	return engine, nil
+	return count
 }
@@ -2,6 +2,8 @@ package filterlist
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
@@ -16,7 +18,7 @@ func TestFilterlist(t *testing.T) {
 		t.Errorf("expected no error, but got %v", err)
 	}
 	fl := FilterList{
-		Engine: engine,
+		Groups: []*Group{newEngineGroup(defaultGroupName, engine)},
 		Logger: zap.NewNop(),
 	}
 	tests := []struct {
@@ -70,3 +72,77 @@ func TestFilterlist(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterlistAllowlist(t *testing.T) {
+	engine, err := CreateEngine([]string{
+		"||example.com^\nexample.net",
+		AllowlistRules("! comment\nfoo.example.com\n0.0.0.0 example.net"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	fl := FilterList{
+		Groups: []*Group{newEngineGroup(defaultGroupName, engine)},
+		Logger: zap.NewNop(),
+	}
+	tests := []struct {
+		qname   string
+		blocked bool
+	}{
+		{qname: "example.com", blocked: true},
+		{qname: "bar.example.com", blocked: true},
+		{qname: "foo.example.com", blocked: false},
+		{qname: "example.net", blocked: false},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn(tc.qname), dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := fl.ServeDNS(context.TODO(), rec, req)
+		// Passed through requests fail without a next plugin.
+		blocked := err == nil && rec.Msg != nil && len(rec.Msg.Answer) == 1
+		if blocked != tc.blocked {
+			t.Errorf("Test %d: Expected %s blocked to be %t, got %t", i, tc.qname, tc.blocked, blocked)
+		}
+	}
+}
+
+func TestAllowlistRules(t *testing.T) {
+	content := "# comment\n\nexample.com\n127.0.0.1 foo.com bar.com # hosts\n||example.net^\n@@||example.org^\n/ads[0-9]/"
+	expected := "@@||example.com^\n@@||foo.com^\n@@||bar.com^\n@@||example.net^\n@@||example.org^\n@@/ads[0-9]/\n"
+	if rules := AllowlistRules(content); rules != expected {
+		t.Errorf("Expected %q, got %q", expected, rules)
+	}
+}
+
+func TestFetchLists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/block":
+			_, _ = w.Write([]byte("||example.com^"))
+		case "/allow":
+			_, _ = w.Write([]byte("foo.example.com"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	lists := FetchLists(newListCache(""),
+		[]string{server.URL + "/block", server.URL + "/missing"},
+		[]string{server.URL + "/allow"},
+		FetchOptions{Retry: RetryPolicy{FailuresUntilBackoff: 5, BackoffFactor: 1, FailuresUntilError: 1}},
+	)
+	if len(lists) != 3 {
+		t.Fatalf("expected 3 lists, got %d", len(lists))
+	}
+	if lists[0].Err != nil || lists[0].Content != "||example.com^" {
+		t.Errorf("expected blocklist content, got %q %v", lists[0].Content, lists[0].Err)
+	}
+	if lists[1].Err == nil || lists[1].URL != server.URL+"/missing" {
+		t.Errorf("expected missing list to fail, got %v", lists[1])
+	}
+	if lists[2].Err != nil || lists[2].Content != "@@||foo.example.com^\n" {
+		t.Errorf("expected allowlist exception rules, got %q %v", lists[2].Content, lists[2].Err)
+	}
+}
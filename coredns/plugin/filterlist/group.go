@@ -0,0 +1,142 @@
+package filterlist
+
+import (
+	"net/netip"
+	"slices"
+	"sync/atomic"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// Name of the group of the top level blocklists and allowlists.
+const defaultGroupName = "default"
+
+// EDNS0 local option code carrying client IDs, the first code of the range
+// reserved for local use.
+const defaultClientIDOption = 65001
+
+// Group of clients sharing blocklists, allowlists and rules. A group without
+// networks or client IDs matches every client.
+type Group struct {
+	Name string
+	// Matched against the client IP.
+	Networks []netip.Prefix
+	// Matched against the address of the EDNS Client Subnet option.
+	ClientSubnets []netip.Prefix
+	// Matched against the client ID EDNS0 local option.
+	ClientIDs []string
+	// Sorted tags matched by $ctag rules.
+	ClientTags []string
+	Blocklists []string
+	Allowlists []string
+	// Inline adblock style rules and hosts lines.
+	Rules []string
+	// Paths of local files of adblock style rules and hosts lines.
+	RulesFiles []string
+	// Sorted names of the catalog services to block.
+	Services []string
+	// Sorted names of the search engines to enforce safe search of.
+	SafeSearch []string
+	// Lists and rules applied only while their schedule is active.
+	Schedules []*Schedule
+	// Names of the lists matched without blocking, the URLs of blocklists
+	// and the services as "service:NAME".
+	Audit []string
+	// Nil while there are no lists or rules. Replaced while requests are
+	// served, so the group must not be copied.
+	Engine atomic.Pointer[urlfilter.DNSEngine]
+	// Engine of the enforced and audited lists, nil without audited lists.
+	auditEngine atomic.Pointer[urlfilter.DNSEngine]
+}
+
+// Returns the blocklists of the group followed by the blocklists of its
+// schedules, which are fetched together.
+func (g *Group) blocklistURLs() []string {
+	urls := slices.Clone(g.Blocklists)
+	for _, schedule := range g.Schedules {
+		urls = append(urls, schedule.Blocklists...)
+	}
+	return urls
+}
+
+// Returns the count of the fetched lists of the group and its schedules.
+func (g *Group) fetchedCount() int {
+	count := len(g.Blocklists) + len(g.Allowlists)
+	for _, schedule := range g.Schedules {
+		count += len(schedule.Blocklists)
+	}
+	return count
+}
+
+// Returns the names of the lists of the group that may be audited.
+func (g *Group) auditableLists() []string {
+	lists := g.blocklistURLs()
+	for _, service := range g.Services {
+		lists = append(lists, "service:"+service)
+	}
+	for _, schedule := range g.Schedules {
+		for _, service := range schedule.Services {
+			lists = append(lists, "service:"+service)
+		}
+	}
+	return lists
+}
+
+func (g *Group) matchesAll() bool {
+	return len(g.Networks) == 0 && len(g.ClientSubnets) == 0 && len(g.ClientIDs) == 0
+}
+
+func (g *Group) matches(client clientInfo) bool {
+	if g.matchesAll() {
+		return true
+	}
+	for _, id := range g.ClientIDs {
+		if client.id != "" && id == client.id {
+			return true
+		}
+	}
+	for _, prefix := range g.ClientSubnets {
+		if client.subnet.IsValid() && prefix.Contains(client.subnet) {
+			return true
+		}
+	}
+	for _, prefix := range g.Networks {
+		if client.ip.IsValid() && prefix.Contains(client.ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identifying details of the client sending a request.
+type clientInfo struct {
+	ip     netip.Addr
+	subnet netip.Addr
+	id     string
+}
+
+func newClientInfo(state request.Request, clientIDOption uint16) clientInfo {
+	client := clientInfo{}
+	if ip, err := netip.ParseAddr(state.IP()); err == nil {
+		client.ip = ip.Unmap()
+	}
+	opt := state.Req.IsEdns0()
+	if opt == nil {
+		return client
+	}
+	for _, option := range opt.Option {
+		switch o := option.(type) {
+		case *dns.EDNS0_SUBNET:
+			if subnet, ok := netip.AddrFromSlice(o.Address); ok {
+				client.subnet = subnet.Unmap()
+			}
+		case *dns.EDNS0_LOCAL:
+			if o.Code == clientIDOption {
+				client.id = string(o.Data)
+			}
+		}
+	}
+	return client
+}
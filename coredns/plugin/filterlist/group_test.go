@@ -0,0 +1,110 @@
+package filterlist
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+func TestMatchGroup(t *testing.T) {
+	kids := &Group{
+		Name:     "kids",
+		Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+	}
+	office := &Group{
+		Name:          "office",
+		ClientSubnets: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+		ClientIDs:     []string{"laptop"},
+	}
+	fallback := &Group{Name: defaultGroupName}
+	fl := FilterList{
+		Groups:         []*Group{kids, office, fallback},
+		ClientIDOption: defaultClientIDOption,
+	}
+	tests := []struct {
+		ip       string
+		subnet   net.IP
+		clientID string
+		expected *Group
+	}{
+		{ip: "10.0.0.5", expected: kids},
+		{ip: "10.0.1.5", expected: fallback},
+		{ip: "10.0.1.5", subnet: net.ParseIP("192.168.1.0"), expected: office},
+		{ip: "10.0.1.5", clientID: "laptop", expected: office},
+		{ip: "10.0.1.5", clientID: "phone", expected: fallback},
+		{ip: "10.0.0.5", clientID: "laptop", expected: kids},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		if tc.subnet != nil || tc.clientID != "" {
+			opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+			if tc.subnet != nil {
+				opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+					Code:          dns.EDNS0SUBNET,
+					Family:        1,
+					SourceNetmask: 24,
+					Address:       tc.subnet,
+				})
+			}
+			if tc.clientID != "" {
+				opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{
+					Code: defaultClientIDOption,
+					Data: []byte(tc.clientID),
+				})
+			}
+			req.Extra = append(req.Extra, opt)
+		}
+		w := &test.ResponseWriter{RemoteIP: tc.ip}
+		if group, _ := fl.matchGroup(request.Request{W: w, Req: req}); group != tc.expected {
+			t.Errorf("Test %d: Expected group %s, got %v", i, tc.expected.Name, group)
+		}
+	}
+}
+
+func TestFilterlistGroups(t *testing.T) {
+	engine, err := CreateEngine([]string{"||example.com^"})
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	fl := FilterList{
+		Groups: []*Group{
+			{Name: "exempt", Networks: []netip.Prefix{netip.MustParsePrefix("10.240.0.1/32")}},
+			newEngineGroup(defaultGroupName, engine),
+		},
+		Logger: zap.NewNop(),
+	}
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		// The default test.ResponseWriter address.
+		{ip: "10.240.0.1", blocked: false},
+		{ip: "10.240.0.2", blocked: true},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.ip})
+		_, err := fl.ServeDNS(context.TODO(), rec, req)
+		// Passed through requests fail without a next plugin.
+		blocked := err == nil && rec.Msg != nil && len(rec.Msg.Answer) == 1
+		if blocked != tc.blocked {
+			t.Errorf("Test %d: Expected blocked to be %t, got %t", i, tc.blocked, blocked)
+		}
+	}
+}
+
+func newEngineGroup(name string, engine *urlfilter.DNSEngine) *Group {
+	group := &Group{Name: name}
+	group.Engine.Store(engine)
+	return group
+}
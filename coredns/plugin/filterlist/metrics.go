@@ -19,22 +19,76 @@ var (
 		Name:      "list_fetch_failures",
 		Help:      "Count of failures during list fetching.",
 	})
+	listLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "list_last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful fetch of each list.",
+	}, []string{"group", "list"})
+	listLastFetchFailed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "list_last_fetch_failed",
+		Help:      "Whether the last fetch of each list failed, 1 when it failed and 0 otherwise.",
+	}, []string{"group", "list"})
+	listRules = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "list_rules",
+		Help:      "Count of rules in each list.",
+	}, []string{"group", "list"})
+	engineBuildDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "engine_build_duration_seconds",
+		Help:      "Duration of the last filter engine build.",
+	}, []string{"group"})
 	listFetchesTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: name,
 		Name:      "list_fetches_total",
 		Help:      "Count of list fetches performed including failures.",
 	})
-	requestsBlocked = promauto.NewCounter(prometheus.CounterOpts{
+	requestsBlocked = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: name,
 		Name:      "requests_blocked",
 		Help:      "Count of requests blocked by filters.",
-	})
-	requestsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	}, []string{"group", "list", "qtype"})
+	requestsWouldBlock = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "requests_would_block",
+		Help:      "Count of requests passed through that audited lists would block.",
+	}, []string{"group", "list", "qtype"})
+	requestsRewritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "requests_rewritten",
+		Help:      "Count of requests rewritten by trusted rewrite rules.",
+	}, []string{"group"})
+	requestsPaused = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "requests_paused",
+		Help:      "Count of requests passed through while blocking was paused.",
+	}, []string{"group"})
+	scheduleActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "schedule_active",
+		Help:      "Whether the lists of each schedule are applied, 1 when active and 0 otherwise.",
+	}, []string{"group", "schedule"})
+	topBlockedDomains = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "top_blocked_domains",
+		Help:      "Approximate count of blocked requests of the most blocked domains.",
+	}, []string{"domain"})
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: name,
 		Name:      "requests_total",
 		Help:      "Count of requests handled by the plugin.",
-	})
+	}, []string{"group"})
 )
@@ -0,0 +1,74 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestFilterlistMetrics(t *testing.T) {
+	group := &Group{
+		Name:       "metrics",
+		Blocklists: []string{"https://example.com/list.txt"},
+		Rules:      []string{"||local.example.com^"},
+	}
+	b := newEngineBuilder()
+	fetched := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := b.setLists(group, []FetchedList{{URL: group.Blocklists[0], Content: "||ads.example.com^\n||tracker.example.com^", Time: fetched}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{
+		Groups:     []*Group{group},
+		Logger:     zap.NewNop(),
+		topBlocked: newTopDomains(1),
+	}
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"ads.example.com.", dns.TypeA},
+		{"ads.example.com.", dns.TypeAAAA},
+		{"ads.example.com.", dns.TypeAAAA},
+		{"local.example.com.", dns.TypeA},
+	} {
+		req := new(dns.Msg)
+		req.SetQuestion(q.name, q.qtype)
+		if _, err := fl.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	counters := []struct {
+		list, qtype string
+		expected    float64
+	}{
+		{"https://example.com/list.txt", "A", 1},
+		{"https://example.com/list.txt", "AAAA", 2},
+		{"local", "A", 1},
+	}
+	for _, c := range counters {
+		if v := testutil.ToFloat64(requestsBlocked.WithLabelValues("metrics", c.list, c.qtype)); v != c.expected {
+			t.Errorf("expected %v requests blocked by %s for %s, got %v", c.expected, c.list, c.qtype, v)
+		}
+	}
+	if v := testutil.ToFloat64(listRules.WithLabelValues("metrics", "https://example.com/list.txt")); v != 2 {
+		t.Errorf("expected 2 rules in list, got %v", v)
+	}
+	if v := testutil.ToFloat64(listLastSuccess.WithLabelValues("metrics", "https://example.com/list.txt")); v != float64(fetched.Unix()) {
+		t.Errorf("expected last success timestamp %d, got %v", fetched.Unix(), v)
+	}
+	fl.topBlocked.updateGauge()
+	if v := testutil.ToFloat64(topBlockedDomains.WithLabelValues("ads.example.com.")); v != 3 {
+		t.Errorf("expected ads.example.com. blocked 3 times, got %v", v)
+	}
+	if n := testutil.CollectAndCount(topBlockedDomains); n != 1 {
+		t.Errorf("expected 1 top blocked domain, got %d", n)
+	}
+}
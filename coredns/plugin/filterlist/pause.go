@@ -0,0 +1,54 @@
+package filterlist
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+)
+
+// Interval of fetching the pauses of blocking from the API server, short so
+// that pauses apply soon after they are created.
+const apiPausesRefreshInterval = 10 * time.Second
+
+// Pauses of blocking managed in the API server, by group name. The pause of
+// the empty group name applies to all groups.
+type pauses struct {
+	until atomic.Pointer[map[string]time.Time]
+}
+
+func (p *pauses) set(list []*resolver.FilterPause) {
+	until := map[string]time.Time{}
+	for _, pause := range list {
+		end := time.Unix(pause.Until, 0)
+		if end.After(until[pause.Group]) {
+			until[pause.Group] = end
+		}
+	}
+	p.until.Store(&until)
+}
+
+// Reports whether blocking is paused for the group at now. Never paused when
+// p is nil.
+func (p *pauses) paused(group string, now time.Time) bool {
+	if p == nil {
+		return false
+	}
+	until := p.until.Load()
+	if until == nil {
+		return false
+	}
+	return now.Before((*until)[""]) || now.Before((*until)[group])
+}
+
+// Fetches the active pauses from the API server. Pauses are kept until they
+// expire while the API server is unreachable.
+func refreshPauses(ctx context.Context, client resolver.FilterClient, p *pauses) error {
+	res, err := client.ListFilterPauses(ctx, &resolver.ListFilterPausesRequest{})
+	if err != nil {
+		return err
+	}
+	p.set(res.Pauses)
+	return nil
+}
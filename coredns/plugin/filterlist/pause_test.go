@@ -0,0 +1,84 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+)
+
+func TestPauses(t *testing.T) {
+	now := time.Now()
+	client := mockFilterClient{
+		pauses: []*resolver.FilterPause{
+			{Group: "kids", Until: now.Add(time.Minute).Unix()},
+			{Group: "kids", Until: now.Add(time.Hour).Unix()},
+			{Group: "guests", Until: now.Add(-time.Minute).Unix()},
+		},
+	}
+	p := &pauses{}
+	if p.paused("kids", now) {
+		t.Errorf("expected kids not to be paused before refreshing")
+	}
+	if err := refreshPauses(context.Background(), &client, p); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tests := []struct {
+		group    string
+		at       time.Time
+		expected bool
+	}{
+		{group: "kids", at: now, expected: true},
+		{group: "kids", at: now.Add(30 * time.Minute), expected: true},
+		{group: "kids", at: now.Add(2 * time.Hour), expected: false},
+		{group: "guests", at: now, expected: false},
+		{group: defaultGroupName, at: now, expected: false},
+	}
+	for _, tc := range tests {
+		if paused := p.paused(tc.group, tc.at); paused != tc.expected {
+			t.Errorf("group %s at %s: expected paused to be %t, got %t", tc.group, tc.at, tc.expected, paused)
+		}
+	}
+
+	client.pauses = []*resolver.FilterPause{{Until: now.Add(time.Minute).Unix()}}
+	if err := refreshPauses(context.Background(), &client, p); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !p.paused("guests", now) || !p.paused(defaultGroupName, now) {
+		t.Errorf("expected all groups to be paused")
+	}
+
+	var nilPauses *pauses
+	if nilPauses.paused("kids", now) {
+		t.Errorf("expected nil pauses never to be paused")
+	}
+}
+
+func TestFilterlistPaused(t *testing.T) {
+	engine, err := CreateEngine([]string{"example.com"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{
+		Next:   test.NextHandler(dns.RcodeRefused, nil),
+		Groups: []*Group{newEngineGroup(defaultGroupName, engine)},
+		Logger: zap.NewNop(),
+		pauses: &pauses{},
+	}
+	fl.pauses.set([]*resolver.FilterPause{{Until: time.Now().Add(time.Minute).Unix()}})
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	code, err := fl.ServeDNS(context.TODO(), rec, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if code != dns.RcodeRefused {
+		t.Errorf("expected the request to pass to the next plugin, got code %d", code)
+	}
+}
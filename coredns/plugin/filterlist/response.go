@@ -0,0 +1,58 @@
+package filterlist
+
+import (
+	"context"
+	"strings"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+)
+
+// Response writer matching the CNAME targets and A and AAAA addresses of the
+// answer against the engine of the group, to block trackers hidden behind
+// first-party CNAMEs. Responses with any matching hop are replaced with a
+// block response.
+type responseChecker struct {
+	dns.ResponseWriter
+	ctx    context.Context
+	fl     FilterList
+	state  request.Request
+	group  *Group
+	engine *urlfilter.DNSEngine
+	client clientInfo
+}
+
+func (w *responseChecker) WriteMsg(res *dns.Msg) error {
+	for _, rr := range res.Answer {
+		var hop string
+		qtype := w.state.QType()
+		switch rr := rr.(type) {
+		case *dns.CNAME:
+			hop = strings.TrimSuffix(rr.Target, ".")
+		case *dns.A:
+			hop = rr.A.String()
+			qtype = dns.TypeA
+		case *dns.AAAA:
+			hop = rr.AAAA.String()
+			qtype = dns.TypeAAAA
+		default:
+			continue
+		}
+		result, ok := w.engine.MatchRequest(&urlfilter.DNSRequest{
+			Hostname:         hop,
+			ClientIP:         w.client.ip,
+			ClientName:       w.client.id,
+			SortedClientTags: w.group.ClientTags,
+			DNSType:          qtype,
+		})
+		if !ok {
+			continue
+		}
+		if listID, ok := getMatchingListID(result); ok {
+			_, err := w.fl.block(w.ctx, w.ResponseWriter, w.state, w.group, listID, hop)
+			return err
+		}
+	}
+	return w.ResponseWriter.WriteMsg(res)
+}
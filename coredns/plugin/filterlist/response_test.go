@@ -0,0 +1,115 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestFilterlistResponseCheck(t *testing.T) {
+	engine, err := CreateEngine([]string{
+		"||tracker.example.net^\n||192.0.2.66^\n||2001:db8::66^\n||allowed.example.net^",
+		AllowlistRules("allowed.example.net"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	answers := map[string][]dns.RR{
+		"metrics.example.com.": {
+			test.CNAME("metrics.example.com. 300 IN CNAME tracker.example.net."),
+			test.A("tracker.example.net. 300 IN A 192.0.2.10"),
+		},
+		"ip.example.com.":      {test.A("ip.example.com. 300 IN A 192.0.2.66")},
+		"ipv6.example.com.":    {test.AAAA("ipv6.example.com. 300 IN AAAA 2001:db8::66")},
+		"allowed.example.com.": {test.CNAME("allowed.example.com. 300 IN CNAME allowed.example.net.")},
+		"www.example.com.":     {test.A("www.example.com. 300 IN A 192.0.2.1")},
+	}
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = answers[r.Question[0].Name]
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	})
+	tests := []struct {
+		qname   string
+		qtype   uint16
+		check   bool
+		blocked bool
+	}{
+		{qname: "metrics.example.com.", qtype: dns.TypeA, check: true, blocked: true},
+		{qname: "metrics.example.com.", qtype: dns.TypeA, check: false, blocked: false},
+		{qname: "ip.example.com.", qtype: dns.TypeA, check: true, blocked: true},
+		{qname: "ipv6.example.com.", qtype: dns.TypeAAAA, check: true, blocked: true},
+		{qname: "allowed.example.com.", qtype: dns.TypeA, check: true, blocked: false},
+		{qname: "www.example.com.", qtype: dns.TypeA, check: true, blocked: false},
+	}
+	for i, tc := range tests {
+		fl := FilterList{
+			Next:           next,
+			Groups:         []*Group{newEngineGroup(defaultGroupName, engine)},
+			BlockTTL:       300,
+			Logger:         zap.NewNop(),
+			CheckResponses: tc.check,
+		}
+		req := new(dns.Msg)
+		req.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := fl.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if len(rec.Msg.Answer) != 1 && tc.blocked {
+			t.Errorf("Test %d: expected a block response, got %v", i, rec.Msg.Answer)
+			continue
+		}
+		blocked := false
+		switch rr := rec.Msg.Answer[0].(type) {
+		case *dns.A:
+			blocked = rr.A.IsUnspecified()
+		case *dns.AAAA:
+			blocked = rr.AAAA.IsUnspecified()
+		}
+		if blocked != tc.blocked {
+			t.Errorf("Test %d: expected blocked to be %t, got answer %v", i, tc.blocked, rec.Msg.Answer)
+		}
+	}
+}
+
+func TestFilterlistResponseCheckMetrics(t *testing.T) {
+	group := &Group{Name: "cloaking", Rules: []string{"||tracker.example.net^"}}
+	if err := newEngineBuilder().buildAll([]*Group{group}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{
+		Next: plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = []dns.RR{test.CNAME("metrics.example.com. 300 IN CNAME tracker.example.net.")}
+			return dns.RcodeSuccess, w.WriteMsg(m)
+		}),
+		Groups:         []*Group{group},
+		BlockMode:      BlockModeNXDomain,
+		Logger:         zap.NewNop(),
+		CheckResponses: true,
+	}
+	counter := requestsBlocked.WithLabelValues("cloaking", "local", "A")
+	before := testutil.ToFloat64(counter)
+	req := new(dns.Msg)
+	req.SetQuestion("metrics.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := fl.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("expected NXDOMAIN, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if after := testutil.ToFloat64(counter); after != before+1 {
+		t.Errorf("expected the blocked counter of the local list to increase, got %v", after-before)
+	}
+}
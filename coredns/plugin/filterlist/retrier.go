@@ -4,10 +4,34 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
 var ErrFailureCountReached = fmt.Errorf("fetch error failure count reached")
+var ErrListTooLarge = fmt.Errorf("list exceeds the maximum size")
+
+// Policy of retrying failed fetches.
+type RetryPolicy struct {
+	// Consecutive failures before sleeping for the backoff.
+	FailuresUntilBackoff int
+	Backoff              time.Duration
+	// The backoff is multiplied by this factor after each backoff, up to
+	// MaxBackoff. A factor of 1 keeps the backoff constant.
+	BackoffFactor float64
+	MaxBackoff    time.Duration
+	// Failures until the fetch fails.
+	FailuresUntilError int
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	FailuresUntilBackoff: 5,
+	Backoff:              time.Minute * 5,
+	BackoffFactor:        1,
+	MaxBackoff:           time.Minute * 5,
+	FailuresUntilError:   15,
+}
 
 type Retrier struct {
 	fetcher Fetcher
@@ -15,8 +39,19 @@ type Retrier struct {
 }
 
 func (r Retrier) FetchWithRetryAndBackoff(failuresUntilBackoff int, backoffWait time.Duration, failuresUntilError int) (result string, err error) {
-	remainingUntilBackoff := failuresUntilBackoff
-	for failures := 0; failures < failuresUntilError; failures++ {
+	return r.FetchWithPolicy(RetryPolicy{
+		FailuresUntilBackoff: failuresUntilBackoff,
+		Backoff:              backoffWait,
+		BackoffFactor:        1,
+		MaxBackoff:           backoffWait,
+		FailuresUntilError:   failuresUntilError,
+	})
+}
+
+func (r Retrier) FetchWithPolicy(policy RetryPolicy) (result string, err error) {
+	remainingUntilBackoff := policy.FailuresUntilBackoff
+	backoffWait := policy.Backoff
+	for failures := 0; failures < policy.FailuresUntilError; failures++ {
 		res, err := r.fetcher.Fetch()
 		listFetchesTotal.Add(1)
 		if err == nil {
@@ -26,36 +61,111 @@ func (r Retrier) FetchWithRetryAndBackoff(failuresUntilBackoff int, backoffWait
 		remainingUntilBackoff--
 		if remainingUntilBackoff == 0 {
 			listFetchBackoffs.Add(1)
-			remainingUntilBackoff = failuresUntilBackoff
+			remainingUntilBackoff = policy.FailuresUntilBackoff
 			r.sleeper.Sleep(backoffWait)
+			backoffWait = min(time.Duration(float64(backoffWait)*policy.BackoffFactor), policy.MaxBackoff)
 		}
 	}
-	return "", fmt.Errorf("%w: failed %d times", ErrFailureCountReached, failuresUntilError)
+	return "", fmt.Errorf("%w: failed %d times", ErrFailureCountReached, policy.FailuresUntilError)
 }
 
 type Fetcher interface {
 	Fetch() (string, error)
 }
 
+// Fetches lists over HTTP, or from local files with file:// URLs.
 type URLFetcher struct {
 	url string
+	// Uses http.DefaultClient when nil.
+	client *http.Client
+	// Maximum size of the list in bytes, unlimited when 0.
+	maxSize int64
+	// Previously fetched copy, revalidated with its validators and replaced
+	// by successful fetches. Nil when there is none.
+	cached *cachedList
 }
 
-func (f URLFetcher) Fetch() (string, error) {
-	resp, err := http.Get(f.url)
+func (f *URLFetcher) Fetch() (string, error) {
+	if path, ok := strings.CutPrefix(f.url, "file://"); ok {
+		return f.fetchFile(path)
+	}
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return "", err
+	}
+	if f.cached != nil {
+		if f.cached.ETag != "" {
+			req.Header.Set("If-None-Match", f.cached.ETag)
+		}
+		if f.cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", f.cached.LastModified)
+		}
+	}
+	client := f.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotModified && f.cached != nil {
+		return f.cached.Content, nil
+	}
+	if f.maxSize != 0 && f.maxSize < resp.ContentLength {
+		return "", fmt.Errorf("%w: %d bytes", ErrListTooLarge, resp.ContentLength)
+	}
+	body, err := f.read(resp.Body)
 	if err != nil {
 		return "", err
 	}
 	if resp.StatusCode != 200 {
 		return "", fmt.Errorf("status %d", resp.StatusCode)
 	}
+	f.cached = &cachedList{
+		URL:          f.url,
+		Content:      body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	return f.cached.Content, nil
+}
+
+func (f *URLFetcher) fetchFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	content, err := f.read(file)
+	if err != nil {
+		return "", err
+	}
+	if f.cached == nil || f.cached.Content != content {
+		f.cached = &cachedList{URL: f.url, Content: content, FetchedAt: time.Now()}
+	}
+	return content, nil
+}
+
+// Reads up to the maximum size, failing on larger lists.
+func (f *URLFetcher) read(r io.Reader) (string, error) {
+	if f.maxSize == 0 {
+		body, err := io.ReadAll(r)
+		return string(body), err
+	}
+	body, err := io.ReadAll(io.LimitReader(r, f.maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if f.maxSize < int64(len(body)) {
+		return "", fmt.Errorf("%w: over %d bytes", ErrListTooLarge, f.maxSize)
+	}
 	return string(body), nil
 }
 
@@ -2,6 +2,10 @@ package filterlist
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -100,3 +104,102 @@ func TestRetryReachingFailureThreshold(t *testing.T) {
 		t.Errorf("Expected retrier to sleep %v seconds, but got %v", expectedWait, sleeper.SecondsWaited())
 	}
 }
+
+func TestRetryExponentialBackoff(t *testing.T) {
+	// Backoffs of 1, 2, 4, and 5 minutes.
+	expectedWait := 60 * 12
+	sleeper := MockSleeper{}
+	retrier := Retrier{
+		fetcher: &MockFetcher{content: "foo", remainingFailurs: 8},
+		sleeper: &sleeper,
+	}
+	res, err := retrier.FetchWithPolicy(RetryPolicy{
+		FailuresUntilBackoff: 2,
+		Backoff:              time.Minute,
+		BackoffFactor:        2,
+		MaxBackoff:           time.Minute * 5,
+		FailuresUntilError:   10,
+	})
+	if err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+	if res != "foo" {
+		t.Errorf("Expected content to be 'foo', but got '%s'", res)
+	}
+	if sleeper.SecondsWaited() != expectedWait {
+		t.Errorf("Expected retrier to sleep %v seconds, but got %v", expectedWait, sleeper.SecondsWaited())
+	}
+}
+
+func TestURLFetcherRevalidation(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte("||example.com^"))
+	}))
+	defer server.Close()
+
+	fetcher := &URLFetcher{url: server.URL}
+	res, err := fetcher.Fetch()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	first := fetcher.cached
+	if res != "||example.com^" || first == nil || first.ETag != `"v1"` {
+		t.Fatalf("Expected fetched copy with ETag, but got %q %v", res, first)
+	}
+	res, err = fetcher.Fetch()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if res != "||example.com^" {
+		t.Errorf("Expected cached content, but got %q", res)
+	}
+	if fetcher.cached != first {
+		t.Errorf("Expected not modified list to keep the cached copy")
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, but got %d", requests)
+	}
+}
+
+func TestURLFetcherMaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("||example.com^"))
+	}))
+	defer server.Close()
+
+	fetcher := &URLFetcher{url: server.URL, maxSize: 8}
+	if _, err := fetcher.Fetch(); !errors.Is(err, ErrListTooLarge) {
+		t.Errorf("Expected error wrapping ErrListTooLarge, but got %v", err)
+	}
+	fetcher = &URLFetcher{url: server.URL, maxSize: 14}
+	if res, err := fetcher.Fetch(); err != nil || res != "||example.com^" {
+		t.Errorf("Expected content without error, but got %q %v", res, err)
+	}
+}
+
+func TestURLFetcherFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("||example.com^"), 0o644); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	fetcher := &URLFetcher{url: "file://" + path}
+	res, err := fetcher.Fetch()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if res != "||example.com^" || fetcher.cached == nil {
+		t.Errorf("Expected file content to be fetched, but got %q", res)
+	}
+	fetcher = &URLFetcher{url: "file://" + filepath.Join(t.TempDir(), "missing.txt")}
+	if _, err := fetcher.Fetch(); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,150 @@
+package filterlist
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// CNAME rewrites are resolved through the plugin chain, which may rewrite the
+// target again, at most this many times.
+const maxRewriteDepth = 8
+
+type Upstream interface {
+	Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
+}
+
+// Context key of the count of CNAME rewrites resolving the current request.
+type rewriteDepthKey struct{}
+
+// Returns the DNS rewrite rules of the trusted lists of the group, after
+// applying exception rules.
+func (g *Group) trustedRewrites(result *urlfilter.DNSResult) []*rules.NetworkRule {
+	rewrites := []*rules.NetworkRule{}
+	for _, rule := range result.DNSRewrites() {
+		if g.trusted(rule.GetFilterListID()) {
+			rewrites = append(rewrites, rule)
+		}
+	}
+	return rewrites
+}
+
+// Builds the response to a rewritten request. A CNAME rewrite takes
+// precedence over rcode rewrites, which take precedence over record rewrites.
+// Record rewrites of other types than the question type result in NODATA.
+func (fl FilterList) rewriteResponse(ctx context.Context, state request.Request, rewrites []*rules.NetworkRule) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Rcode = dns.RcodeSuccess
+	for _, rule := range rewrites {
+		if rule.DNSRewrite.NewCNAME != "" {
+			fl.rewriteCNAME(ctx, state, m, dns.Fqdn(rule.DNSRewrite.NewCNAME))
+			return m, nil
+		}
+	}
+	for _, rule := range rewrites {
+		if rule.DNSRewrite.RCode != dns.RcodeSuccess {
+			m.Rcode = rule.DNSRewrite.RCode
+			if m.Rcode == dns.RcodeNameError {
+				m.Ns = []dns.RR{fl.blockSOA(state)}
+			}
+			return m, nil
+		}
+	}
+	for _, rule := range rewrites {
+		if rule.DNSRewrite.RRType != state.QType() {
+			continue
+		}
+		hdr := dns.RR_Header{Name: state.QName(), Ttl: fl.BlockTTL, Class: dns.ClassINET, Rrtype: state.QType()}
+		rr, err := rewriteRR(hdr, rule.DNSRewrite)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite rule %q: %w", rule.Text(), err)
+		}
+		m.Answer = append(m.Answer, rr)
+	}
+	if len(m.Answer) == 0 {
+		m.Ns = []dns.RR{fl.blockSOA(state)}
+	}
+	return m, nil
+}
+
+// Answers with a CNAME record to target, followed by the answer to the
+// question for target from the plugin chain.
+func (fl FilterList) rewriteCNAME(ctx context.Context, state request.Request, m *dns.Msg, target string) {
+	hdr := dns.RR_Header{Name: state.QName(), Ttl: fl.BlockTTL, Class: dns.ClassINET, Rrtype: dns.TypeCNAME}
+	m.Answer = []dns.RR{&dns.CNAME{Hdr: hdr, Target: target}}
+	if state.QType() == dns.TypeCNAME {
+		return
+	}
+	depth, _ := ctx.Value(rewriteDepthKey{}).(int)
+	if maxRewriteDepth <= depth {
+		fl.Logger.Warn("CNAME rewrite chain too long", zap.String("name", state.Name()), zap.String("target", target))
+		return
+	}
+	ctx = context.WithValue(ctx, rewriteDepthKey{}, depth+1)
+	up, err := fl.Upstream.Lookup(ctx, state, target, state.QType())
+	if err != nil || up == nil {
+		fl.Logger.Warn("failed to resolve CNAME rewrite", zap.String("target", target), zap.Error(err))
+		return
+	}
+	m.Rcode = up.Rcode
+	m.Truncated = up.Truncated
+	m.Answer = append(m.Answer, up.Answer...)
+	m.Ns = up.Ns
+}
+
+// Converts the value of a record rewrite to a record, see rules.RRValue for
+// the value types.
+func rewriteRR(hdr dns.RR_Header, rewrite *rules.DNSRewrite) (dns.RR, error) {
+	switch value := rewrite.Value.(type) {
+	case netip.Addr:
+		if hdr.Rrtype == dns.TypeA && value.Is4() {
+			return &dns.A{Hdr: hdr, A: net.IP(value.AsSlice())}, nil
+		}
+		if hdr.Rrtype == dns.TypeAAAA {
+			ip := value.As16()
+			return &dns.AAAA{Hdr: hdr, AAAA: net.IP(ip[:])}, nil
+		}
+	case string:
+		switch hdr.Rrtype {
+		case dns.TypePTR:
+			return &dns.PTR{Hdr: hdr, Ptr: dns.Fqdn(value)}, nil
+		case dns.TypeTXT:
+			return &dns.TXT{Hdr: hdr, Txt: []string{value}}, nil
+		}
+	case *rules.DNSMX:
+		return &dns.MX{Hdr: hdr, Preference: value.Preference, Mx: dns.Fqdn(value.Exchange)}, nil
+	case *rules.DNSSRV:
+		return &dns.SRV{
+			Hdr:      hdr,
+			Priority: value.Priority,
+			Weight:   value.Weight,
+			Port:     value.Port,
+			Target:   dns.Fqdn(value.Target),
+		}, nil
+	case *rules.DNSSVCB:
+		// The parameters are parsed from presentation format, as their
+		// values are strings in rewrite rules.
+		params := []string{}
+		for key, v := range value.Params {
+			if v == "" {
+				params = append(params, key)
+			} else {
+				params = append(params, key+"="+v)
+			}
+		}
+		slices.Sort(params)
+		return dns.NewRR(fmt.Sprintf("%s %d IN %s %d %s %s",
+			hdr.Name, hdr.Ttl, dns.TypeToString[hdr.Rrtype], value.Priority, dns.Fqdn(value.Target), strings.Join(params, " ")))
+	}
+	return nil, fmt.Errorf("unsupported %s value %v", dns.TypeToString[hdr.Rrtype], rewrite.Value)
+}
@@ -0,0 +1,155 @@
+package filterlist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+func TestFilterlistRewrites(t *testing.T) {
+	group := &Group{
+		Name:       defaultGroupName,
+		Blocklists: []string{"https://example.com/list.txt"},
+		ClientTags: []string{"device_phone"},
+		Rules: []string{
+			"||a.example.com^$dnsrewrite=192.0.2.1",
+			"||a.example.com^$dnsrewrite=NOERROR;AAAA;2001:db8::1",
+			"||mx.example.com^$dnsrewrite=NOERROR;MX;10 mail.example.com",
+			"||txt.example.com^$dnsrewrite=NOERROR;TXT;hello",
+			"||srv.example.com^$dnsrewrite=NOERROR;SRV;10 20 443 target.example.com",
+			"||https.example.com^$dnsrewrite=NOERROR;HTTPS;1 . alpn=h2",
+			"||nx.example.com^$dnsrewrite=NXDOMAIN",
+			"||cname.example.com^$dnsrewrite=target.example.net",
+			"||type.example.com^$dnstype=AAAA,dnsrewrite=REFUSED",
+			"||client.example.com^$client=10.240.0.2,dnsrewrite=192.0.2.3",
+			"||ctag.example.com^$ctag=device_phone,dnsrewrite=192.0.2.4",
+			"||ctag.example.net^$ctag=device_pc,dnsrewrite=192.0.2.5",
+		},
+	}
+	b := newEngineBuilder()
+	remote := "||remote.example.com^$dnsrewrite=192.0.2.6\n||blocked.example.com^"
+	if err := b.setLists(group, []FetchedList{{URL: group.Blocklists[0], Content: remote}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	upstream := mockUpstream{answers: map[string]dns.RR{
+		"target.example.net.": test.A("target.example.net. 300 IN A 192.0.2.7"),
+	}}
+	fl := FilterList{
+		Groups:   []*Group{group},
+		BlockTTL: 300,
+		Upstream: &upstream,
+		Logger:   zap.NewNop(),
+	}
+	tests := []struct {
+		qname        string
+		qtype        uint16
+		ip           string
+		passed       bool
+		expectedCode int
+		answer       []string
+	}{
+		{qname: "a.example.com", qtype: dns.TypeA, answer: []string{"a.example.com.\t300\tIN\tA\t192.0.2.1"}},
+		{qname: "a.example.com", qtype: dns.TypeAAAA, answer: []string{"a.example.com.\t300\tIN\tAAAA\t2001:db8::1"}},
+		{qname: "a.example.com", qtype: dns.TypeMX, answer: []string{}},
+		{qname: "mx.example.com", qtype: dns.TypeMX, answer: []string{"mx.example.com.\t300\tIN\tMX\t10 mail.example.com."}},
+		{qname: "txt.example.com", qtype: dns.TypeTXT, answer: []string{"txt.example.com.\t300\tIN\tTXT\t\"hello\""}},
+		{qname: "srv.example.com", qtype: dns.TypeSRV, answer: []string{"srv.example.com.\t300\tIN\tSRV\t10 20 443 target.example.com."}},
+		{qname: "https.example.com", qtype: dns.TypeHTTPS, answer: []string{"https.example.com.\t300\tIN\tHTTPS\t1 . alpn=\"h2\""}},
+		{qname: "nx.example.com", qtype: dns.TypeA, expectedCode: dns.RcodeNameError, answer: []string{}},
+		{
+			qname:  "cname.example.com",
+			qtype:  dns.TypeA,
+			answer: []string{"cname.example.com.\t300\tIN\tCNAME\ttarget.example.net.", "target.example.net.\t300\tIN\tA\t192.0.2.7"},
+		},
+		{qname: "type.example.com", qtype: dns.TypeAAAA, expectedCode: dns.RcodeRefused, answer: []string{}},
+		{qname: "type.example.com", qtype: dns.TypeA, passed: true},
+		{qname: "client.example.com", qtype: dns.TypeA, ip: "10.240.0.2", answer: []string{"client.example.com.\t300\tIN\tA\t192.0.2.3"}},
+		{qname: "client.example.com", qtype: dns.TypeA, passed: true},
+		{qname: "ctag.example.com", qtype: dns.TypeA, answer: []string{"ctag.example.com.\t300\tIN\tA\t192.0.2.4"}},
+		{qname: "ctag.example.net", qtype: dns.TypeA, passed: true},
+		// Rewrites from fetched lists are ignored.
+		{qname: "remote.example.com", qtype: dns.TypeA, passed: true},
+		{qname: "blocked.example.com", qtype: dns.TypeA, answer: []string{"blocked.example.com.\t300\tIN\tA\t0.0.0.0"}},
+	}
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn(tc.qname), tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.ip})
+		code, err := fl.ServeDNS(context.TODO(), rec, req)
+		// Passed through requests fail without a next plugin.
+		if tc.passed {
+			if err == nil {
+				t.Errorf("Test %d: expected request to be passed through, got %v", i, rec.Msg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, but got %v", i, err)
+			continue
+		}
+		if code != tc.expectedCode {
+			t.Errorf("Test %d: expected status code %d, but got %d", i, tc.expectedCode, code)
+		}
+		answer := []string{}
+		for _, rr := range rec.Msg.Answer {
+			answer = append(answer, rr.String())
+		}
+		if len(answer) != len(tc.answer) {
+			t.Errorf("Test %d: expected answer %v, got %v", i, tc.answer, answer)
+			continue
+		}
+		for j := range answer {
+			if answer[j] != tc.answer[j] {
+				t.Errorf("Test %d: expected answer %v, got %v", i, tc.answer, answer)
+				break
+			}
+		}
+	}
+}
+
+func TestFilterlistRewriteLoop(t *testing.T) {
+	group := &Group{Name: defaultGroupName, Rules: []string{"||loop.example.com^$dnsrewrite=loop.example.com"}}
+	if err := newEngineBuilder().buildAll([]*Group{group}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fl := FilterList{Groups: []*Group{group}, Logger: zap.NewNop()}
+	// Resolves the CNAME target through the plugin again, like the plugin chain.
+	fl.Upstream = upstreamFunc(func(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		_, err := fl.ServeDNS(ctx, rec, state.NewWithQuestion(name, typ).Req)
+		return rec.Msg, err
+	})
+	req := new(dns.Msg)
+	req.SetQuestion("loop.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := fl.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(rec.Msg.Answer) != maxRewriteDepth+1 {
+		t.Errorf("expected %d answer records, got %d", maxRewriteDepth+1, len(rec.Msg.Answer))
+	}
+}
+
+type mockUpstream struct {
+	answers map[string]dns.RR
+}
+
+func (u *mockUpstream) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, typ)
+	if rr, ok := u.answers[name]; ok {
+		m.Answer = []dns.RR{rr}
+	}
+	return m, nil
+}
+
+type upstreamFunc func(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error)
+
+func (f upstreamFunc) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	return f(ctx, state, name, typ)
+}
@@ -0,0 +1,312 @@
+package filterlist
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+)
+
+// Builds the engines of the groups from the fetched lists, the local rules
+// of the group and the rules managed in the API server.
+//
+// All lists share one rule storage, so the urlfilter precedence applies
+// across them: exception rules, including allowlists, take precedence over
+// blocking rules, and $important rules take precedence over exceptions.
+type engineBuilder struct {
+	mu sync.Mutex
+	// Fetched lists of each group, indexed like the blocklists followed by
+	// the allowlists of the group.
+	lists    map[*Group][]fetchedList
+	apiRules []*resolver.FilterRule
+}
+
+// Last successfully fetched content of a list and its status.
+type fetchedList struct {
+	content string
+	status  ListStatus
+}
+
+// Status of fetching a list of a group.
+type ListStatus struct {
+	Group string `json:"group"`
+	URL   string `json:"url"`
+	// Zero while the list was never fetched.
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	// Count of rules and hosts lines of the last successfully fetched content.
+	Rules int `json:"rules"`
+	// Empty when the last fetch succeeded.
+	LastError string `json:"lastError,omitempty"`
+}
+
+func newEngineBuilder() *engineBuilder {
+	return &engineBuilder{lists: map[*Group][]fetchedList{}}
+}
+
+// Updates the fetched lists of the group and rebuilds its engine. Failed
+// lists keep their previously fetched content.
+func (b *engineBuilder) setLists(group *Group, lists []FetchedList) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.updateLists(group, lists)
+	return b.build(group)
+}
+
+// Updates the fetched lists of the group without rebuilding its engine, for
+// lists loaded before the engines are first built.
+func (b *engineBuilder) preloadLists(group *Group, lists []FetchedList) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.updateLists(group, lists)
+}
+
+// Must be called with mu held.
+func (b *engineBuilder) updateLists(group *Group, lists []FetchedList) {
+	current, ok := b.lists[group]
+	if !ok {
+		current = make([]fetchedList, len(lists))
+		b.lists[group] = current
+	}
+	for i, list := range lists {
+		current[i].status.Group = group.Name
+		current[i].status.URL = list.URL
+		if list.Err != nil {
+			current[i].status.LastError = list.Err.Error()
+			listLastFetchFailed.WithLabelValues(group.Name, list.URL).Set(1)
+			continue
+		}
+		listLastFetchFailed.WithLabelValues(group.Name, list.URL).Set(0)
+		current[i].content = list.Content
+		current[i].status.LastSuccess = list.Time
+		current[i].status.Rules = countRules(list.Content)
+		current[i].status.LastError = ""
+		if !list.Time.IsZero() {
+			listLastSuccess.WithLabelValues(group.Name, list.URL).Set(float64(list.Time.Unix()))
+		}
+	}
+}
+
+// Returns the status of the fetched lists of the groups.
+func (b *engineBuilder) listStatuses(groups []*Group) []ListStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	statuses := []ListStatus{}
+	for _, group := range groups {
+		for _, list := range b.lists[group] {
+			statuses = append(statuses, list.status)
+		}
+	}
+	return statuses
+}
+
+// Replaces the API rules and rebuilds the engines of the groups when they
+// changed.
+func (b *engineBuilder) setAPIRules(groups []*Group, apiRules []*resolver.FilterRule) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	equal := slices.EqualFunc(b.apiRules, apiRules, func(a, b *resolver.FilterRule) bool {
+		return a.Rule == b.Rule && a.Group == b.Group
+	})
+	if equal {
+		return false, nil
+	}
+	b.apiRules = apiRules
+	for _, group := range groups {
+		if err := b.build(group); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Rebuilds the engines of the groups, reading the rules files again.
+func (b *engineBuilder) buildAll(groups []*Group) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, group := range groups {
+		if err := b.build(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The IDs of the fetched lists are the blocklists of the group, the
+// blocklists of its schedules and the allowlists. They are followed by the
+// IDs of the local and API rules, the services, the safe search rules, and
+// the rules and services of each schedule.
+// Must be called with mu held.
+func (b *engineBuilder) build(group *Group) error {
+	start := time.Now()
+	contents := make([]string, group.fetchedCount())
+	for i, list := range b.lists[group] {
+		contents[i] = list.content
+	}
+	// The lists of inactive schedules are empty.
+	i := len(group.Blocklists)
+	for _, schedule := range group.Schedules {
+		if !schedule.active {
+			clear(contents[i : i+len(schedule.Blocklists)])
+		}
+		i += len(schedule.Blocklists)
+	}
+	localRules, err := readLocalRules(group.Name, group.Rules, group.RulesFiles)
+	if err != nil {
+		return err
+	}
+	apiRules := []string{}
+	for _, rule := range b.apiRules {
+		if rule.Group == "" || rule.Group == group.Name {
+			apiRules = append(apiRules, rule.Rule)
+		}
+	}
+	contents = append(contents, localRules, strings.Join(apiRules, "\n"))
+	for _, service := range group.Services {
+		contents = append(contents, serviceRules(service))
+	}
+	contents = append(contents, safeSearchRules(group.SafeSearch))
+	for _, schedule := range group.Schedules {
+		// Rules files are read while inactive, so that missing files are
+		// reported.
+		scheduleRules, err := readLocalRules(group.Name, schedule.Rules, schedule.RulesFiles)
+		if err != nil {
+			return err
+		}
+		services := make([]string, len(schedule.Services))
+		if schedule.active {
+			for i, service := range schedule.Services {
+				services[i] = serviceRules(service)
+			}
+		} else {
+			scheduleRules = ""
+		}
+		contents = append(contents, scheduleRules)
+		contents = append(contents, services...)
+	}
+	// Audited lists are left out of the engine, and matched with the audit
+	// engine of all lists when the engine does not block.
+	enforced := slices.Clone(contents)
+	audited := false
+	for i, content := range contents {
+		list := group.listName(uint64(i))
+		listRules.WithLabelValues(group.Name, list).Set(float64(countRules(content)))
+		if slices.Contains(group.Audit, list) {
+			enforced[i] = ""
+			audited = true
+		}
+	}
+	if !audited && !slices.ContainsFunc(contents, func(content string) bool { return content != "" }) {
+		group.Engine.Store(nil)
+		group.auditEngine.Store(nil)
+		return nil
+	}
+	engine, err := CreateEngine(enforced)
+	if err != nil {
+		return err
+	}
+	var auditEngine *urlfilter.DNSEngine
+	if audited {
+		auditEngine, err = CreateEngine(contents)
+		if err != nil {
+			return err
+		}
+	}
+	group.Engine.Store(engine)
+	group.auditEngine.Store(auditEngine)
+	engineBuildDuration.WithLabelValues(group.Name).Set(time.Since(start).Seconds())
+	return nil
+}
+
+// Only the local, safe search, and schedule rules are trusted to rewrite
+// responses. Rewrites from the fetched lists and API rules are ignored, as
+// they may lead to DNS hijack.
+func (g *Group) trusted(id rules.ListID) bool {
+	fetched := g.fetchedCount()
+	safeSearch := fetched + 2 + len(g.Services)
+	switch {
+	case int(id) == fetched || int(id) == safeSearch:
+		return true
+	case int(id) < safeSearch:
+		return false
+	}
+	scheduleID := safeSearch + 1
+	for _, schedule := range g.Schedules {
+		if int(id) == scheduleID {
+			return true
+		}
+		scheduleID += 1 + len(schedule.Services)
+	}
+	return false
+}
+
+// Names lists by their URL in metrics, the local rules are named "local", the
+// API rules "api", the services "service:NAME", the safe search rules
+// "safesearch" and the rules of schedules "schedule:NAME".
+func (g *Group) listName(id uint64) string {
+	fetched := uint64(g.fetchedCount())
+	safeSearch := fetched + 2 + uint64(len(g.Services))
+	switch {
+	case id < uint64(len(g.Blocklists)):
+		return g.Blocklists[id]
+	case id < fetched-uint64(len(g.Allowlists)):
+		id -= uint64(len(g.Blocklists))
+		for _, schedule := range g.Schedules {
+			if id < uint64(len(schedule.Blocklists)) {
+				return schedule.Blocklists[id]
+			}
+			id -= uint64(len(schedule.Blocklists))
+		}
+	case id < fetched:
+		return g.Allowlists[id-(fetched-uint64(len(g.Allowlists)))]
+	case id == fetched:
+		return "local"
+	case id == fetched+1:
+		return "api"
+	case id < safeSearch:
+		return "service:" + g.Services[id-fetched-2]
+	case id == safeSearch:
+		return "safesearch"
+	default:
+		id -= safeSearch + 1
+		for _, schedule := range g.Schedules {
+			if id == 0 {
+				return "schedule:" + schedule.Name
+			}
+			if id <= uint64(len(schedule.Services)) {
+				return "service:" + schedule.Services[id-1]
+			}
+			id -= 1 + uint64(len(schedule.Services))
+		}
+	}
+	return ""
+}
+
+// Returns the inline rules followed by the contents of the rules files.
+func readLocalRules(group string, inline []string, files []string) (string, error) {
+	contents := slices.Clone(inline)
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read rules file of group %s: %w", group, err)
+		}
+		contents = append(contents, string(content))
+	}
+	return strings.Join(contents, "\n"), nil
+}
+
+// Fetches the rules managed in the API server and rebuilds the engines when
+// they changed.
+func refreshAPIRules(ctx context.Context, client resolver.FilterClient, builder *engineBuilder, groups []*Group) (bool, error) {
+	res, err := client.ListFilterRules(ctx, &resolver.ListFilterRulesRequest{})
+	if err != nil {
+		return false, err
+	}
+	return builder.setAPIRules(groups, res.Rules)
+}
@@ -0,0 +1,196 @@
+package filterlist
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/urlfilter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"google.golang.org/grpc"
+)
+
+func TestEngineBuilder(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(rulesFile, []byte("! local rules\n||file.example.com^\n"), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	kids := &Group{
+		Name:       "kids",
+		Blocklists: []string{"https://example.com/blocklist"},
+		Allowlists: []string{"https://example.com/allowlist"},
+		Rules:      []string{"@@||allowed.example.com^", "0.0.0.0 hosts.example.com"},
+		RulesFiles: []string{rulesFile},
+	}
+	other := &Group{Name: "other"}
+	groups := []*Group{kids, other}
+	b := newEngineBuilder()
+	if err := b.buildAll(groups); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if other.Engine.Load() != nil {
+		t.Errorf("expected groups without lists or rules to have no engine")
+	}
+	assertBlocked(t, kids, map[string]bool{
+		"file.example.com":  true,
+		"hosts.example.com": true,
+		"example.com":       false,
+	})
+
+	err := b.setLists(kids, []FetchedList{
+		{URL: kids.Blocklists[0], Content: "||example.com^\n||important.example.net^$important"},
+		{URL: kids.Allowlists[0], Content: AllowlistRules("example.org\nimportant.example.net")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertBlocked(t, kids, map[string]bool{
+		"example.com":           true,
+		"allowed.example.com":   false,
+		"file.example.com":      true,
+		"important.example.net": true,
+	})
+
+	client := mockFilterClient{rules: []*resolver.FilterRule{
+		{Rule: "@@||example.com^$important"},
+		{Rule: "||other.example.net^", Group: "other"},
+	}}
+	changed, err := refreshAPIRules(context.Background(), &client, b, groups)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !changed {
+		t.Errorf("expected API rules to change")
+	}
+	assertBlocked(t, kids, map[string]bool{
+		"example.com":       false,
+		"other.example.net": false,
+		"file.example.com":  false,
+		"hosts.example.com": false,
+		"ads.example.org":   false,
+		"example.org":       false,
+	})
+	assertBlocked(t, other, map[string]bool{
+		"other.example.net": true,
+	})
+	changed, err = refreshAPIRules(context.Background(), &client, b, groups)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if changed {
+		t.Errorf("expected API rules to be unchanged")
+	}
+}
+
+func TestEngineBuilderAPIRuleGroups(t *testing.T) {
+	kids := &Group{Name: "kids"}
+	defaultGroup := &Group{Name: defaultGroupName}
+	groups := []*Group{kids, defaultGroup}
+	b := newEngineBuilder()
+	_, err := b.setAPIRules(groups, []*resolver.FilterRule{
+		{Rule: "||all.example.com^"},
+		{Rule: "||kids.example.com^", Group: "kids"},
+		{Rule: "||default.example.com^", Group: defaultGroupName},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Clients not matching a group only get the ungrouped API rules and the
+	// rules of the default group.
+	assertBlocked(t, defaultGroup, map[string]bool{
+		"all.example.com":     true,
+		"kids.example.com":    false,
+		"default.example.com": true,
+	})
+	assertBlocked(t, kids, map[string]bool{
+		"all.example.com":     true,
+		"kids.example.com":    true,
+		"default.example.com": false,
+	})
+}
+
+func TestEngineBuilderMissingRulesFile(t *testing.T) {
+	group := &Group{
+		Name:       defaultGroupName,
+		RulesFiles: []string{filepath.Join(t.TempDir(), "missing.txt")},
+	}
+	if err := newEngineBuilder().buildAll([]*Group{group}); err == nil {
+		t.Errorf("expected an error, got no errors")
+	}
+}
+
+func assertBlocked(t *testing.T, group *Group, hostnames map[string]bool) {
+	t.Helper()
+	for hostname, expected := range hostnames {
+		blocked := false
+		if engine := group.Engine.Load(); engine != nil {
+			result, ok := engine.MatchRequest(&urlfilter.DNSRequest{Hostname: hostname})
+			if ok {
+				_, blocked = getMatchingListID(result)
+			}
+		}
+		if blocked != expected {
+			t.Errorf("group %s: expected %s blocked to be %t, got %t", group.Name, hostname, expected, blocked)
+		}
+	}
+}
+
+type mockFilterClient struct {
+	rules  []*resolver.FilterRule
+	pauses []*resolver.FilterPause
+}
+
+func (c *mockFilterClient) ListFilterRules(ctx context.Context, in *resolver.ListFilterRulesRequest, opts ...grpc.CallOption) (*resolver.ListFilterRulesResponse, error) {
+	return &resolver.ListFilterRulesResponse{Rules: c.rules}, nil
+}
+
+func (c *mockFilterClient) ListFilterPauses(ctx context.Context, in *resolver.ListFilterPausesRequest, opts ...grpc.CallOption) (*resolver.ListFilterPausesResponse, error) {
+	return &resolver.ListFilterPausesResponse{Pauses: c.pauses}, nil
+}
+
+func TestEngineBuilderFailedLists(t *testing.T) {
+	group := &Group{
+		Name:       defaultGroupName,
+		Blocklists: []string{"https://example.com/first", "https://example.com/second"},
+	}
+	b := newEngineBuilder()
+	fetched := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := b.setLists(group, []FetchedList{
+		{URL: group.Blocklists[0], Content: "||first.example.com^", Time: fetched},
+		{URL: group.Blocklists[1], Content: "! comment\n||second.example.com^\n0.0.0.0 hosts.example.com", Time: fetched},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = b.setLists(group, []FetchedList{
+		{URL: group.Blocklists[0], Content: "||updated.example.com^", Time: fetched.Add(time.Hour)},
+		{URL: group.Blocklists[1], Err: errors.New("status 500"), Time: fetched.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The failed list keeps its previous content.
+	assertBlocked(t, group, map[string]bool{
+		"first.example.com":   false,
+		"updated.example.com": true,
+		"second.example.com":  true,
+	})
+	expected := []ListStatus{
+		{Group: defaultGroupName, URL: group.Blocklists[0], LastSuccess: fetched.Add(time.Hour), Rules: 1},
+		{Group: defaultGroupName, URL: group.Blocklists[1], LastSuccess: fetched, Rules: 2, LastError: "status 500"},
+	}
+	statuses := FilterList{Groups: []*Group{group}, builder: b}.ListStatuses()
+	if !slices.Equal(statuses, expected) {
+		t.Errorf("expected statuses %v, got %v", expected, statuses)
+	}
+	for i, failed := range []float64{0, 1} {
+		if v := testutil.ToFloat64(listLastFetchFailed.WithLabelValues(defaultGroupName, group.Blocklists[i])); v != failed {
+			t.Errorf("expected %s last fetch failed to be %v, got %v", group.Blocklists[i], failed, v)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package filterlist
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Search engine answered with CNAMEs to its safe endpoint.
+type safeSearchEngine struct {
+	// Safe endpoint enforcing safe search or restricted mode.
+	target string
+	// Exact hostnames rewritten to the target.
+	hosts []string
+}
+
+// Country domains of Google search, from https://www.google.com/supported_domains.
+var googleDomains = []string{
+	"com", "ad", "ae", "com.af", "com.ag", "al", "am", "co.ao", "com.ar", "as", "at", "com.au", "az",
+	"ba", "com.bd", "be", "bf", "bg", "com.bh", "bi", "bj", "com.bn", "com.bo", "com.br", "bs", "bt",
+	"co.bw", "by", "com.bz", "ca", "cat", "cd", "cf", "cg", "ch", "ci", "co.ck", "cl", "cm", "cn",
+	"com.co", "co.cr", "com.cu", "cv", "com.cy", "cz", "de", "dj", "dk", "dm", "com.do", "dz",
+	"com.ec", "ee", "com.eg", "es", "com.et", "fi", "com.fj", "fm", "fr", "ga", "ge", "gg", "com.gh",
+	"com.gi", "gl", "gm", "gr", "com.gt", "gy", "com.hk", "hn", "hr", "ht", "hu", "co.id", "ie",
+	"co.il", "im", "co.in", "iq", "is", "it", "je", "com.jm", "jo", "co.jp", "co.ke", "com.kh", "ki",
+	"kg", "co.kr", "com.kw", "kz", "la", "com.lb", "li", "lk", "co.ls", "lt", "lu", "lv", "com.ly",
+	"co.ma", "md", "me", "mg", "mk", "ml", "com.mm", "mn", "com.mt", "mu", "mv", "mw", "com.mx",
+	"com.my", "co.mz", "com.na", "com.ng", "com.ni", "ne", "nl", "no", "com.np", "nr", "nu", "co.nz",
+	"com.om", "com.pa", "com.pe", "com.pg", "com.ph", "com.pk", "pl", "pn", "com.pr", "ps", "pt",
+	"com.py", "com.qa", "ro", "rs", "ru", "rw", "com.sa", "com.sb", "sc", "se", "com.sg", "sh", "si",
+	"sk", "com.sl", "sn", "so", "sm", "sr", "st", "com.sv", "td", "tg", "co.th", "com.tj", "tl", "tm",
+	"tn", "to", "com.tr", "tt", "com.tw", "co.tz", "com.ua", "co.ug", "co.uk", "com.uy", "co.uz",
+	"com.vc", "co.ve", "co.vi", "com.vn", "vu", "ws", "co.za", "co.zm", "co.zw",
+}
+
+var safeSearchEngines = map[string]safeSearchEngine{
+	"bing": {
+		target: "strict.bing.com",
+		hosts:  []string{"bing.com", "www.bing.com"},
+	},
+	"duckduckgo": {
+		target: "safe.duckduckgo.com",
+		hosts:  []string{"duckduckgo.com", "www.duckduckgo.com", "start.duckduckgo.com"},
+	},
+	"google": {
+		target: "forcesafesearch.google.com",
+		hosts:  googleHosts(),
+	},
+	"youtube": {
+		target: "restrict.youtube.com",
+		hosts: []string{
+			"www.youtube.com",
+			"m.youtube.com",
+			"youtubei.googleapis.com",
+			"youtube.googleapis.com",
+			"www.youtube-nocookie.com",
+		},
+	},
+}
+
+func googleHosts() []string {
+	hosts := []string{}
+	for _, domain := range googleDomains {
+		hosts = append(hosts, "google."+domain, "www.google."+domain)
+	}
+	return hosts
+}
+
+// Returns the names of all safe search engines, sorted.
+func safeSearchEngineNames() []string {
+	return slices.Sorted(maps.Keys(safeSearchEngines))
+}
+
+// Returns the CNAME rewrite rules of the safe search engines.
+func safeSearchRules(engines []string) string {
+	var b strings.Builder
+	for _, name := range engines {
+		engine := safeSearchEngines[name]
+		for _, host := range engine.hosts {
+			b.WriteString("|" + host + "^$dnsrewrite=NOERROR;CNAME;" + engine.target + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package filterlist
+
+import (
+	"time"
+)
+
+// Lists and rules of a group applied only during time ranges starting on the
+// days of the schedule.
+type Schedule struct {
+	Name string
+	// Indexed by time.Weekday.
+	Days   [7]bool
+	Ranges []TimeRange
+	// Timezone of the days and time ranges.
+	Location   *time.Location
+	Blocklists []string
+	// Inline adblock style rules and hosts lines.
+	Rules []string
+	// Paths of local files of adblock style rules and hosts lines.
+	RulesFiles []string
+	// Sorted names of the catalog services to block.
+	Services []string
+	// Whether the lists and rules are applied. Guarded by the mutex of the
+	// engine builder.
+	active bool
+}
+
+// Time range in minutes since midnight. Ranges ending before or when they
+// start continue past midnight into the next day.
+type TimeRange struct {
+	Start int
+	End   int
+}
+
+// Every day of the week.
+var allDays = [7]bool{true, true, true, true, true, true, true}
+
+// The whole day, from midnight to midnight.
+var allDay = TimeRange{Start: 0, End: 0}
+
+func (s *Schedule) activeAt(t time.Time) bool {
+	t = t.In(s.Location)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+	for _, r := range s.Ranges {
+		if r.Start < r.End {
+			if s.Days[today] && r.Start <= minute && minute < r.End {
+				return true
+			}
+			continue
+		}
+		if s.Days[today] && r.Start <= minute {
+			return true
+		}
+		if s.Days[yesterday] && minute < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Updates which schedules of the group are active at now, and reports whether
+// any changed. Must be called with the mutex of the engine builder held, or
+// before the engines are first built.
+func (g *Group) updateSchedules(now time.Time) bool {
+	changed := false
+	for _, schedule := range g.Schedules {
+		active := schedule.activeAt(now)
+		if active {
+			scheduleActive.WithLabelValues(g.Name, schedule.Name).Set(1)
+		} else {
+			scheduleActive.WithLabelValues(g.Name, schedule.Name).Set(0)
+		}
+		if active != schedule.active {
+			schedule.active = active
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Updates which schedules are active at now, and rebuilds the engines of the
+// groups with changed schedules.
+func (b *engineBuilder) updateSchedules(groups []*Group, now time.Time) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	changed := false
+	for _, group := range groups {
+		if !group.updateSchedules(now) {
+			continue
+		}
+		changed = true
+		if err := b.build(group); err != nil {
+			return true, err
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,131 @@
+package filterlist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleActiveAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// School nights, from Sunday to Thursday evening until the next morning.
+	schoolNights := &Schedule{
+		Days:     [7]bool{true, true, true, true, true, false, false},
+		Ranges:   []TimeRange{{Start: 20 * 60, End: 7 * 60}},
+		Location: berlin,
+	}
+	weekends := &Schedule{
+		Days:     [7]bool{true, false, false, false, false, false, true},
+		Ranges:   []TimeRange{allDay},
+		Location: time.UTC,
+	}
+	afternoons := &Schedule{
+		Days:     allDays,
+		Ranges:   []TimeRange{{Start: 12 * 60, End: 14 * 60}, {Start: 16 * 60, End: 24 * 60}},
+		Location: time.UTC,
+	}
+	tests := []struct {
+		schedule *Schedule
+		at       time.Time
+		expected bool
+	}{
+		// 2026-10-18 is a Sunday.
+		{schedule: schoolNights, at: time.Date(2026, 10, 18, 19, 59, 0, 0, berlin), expected: false},
+		{schedule: schoolNights, at: time.Date(2026, 10, 18, 20, 0, 0, 0, berlin), expected: true},
+		{schedule: schoolNights, at: time.Date(2026, 10, 19, 6, 59, 0, 0, berlin), expected: true},
+		{schedule: schoolNights, at: time.Date(2026, 10, 19, 7, 0, 0, 0, berlin), expected: false},
+		{schedule: schoolNights, at: time.Date(2026, 10, 18, 18, 30, 0, 0, time.UTC), expected: true},
+		// Friday night is not a school night, the Thursday night continues
+		// into Friday morning.
+		{schedule: schoolNights, at: time.Date(2026, 10, 23, 21, 0, 0, 0, berlin), expected: false},
+		{schedule: schoolNights, at: time.Date(2026, 10, 23, 6, 0, 0, 0, berlin), expected: true},
+		{schedule: schoolNights, at: time.Date(2026, 10, 24, 6, 0, 0, 0, berlin), expected: false},
+		{schedule: weekends, at: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), expected: true},
+		{schedule: weekends, at: time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), expected: true},
+		{schedule: weekends, at: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), expected: false},
+		{schedule: afternoons, at: time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC), expected: true},
+		{schedule: afternoons, at: time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC), expected: false},
+		{schedule: afternoons, at: time.Date(2026, 10, 19, 23, 59, 0, 0, time.UTC), expected: true},
+		{schedule: afternoons, at: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), expected: false},
+	}
+	for i, tc := range tests {
+		if active := tc.schedule.activeAt(tc.at); active != tc.expected {
+			t.Errorf("Test %d: expected active at %s to be %t, got %t", i, tc.at, tc.expected, active)
+		}
+	}
+}
+
+func TestEngineBuilderSchedules(t *testing.T) {
+	night := &Schedule{
+		Name:       "night",
+		Days:       allDays,
+		Ranges:     []TimeRange{{Start: 20 * 60, End: 7 * 60}},
+		Location:   time.UTC,
+		Blocklists: []string{"https://example.com/social.txt"},
+		Rules:      []string{"||games.example.com^"},
+		Services:   []string{"tiktok"},
+	}
+	kids := &Group{
+		Name:       "kids",
+		Blocklists: []string{"https://example.com/ads.txt"},
+		Allowlists: []string{"https://example.com/allow.txt"},
+		Schedules:  []*Schedule{night},
+	}
+	day := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	b := newEngineBuilder()
+	kids.updateSchedules(day)
+	b.preloadLists(kids, []FetchedList{
+		{URL: "https://example.com/ads.txt", Content: "||ads.example.com^"},
+		{URL: "https://example.com/social.txt", Content: "||social.example.com^"},
+		{URL: "https://example.com/allow.txt", Content: AllowlistRules("www.tiktok.com")},
+	})
+	if err := b.buildAll([]*Group{kids}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertBlocked(t, kids, map[string]bool{
+		"ads.example.com":    true,
+		"social.example.com": false,
+		"games.example.com":  false,
+		"tiktok.com":         false,
+	})
+
+	changed, err := b.updateSchedules([]*Group{kids}, day.Add(9*time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !changed {
+		t.Errorf("expected schedules to change")
+	}
+	assertBlocked(t, kids, map[string]bool{
+		"ads.example.com":    true,
+		"social.example.com": true,
+		"games.example.com":  true,
+		"tiktok.com":         true,
+		"www.tiktok.com":     false,
+	})
+	changed, err = b.updateSchedules([]*Group{kids}, day.Add(10*time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if changed {
+		t.Errorf("expected schedules to be unchanged")
+	}
+
+	expected := []string{
+		"https://example.com/ads.txt",
+		"https://example.com/social.txt",
+		"https://example.com/allow.txt",
+		"local",
+		"api",
+		"safesearch",
+		"schedule:night",
+		"service:tiktok",
+	}
+	for i, name := range expected {
+		if listName := kids.listName(uint64(i)); listName != name {
+			t.Errorf("expected list %d to be named %q, got %q", i, name, listName)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package filterlist
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Built-in catalog of the domains of services, blocked by name with
+// block_services. Subdomains of the domains are blocked as well.
+var serviceDomains = map[string][]string{
+	"discord":   {"discord.com", "discord.gg", "discord.media", "discordapp.com", "discordapp.net", "discordcdn.com"},
+	"epicgames": {"epicgames.com", "epicgames.dev", "fortnite.com", "unrealengine.com"},
+	"facebook":  {"facebook.com", "facebook.net", "fb.com", "fb.me", "fbcdn.net", "fbsbx.com", "messenger.com"},
+	"instagram": {"cdninstagram.com", "ig.me", "instagr.am", "instagram.com"},
+	"minecraft": {"minecraft.net", "minecraftservices.com", "mojang.com"},
+	"netflix":   {"netflix.com", "netflix.net", "nflxext.com", "nflximg.com", "nflximg.net", "nflxso.net", "nflxvideo.net"},
+	"pinterest": {"pin.it", "pinimg.com", "pinterest.com"},
+	"reddit":    {"redd.it", "reddit.com", "redditmedia.com", "redditstatic.com", "reddituploads.com"},
+	"roblox":    {"rbx.com", "rbxcdn.com", "roblox.com", "robloxlabs.com"},
+	"snapchat":  {"sc-cdn.net", "snap.com", "snapads.com", "snapchat.com", "snapkit.com"},
+	"steam":     {"steamcommunity.com", "steamcontent.com", "steampowered.com", "steamserver.net", "steamstatic.com", "steamusercontent.com"},
+	"telegram":  {"t.me", "telegra.ph", "telegram.me", "telegram.org", "telesco.pe"},
+	"tiktok":    {"byteoversea.com", "ibytedtos.com", "ibyteimg.com", "muscdn.com", "musical.ly", "tiktok.com", "tiktokcdn.com", "tiktokcdn-us.com", "tiktokv.com", "ttwstatic.com"},
+	"twitch":    {"ext-twitch.tv", "jtvnw.net", "ttvnw.net", "twitch.tv", "twitchcdn.net", "twitchsvc.net"},
+	"twitter":   {"t.co", "twimg.com", "twitter.com", "x.com"},
+	"whatsapp":  {"wa.me", "whatsapp.com", "whatsapp.net"},
+	"youtube":   {"googlevideo.com", "youtu.be", "youtube-nocookie.com", "youtube.com", "youtubei.googleapis.com", "youtubekids.com", "ytimg.com"},
+}
+
+// Returns the names of the services in the catalog, sorted.
+func serviceNames() []string {
+	return slices.Sorted(maps.Keys(serviceDomains))
+}
+
+// Returns the blocking rules of the domains of the service.
+func serviceRules(service string) string {
+	var b strings.Builder
+	for _, domain := range serviceDomains[service] {
+		b.WriteString("||" + domain + "^\n")
+	}
+	return b.String()
+}
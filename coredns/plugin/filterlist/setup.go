@@ -1,40 +1,187 @@
 package filterlist
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sneakybugs/corewarden/coredns/plugin/slog"
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/go-co-op/gocron"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func init() {
 	plugin.Register("filterlist", setup)
 }
 
+// Interval of fetching the rules managed in the API server.
+const apiRulesRefreshInterval = time.Minute
+
 func setup(c *caddy.Controller) error {
 	// See example config parsing
 	// https://github.com/coredns/coredns/blob/master/plugin/transfer/setup.go#L48-L81
-	listURLs := []string{}
+	defaultGroup := &Group{Name: defaultGroupName}
+	groups := []*Group{}
+	clientIDOption := uint16(defaultClientIDOption)
+	apiTarget := ""
+	cacheDir := ""
+	topBlocked := 0
+	checkResponses := false
+	checkListen := ""
+	fetch := defaultFetchConfig()
+	blockMode := BlockModeNullIP
+	blockTTL := uint32(defaultBlockTTL)
+	var blockIPs []net.IP
+	// Timezone of schedules without their own timezone.
+	location := time.Local
 	for c.Next() {
 		for c.NextBlock() {
 			switch c.Val() {
-			case "blocklists":
+			case "blocklists", "allowlists", "rule", "rules_file":
+				if err := parseRules(c, defaultGroup); err != nil {
+					return err
+				}
+			case "block_services", "safesearch":
+				if err := parseCatalog(c, defaultGroup); err != nil {
+					return err
+				}
+			case "schedule":
+				if err := parseSchedule(c, defaultGroup); err != nil {
+					return err
+				}
+			case "audit":
 				args := c.RemainingArgs()
 				if len(args) == 0 {
 					return c.ArgErr()
 				}
-				listURLs = append(listURLs, args...)
+				defaultGroup.Audit = append(defaultGroup.Audit, args...)
+			case "timezone":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				var err error
+				location, err = time.LoadLocation(args[0])
+				if err != nil {
+					return plugin.Error("filterlist", c.Errf("invalid timezone %q: %v", args[0], err))
+				}
+			case "client_id_option":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				code, err := strconv.ParseUint(args[0], 10, 16)
+				if err != nil {
+					return plugin.Error("filterlist", c.Errf("invalid client_id_option %q: %v", args[0], err))
+				}
+				clientIDOption = uint16(code)
+			case "block_mode":
+				var err error
+				blockMode, blockIPs, err = parseBlockMode(c)
+				if err != nil {
+					return err
+				}
+			case "block_ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				seconds, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return plugin.Error("filterlist", c.Errf("invalid block_ttl %q: %v", args[0], err))
+				}
+				blockTTL = uint32(seconds)
+			case "refresh", "retry", "backoff", "fetch_timeout", "max_list_size", "ca_bundle":
+				if err := parseFetchConfig(c, &fetch); err != nil {
+					return err
+				}
+			case "top_blocked_domains":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return plugin.Error("filterlist", c.Errf("invalid top_blocked_domains %q", args[0]))
+				}
+				topBlocked = n
+			case "response_check":
+				if len(c.RemainingArgs()) != 0 {
+					return c.ArgErr()
+				}
+				checkResponses = true
+			case "check_listen":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				checkListen = args[0]
+			case "cache_dir":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				cacheDir = args[0]
+			case "api":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				apiTarget = args[0]
+			case "group":
+				group, err := parseGroup(c)
+				if err != nil {
+					return err
+				}
+				for _, g := range groups {
+					if g.Name == group.Name {
+						return plugin.Error("filterlist", c.Errf("duplicate group %q", group.Name))
+					}
+				}
+				groups = append(groups, group)
 			default:
 				return plugin.Error("filterlist", c.Errf("unknown property %q", c.Val()))
 			}
 		}
 	}
-	if len(listURLs) == 0 {
-		return plugin.Error("filterlist", c.Errf("blocklists property is required"))
+	if err := validateAudit(c, defaultGroup); err != nil {
+		return err
+	}
+	// Clients not matching other groups use the top level lists and rules,
+	// and the ungrouped API rules.
+	if hasRules(defaultGroup) || apiTarget != "" {
+		groups = append(groups, defaultGroup)
+	}
+	if len(groups) == 0 {
+		return plugin.Error("filterlist", c.Errf("blocklists property or a group is required"))
+	}
+	hasSchedules := false
+	for _, group := range groups {
+		for _, schedule := range group.Schedules {
+			if schedule.Location == nil {
+				schedule.Location = location
+			}
+			hasSchedules = true
+		}
+		group.updateSchedules(time.Now())
 	}
 
 	logger, ok := slog.LoggerFromController(c)
@@ -43,27 +190,172 @@ func setup(c *caddy.Controller) error {
 		logger = zap.NewNop()
 	}
 
-	filterlistPlugin := &FilterList{Logger: logger}
+	// Local rules and the cached lists are applied before the lists are
+	// fetched, so that filtering starts without network access.
+	cache := newListCache(cacheDir)
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return plugin.Error("filterlist", err)
+		}
+	}
+	builder := newEngineBuilder()
+	for _, group := range groups {
+		lists, err := cache.contents(group.blocklistURLs(), group.Allowlists)
+		if err != nil {
+			// Unreadable lists are replaced by the first fetch.
+			logger.Warn("failed to load cached lists",
+				zap.String("group", group.Name),
+				zap.Error(err),
+			)
+		}
+		builder.preloadLists(group, lists)
+	}
+	if err := builder.buildAll(groups); err != nil {
+		return plugin.Error("filterlist", err)
+	}
+
+	filterlistPlugin := &FilterList{
+		Groups:         groups,
+		ClientIDOption: clientIDOption,
+		BlockMode:      blockMode,
+		BlockTTL:       blockTTL,
+		BlockIPs:       blockIPs,
+		Upstream:       upstream.New(),
+		CheckResponses: checkResponses,
+		Logger:         logger,
+		builder:        builder,
+	}
+	if topBlocked != 0 {
+		filterlistPlugin.topBlocked = newTopDomains(topBlocked)
+	}
+	if apiTarget != "" {
+		filterlistPlugin.pauses = &pauses{}
+	}
+	if checkListen != "" {
+		filterlistPlugin.overrides = &overrides{}
+	}
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		filterlistPlugin.Next = next
 		return filterlistPlugin
 	})
 
+	fetchOptions, err := fetch.options()
+	if err != nil {
+		return plugin.Error("filterlist", err)
+	}
 	cron := gocron.NewScheduler(time.UTC)
-	_, err := cron.Every(6).Hours().Do(func() {
-		blocklistFetchStart := time.Now()
-		engine, err := CreateEngineFromRemote(listURLs, 5, time.Minute*5, 15)
-		if err != nil {
-			logger.Error("failed to fetch blocklists after retrying 15 times",
-				zap.Error(err),
+	// Slow fetches are not overlapped by the next run.
+	cron.SingletonModeAll()
+	var fetched atomic.Bool
+	_, err = cron.Every(fetch.interval).Do(func() {
+		// Refreshes are delayed to spread the load on the list servers, the
+		// lists are fetched without delay at startup.
+		if fetched.Swap(true) && fetch.jitter != 0 {
+			time.Sleep(rand.N(fetch.jitter))
+		}
+		for _, group := range groups {
+			blocklists := group.blocklistURLs()
+			if len(blocklists) == 0 && len(group.Allowlists) == 0 {
+				continue
+			}
+			blocklistFetchStart := time.Now()
+			lists := FetchLists(cache, blocklists, group.Allowlists, fetchOptions)
+			for _, list := range lists {
+				if list.CacheErr != nil {
+					logger.Error("failed to cache list",
+						zap.String("group", group.Name),
+						zap.String("url", list.URL),
+						zap.Error(list.CacheErr),
+					)
+				}
+			}
+			err := builder.setLists(group, lists)
+			for _, status := range builder.listStatuses([]*Group{group}) {
+				if status.LastError != "" {
+					// The previously fetched content of the list is kept.
+					logger.Error("failed to fetch list after retrying",
+						zap.String("group", group.Name),
+						zap.String("url", status.URL),
+						zap.String("error", status.LastError),
+						zap.Time("last_success", status.LastSuccess),
+						zap.Int("rules", status.Rules),
+					)
+				}
+			}
+			if err != nil {
+				logger.Error("failed to build filter engine",
+					zap.String("group", group.Name),
+					zap.Error(err),
+				)
+				continue
+			}
+			logger.Info("blocklists fetched",
+				zap.String("group", group.Name),
+				zap.Duration("duration", time.Since(blocklistFetchStart)),
 			)
-			return
 		}
-		filterlistPlugin.Engine = engine
-		logger.Info("blocklists fetched",
-			zap.Duration("duration", time.Since(blocklistFetchStart)),
-		)
 	})
+	if err != nil {
+		return err
+	}
+
+	if hasSchedules {
+		// Schedules are updated at the start of every minute.
+		_, err = cron.Cron("* * * * *").Do(func() {
+			changed, err := builder.updateSchedules(groups, time.Now())
+			if err != nil {
+				logger.Error("failed to build filter engine for schedules", zap.Error(err))
+				return
+			}
+			if changed {
+				logger.Info("active schedules changed")
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if filterlistPlugin.topBlocked != nil {
+		_, err = cron.Every(topBlockedInterval).Do(filterlistPlugin.topBlocked.updateGauge)
+		if err != nil {
+			return err
+		}
+	}
+
+	var conn *grpc.ClientConn
+	if apiTarget != "" {
+		conn, err = grpc.NewClient(apiTarget, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return plugin.Error("filterlist", err)
+		}
+		client := resolver.NewFilterClient(conn)
+		_, err = cron.Every(apiRulesRefreshInterval).Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), apiRulesRefreshInterval)
+			defer cancel()
+			changed, err := refreshAPIRules(ctx, client, builder, groups)
+			if err != nil {
+				logger.Error("failed to refresh API rules", zap.Error(err))
+				return
+			}
+			if changed {
+				logger.Info("API rules changed")
+			}
+		})
+		if err != nil {
+			return err
+		}
+		_, err = cron.Every(apiPausesRefreshInterval).Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), apiPausesRefreshInterval)
+			defer cancel()
+			if err := refreshPauses(ctx, client, filterlistPlugin.pauses); err != nil {
+				logger.Error("failed to refresh pauses", zap.Error(err))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
 
 	c.OnStartup(func() error {
 		cron.StartAsync()
@@ -71,7 +363,545 @@ func setup(c *caddy.Controller) error {
 	})
 	c.OnShutdown(func() error {
 		cron.Stop()
+		if conn != nil {
+			return conn.Close()
+		}
 		return nil
 	})
-	return err
+	if checkListen != "" {
+		config := dnsserver.GetConfig(c)
+		server := &http.Server{Handler: filterlistPlugin.checkHandler()}
+		c.OnStartup(func() error {
+			// The injector plugin is registered once all plugins are set up.
+			if lookup, ok := config.Handler("injector").(overrideLookup); ok {
+				filterlistPlugin.overrides.set(lookup)
+			}
+			listener, err := net.Listen("tcp", checkListen)
+			if err != nil {
+				return plugin.Error("filterlist", err)
+			}
+			go func() {
+				if err := server.Serve(listener); err != http.ErrServerClosed {
+					logger.Error("check server failed", zap.Error(err))
+				}
+			}()
+			return nil
+		})
+		c.OnShutdown(func() error {
+			return server.Close()
+		})
+	}
+	return nil
+}
+
+func hasRules(group *Group) bool {
+	return len(group.Blocklists) != 0 || len(group.Allowlists) != 0 || len(group.Rules) != 0 || len(group.RulesFiles) != 0 ||
+		len(group.Services) != 0 || len(group.SafeSearch) != 0 || len(group.Schedules) != 0
+}
+
+// Configuration of fetching the lists.
+type fetchConfig struct {
+	interval  time.Duration
+	jitter    time.Duration
+	retry     RetryPolicy
+	timeout   time.Duration
+	maxSize   int64
+	caBundles []string
+}
+
+func defaultFetchConfig() fetchConfig {
+	return fetchConfig{
+		interval: time.Hour * 6,
+		retry:    defaultRetryPolicy,
+		timeout:  time.Minute * 5,
+	}
+}
+
+// Parses the list fetching properties:
+//
+//	refresh INTERVAL [JITTER]
+//	retry ATTEMPTS
+//	backoff FAILURES DURATION [FACTOR MAX]
+//	fetch_timeout DURATION
+//	max_list_size BYTES
+//	ca_bundle PATH...
+func parseFetchConfig(c *caddy.Controller, fetch *fetchConfig) error {
+	property := c.Val()
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return c.ArgErr()
+	}
+	durations := []time.Duration{}
+	parseDurations := func(args ...string) error {
+		for _, arg := range args {
+			duration, err := time.ParseDuration(arg)
+			if err != nil || duration < 0 {
+				return plugin.Error("filterlist", c.Errf("invalid %s duration %q", property, arg))
+			}
+			durations = append(durations, duration)
+		}
+		return nil
+	}
+	switch property {
+	case "refresh":
+		if len(args) > 2 {
+			return c.ArgErr()
+		}
+		if err := parseDurations(args...); err != nil {
+			return err
+		}
+		if durations[0] == 0 {
+			return plugin.Error("filterlist", c.Errf("refresh interval must be positive"))
+		}
+		fetch.interval = durations[0]
+		if len(durations) == 2 {
+			fetch.jitter = durations[1]
+		}
+	case "retry":
+		if len(args) != 1 {
+			return c.ArgErr()
+		}
+		attempts, err := strconv.Atoi(args[0])
+		if err != nil || attempts < 1 {
+			return plugin.Error("filterlist", c.Errf("invalid retry attempts %q", args[0]))
+		}
+		fetch.retry.FailuresUntilError = attempts
+	case "backoff":
+		if len(args) != 2 && len(args) != 4 {
+			return c.ArgErr()
+		}
+		failures, err := strconv.Atoi(args[0])
+		if err != nil || failures < 1 {
+			return plugin.Error("filterlist", c.Errf("invalid backoff failures %q", args[0]))
+		}
+		fetch.retry.FailuresUntilBackoff = failures
+		if err := parseDurations(args[1]); err != nil {
+			return err
+		}
+		fetch.retry.Backoff = durations[0]
+		fetch.retry.BackoffFactor = 1
+		fetch.retry.MaxBackoff = durations[0]
+		if len(args) == 4 {
+			factor, err := strconv.ParseFloat(args[2], 64)
+			if err != nil || factor < 1 {
+				return plugin.Error("filterlist", c.Errf("invalid backoff factor %q", args[2]))
+			}
+			if err := parseDurations(args[3]); err != nil {
+				return err
+			}
+			fetch.retry.BackoffFactor = factor
+			fetch.retry.MaxBackoff = max(durations[1], durations[0])
+		}
+	case "fetch_timeout":
+		if len(args) != 1 {
+			return c.ArgErr()
+		}
+		if err := parseDurations(args...); err != nil {
+			return err
+		}
+		fetch.timeout = durations[0]
+	case "max_list_size":
+		if len(args) != 1 {
+			return c.ArgErr()
+		}
+		size, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || size < 0 {
+			return plugin.Error("filterlist", c.Errf("invalid max_list_size %q", args[0]))
+		}
+		fetch.maxSize = size
+	case "ca_bundle":
+		fetch.caBundles = append(fetch.caBundles, args...)
+	}
+	return nil
+}
+
+// Builds the fetch options, the HTTP client uses the proxy environment
+// variables like the default client.
+func (fetch fetchConfig) options() (FetchOptions, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(fetch.caBundles) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range fetch.caBundles {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return FetchOptions{}, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return FetchOptions{}, fmt.Errorf("no certificates in CA bundle %s", path)
+			}
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return FetchOptions{
+		// A timeout of 0 means no timeout.
+		Client:  &http.Client{Transport: transport, Timeout: fetch.timeout},
+		MaxSize: fetch.maxSize,
+		Retry:   fetch.retry,
+	}, nil
+}
+
+// Parses the block mode, custom_ip takes an IPv4 address, an IPv6 address, or
+// one of each:
+//
+//	block_mode null_ip|nxdomain|refused|custom_ip [IP...]
+func parseBlockMode(c *caddy.Controller) (BlockMode, []net.IP, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return "", nil, c.ArgErr()
+	}
+	mode := BlockMode(args[0])
+	switch mode {
+	case BlockModeNullIP, BlockModeNXDomain, BlockModeRefused:
+		if len(args) != 1 {
+			return "", nil, c.ArgErr()
+		}
+		return mode, nil, nil
+	case BlockModeCustomIP:
+		if len(args) < 2 || len(args) > 3 {
+			return "", nil, c.ArgErr()
+		}
+		ips := []net.IP{}
+		hasIPv4, hasIPv6 := false, false
+		for _, arg := range args[1:] {
+			ip := net.ParseIP(arg)
+			if ip == nil {
+				return "", nil, plugin.Error("filterlist", c.Errf("invalid block_mode address %q", arg))
+			}
+			if ip.To4() != nil {
+				if hasIPv4 {
+					return "", nil, plugin.Error("filterlist", c.Errf("block_mode accepts one IPv4 address"))
+				}
+				hasIPv4 = true
+			} else {
+				if hasIPv6 {
+					return "", nil, plugin.Error("filterlist", c.Errf("block_mode accepts one IPv6 address"))
+				}
+				hasIPv6 = true
+			}
+			ips = append(ips, ip)
+		}
+		return mode, ips, nil
+	}
+	return "", nil, plugin.Error("filterlist", c.Errf("unknown block_mode %q", args[0]))
+}
+
+// Parses a group block:
+//
+//	group NAME {
+//		cidr CIDR...
+//		ecs CIDR...
+//		client_id ID...
+//		client_tags TAG...
+//		blocklists URL...
+//		allowlists URL...
+//		rule RULE
+//		rules_file PATH...
+//		block_services SERVICE...
+//		safesearch [ENGINE...]
+//		schedule NAME {...}
+//		audit LIST...
+//	}
+func parseGroup(c *caddy.Controller) (*Group, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return nil, c.ArgErr()
+	}
+	if args[0] == defaultGroupName {
+		return nil, plugin.Error("filterlist", c.Errf("group name %q is reserved for the top level lists", args[0]))
+	}
+	group := &Group{Name: args[0]}
+	if !c.NextArg() || c.Val() != "{" {
+		return nil, plugin.Error("filterlist", c.Errf("expected a block for group %q", group.Name))
+	}
+	for c.Next() && c.Val() != "}" {
+		switch c.Val() {
+		case "blocklists", "allowlists", "rule", "rules_file":
+			if err := parseRules(c, group); err != nil {
+				return nil, err
+			}
+		case "block_services", "safesearch":
+			if err := parseCatalog(c, group); err != nil {
+				return nil, err
+			}
+		case "schedule":
+			if err := parseSchedule(c, group); err != nil {
+				return nil, err
+			}
+		case "audit":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			group.Audit = append(group.Audit, args...)
+		case "cidr", "ecs":
+			property := c.Val()
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			for _, arg := range args {
+				prefix, err := parsePrefix(arg)
+				if err != nil {
+					return nil, plugin.Error("filterlist", c.Errf("invalid %s %q: %v", property, arg, err))
+				}
+				if property == "cidr" {
+					group.Networks = append(group.Networks, prefix)
+				} else {
+					group.ClientSubnets = append(group.ClientSubnets, prefix)
+				}
+			}
+		case "client_id":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			group.ClientIDs = append(group.ClientIDs, args...)
+		case "client_tags":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			group.ClientTags = append(group.ClientTags, args...)
+			slices.Sort(group.ClientTags)
+		default:
+			return nil, plugin.Error("filterlist", c.Errf("unknown group property %q", c.Val()))
+		}
+	}
+	if c.Val() != "}" {
+		return nil, plugin.Error("filterlist", c.Errf("unterminated block for group %q", group.Name))
+	}
+	if group.matchesAll() {
+		return nil, plugin.Error("filterlist", c.Errf("group %q requires cidr, ecs, or client_id", group.Name))
+	}
+	if err := validateAudit(c, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func parseRules(c *caddy.Controller, group *Group) error {
+	property := c.Val()
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return c.ArgErr()
+	}
+	switch property {
+	case "blocklists":
+		group.Blocklists = append(group.Blocklists, args...)
+	case "allowlists":
+		group.Allowlists = append(group.Allowlists, args...)
+	case "rule":
+		// Hosts lines are split into arguments.
+		group.Rules = append(group.Rules, strings.Join(args, " "))
+	case "rules_file":
+		group.RulesFiles = append(group.RulesFiles, args...)
+	}
+	return nil
+}
+
+// Parses a schedule block of the group, the schedule is active all day on
+// every day without days and time:
+//
+//	schedule NAME {
+//		days DAY|DAY-DAY...
+//		time START END
+//		timezone ZONE
+//		blocklists URL...
+//		rule RULE
+//		rules_file PATH...
+//		block_services SERVICE...
+//	}
+func parseSchedule(c *caddy.Controller, group *Group) error {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return c.ArgErr()
+	}
+	for _, s := range group.Schedules {
+		if s.Name == args[0] {
+			return plugin.Error("filterlist", c.Errf("duplicate schedule %q", args[0]))
+		}
+	}
+	schedule := &Schedule{Name: args[0]}
+	if !c.NextArg() || c.Val() != "{" {
+		return plugin.Error("filterlist", c.Errf("expected a block for schedule %q", schedule.Name))
+	}
+	// The lists of the schedule are parsed like the lists of a group.
+	lists := &Group{Name: group.Name}
+	hasDays := false
+	for c.Next() && c.Val() != "}" {
+		switch c.Val() {
+		case "blocklists", "rule", "rules_file":
+			if err := parseRules(c, lists); err != nil {
+				return err
+			}
+		case "block_services":
+			if err := parseCatalog(c, lists); err != nil {
+				return err
+			}
+		case "days":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return c.ArgErr()
+			}
+			for _, arg := range args {
+				if err := parseDays(arg, &schedule.Days); err != nil {
+					return plugin.Error("filterlist", c.Errf("invalid days %q: %v", arg, err))
+				}
+			}
+			hasDays = true
+		case "time":
+			args := c.RemainingArgs()
+			if len(args) != 2 {
+				return c.ArgErr()
+			}
+			start, err := parseTimeOfDay(args[0])
+			if err != nil {
+				return plugin.Error("filterlist", c.Errf("invalid time %q: %v", args[0], err))
+			}
+			end, err := parseTimeOfDay(args[1])
+			if err != nil {
+				return plugin.Error("filterlist", c.Errf("invalid time %q: %v", args[1], err))
+			}
+			schedule.Ranges = append(schedule.Ranges, TimeRange{Start: start, End: end})
+		case "timezone":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.ArgErr()
+			}
+			location, err := time.LoadLocation(args[0])
+			if err != nil {
+				return plugin.Error("filterlist", c.Errf("invalid timezone %q: %v", args[0], err))
+			}
+			schedule.Location = location
+		default:
+			return plugin.Error("filterlist", c.Errf("unknown schedule property %q", c.Val()))
+		}
+	}
+	if c.Val() != "}" {
+		return plugin.Error("filterlist", c.Errf("unterminated block for schedule %q", schedule.Name))
+	}
+	if len(lists.Blocklists) == 0 && len(lists.Rules) == 0 && len(lists.RulesFiles) == 0 && len(lists.Services) == 0 {
+		return plugin.Error("filterlist", c.Errf("schedule %q requires blocklists, rule, rules_file, or block_services", schedule.Name))
+	}
+	if !hasDays {
+		schedule.Days = allDays
+	}
+	if len(schedule.Ranges) == 0 {
+		schedule.Ranges = []TimeRange{allDay}
+	}
+	schedule.Blocklists = lists.Blocklists
+	schedule.Rules = lists.Rules
+	schedule.RulesFiles = lists.RulesFiles
+	schedule.Services = lists.Services
+	group.Schedules = append(group.Schedules, schedule)
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parses a day or a range of days, such as "sun-thu", into days.
+func parseDays(s string, days *[7]bool) error {
+	first, last, isRange := strings.Cut(strings.ToLower(s), "-")
+	start, ok := weekdays[first]
+	if !ok {
+		return fmt.Errorf("unknown day %q", first)
+	}
+	end := start
+	if isRange {
+		end, ok = weekdays[last]
+		if !ok {
+			return fmt.Errorf("unknown day %q", last)
+		}
+	}
+	for day := start; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == end {
+			return nil
+		}
+	}
+}
+
+// Parses a time of day in the HH:MM format to minutes since midnight, "24:00"
+// is the end of the day.
+func parseTimeOfDay(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Parses the properties of the built-in catalogs, safesearch without engines
+// enforces safe search of all engines:
+//
+//	block_services SERVICE...
+//	safesearch [ENGINE...]
+func parseCatalog(c *caddy.Controller, group *Group) error {
+	property := c.Val()
+	args := c.RemainingArgs()
+	switch property {
+	case "block_services":
+		if len(args) == 0 {
+			return c.ArgErr()
+		}
+		for _, arg := range args {
+			if _, ok := serviceDomains[arg]; !ok {
+				return plugin.Error("filterlist", c.Errf("unknown service %q, known services are %s", arg, strings.Join(serviceNames(), ", ")))
+			}
+		}
+		group.Services = append(group.Services, args...)
+		slices.Sort(group.Services)
+		group.Services = slices.Compact(group.Services)
+	case "safesearch":
+		if len(args) == 0 {
+			args = safeSearchEngineNames()
+		}
+		for _, arg := range args {
+			if _, ok := safeSearchEngines[arg]; !ok {
+				return plugin.Error("filterlist", c.Errf("unknown safesearch engine %q, known engines are %s", arg, strings.Join(safeSearchEngineNames(), ", ")))
+			}
+		}
+		group.SafeSearch = append(group.SafeSearch, args...)
+		slices.Sort(group.SafeSearch)
+		group.SafeSearch = slices.Compact(group.SafeSearch)
+	}
+	return nil
+}
+
+// Audited lists must be blocklists or services of the group or its schedules.
+func validateAudit(c *caddy.Controller, group *Group) error {
+	lists := group.auditableLists()
+	for _, list := range group.Audit {
+		if !slices.Contains(lists, list) {
+			return plugin.Error("filterlist", c.Errf("audit %q is not a blocklist or service:NAME of group %q", list, group.Name))
+		}
+	}
+	return nil
+}
+
+// Parses a CIDR or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
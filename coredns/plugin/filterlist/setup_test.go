@@ -1,9 +1,15 @@
 package filterlist
 
 import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
 )
 
 func TestSetup(t *testing.T) {
@@ -46,3 +52,488 @@ func TestSetupNoBlocklistsArgs(t *testing.T) {
 		t.Fatalf("expected an error, got no errors")
 	}
 }
+
+func TestSetupGroups(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		allowlists https://example.com/allow
+		client_id_option 65002
+		group kids {
+			cidr 10.0.0.0/24 10.0.1.5
+			ecs 192.168.0.0/16
+			client_id tablet
+			blocklists https://example.com/kids
+		}
+		group work {
+			client_id laptop
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupOnlyGroups(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		group kids {
+			cidr 10.0.0.0/24
+			blocklists https://example.com/kids
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupInvalidGroups(t *testing.T) {
+	configs := []string{
+		`filterlist {
+			group kids {
+				blocklists https://example.com
+			}
+		}`,
+		`filterlist {
+			group kids {
+				cidr 10.0.0.0/33
+			}
+		}`,
+		`filterlist {
+			group default {
+				cidr 10.0.0.0/24
+			}
+		}`,
+		`filterlist {
+			group kids {
+				cidr 10.0.0.0/24
+			}
+			group kids {
+				cidr 10.0.1.0/24
+			}
+		}`,
+		`filterlist {
+			group kids {
+				cidr 10.0.0.0/24
+				foo bar
+			}
+		}`,
+		`filterlist {
+			group {
+				cidr 10.0.0.0/24
+			}
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			client_id_option 70000
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupRules(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(rulesFile, []byte("||example.net^\n"), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	c := caddy.NewTestController("dns", `filterlist {
+		rule @@||example.com^
+		rule 0.0.0.0 ads.example.com
+		rules_file `+rulesFile+`
+		api localhost:50051
+		group kids {
+			cidr 10.0.0.0/24
+			client_tags user_child device_phone
+			rule ||example.org^
+			rule ||example.org^$ctag=user_child,dnsrewrite=192.0.2.1
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupCacheDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "lists")
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		cache_dir `+dir+`
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("expected cache directory to be created, got %v", err)
+	}
+}
+
+func TestSetupFetchOptions(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com file:///etc/corewarden/blocklist.txt
+		refresh 1h 10m
+		retry 5
+		backoff 2 30s 2 10m
+		fetch_timeout 30s
+		max_list_size 10485760
+		top_blocked_domains 20
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupInvalidFetchOptions(t *testing.T) {
+	configs := []string{
+		"refresh 0s",
+		"refresh 1h 1m 1m",
+		"refresh foo",
+		"retry 0",
+		"backoff 2",
+		"backoff 2 30s 2",
+		"backoff 2 30s 0.5 10m",
+		"backoff 0 30s",
+		"fetch_timeout -1s",
+		"max_list_size -1",
+		"ca_bundle /nonexistent/ca.pem",
+		"top_blocked_domains 0",
+	}
+	for i, option := range configs {
+		c := caddy.NewTestController("dns", `filterlist {
+			blocklists https://example.com
+			`+option+`
+		}`)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupOnlyAPI(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		api localhost:50051
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupBlockMode(t *testing.T) {
+	configs := []string{
+		`filterlist {
+			blocklists https://example.com
+			block_mode nxdomain
+			block_ttl 60
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_mode custom_ip 192.0.2.1 2001:db8::1
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err != nil {
+			t.Errorf("Test %d: expected no errors, got: %v", i, err)
+		}
+	}
+}
+
+func TestSetupInvalidBlockMode(t *testing.T) {
+	configs := []string{
+		`filterlist {
+			blocklists https://example.com
+			block_mode foo
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_mode refused 192.0.2.1
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_mode custom_ip
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_mode custom_ip 192.0.2.1 192.0.2.2
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_mode custom_ip foo
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			block_ttl -1
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupInvalidRules(t *testing.T) {
+	configs := []string{
+		`filterlist {
+			rule
+		}`,
+		`filterlist {
+			rules_file /nonexistent/rules.txt
+		}`,
+		`filterlist {
+			api
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			cache_dir
+		}`,
+		`filterlist {
+			group kids {
+				cidr 10.0.0.0/24
+				client_tags
+			}
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestFetchConfigCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("||example.com^"))
+	}))
+	defer server.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	fetch := defaultFetchConfig()
+	fetch.caBundles = []string{bundle}
+	options, err := fetch.options()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	fetcher := &URLFetcher{url: server.URL, client: options.Client}
+	if res, err := fetcher.Fetch(); err != nil || res != "||example.com^" {
+		t.Errorf("expected content without error, got %q %v", res, err)
+	}
+}
+
+func TestSetupCatalogs(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		safesearch
+		group kids {
+			cidr 10.0.0.0/24
+			block_services tiktok youtube
+			block_services tiktok
+			safesearch google bing
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupInvalidCatalogs(t *testing.T) {
+	tests := []string{
+		`filterlist {
+			block_services
+		}`,
+		`filterlist {
+			block_services foo
+		}`,
+		`filterlist {
+			safesearch yahoo
+		}`,
+	}
+	for i, config := range tests {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupSchedules(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		timezone Europe/Berlin
+		schedule weekends {
+			days sat sun
+			rule ||example.net^
+		}
+		group kids {
+			cidr 10.0.0.0/24
+			schedule school_nights {
+				days sun-thu
+				time 20:00 07:00
+				time 12:00 13:00
+				timezone America/New_York
+				blocklists https://example.com/social.txt
+				block_services tiktok
+			}
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+}
+
+func TestSetupInvalidSchedules(t *testing.T) {
+	tests := []string{
+		`filterlist {
+			schedule
+		}`,
+		`filterlist {
+			schedule night
+		}`,
+		`filterlist {
+			schedule night {
+				days mon
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				days someday
+				rule ||example.com^
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				time 20:00
+				rule ||example.com^
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				time 20:00 25:00
+				rule ||example.com^
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				timezone Mars/Olympus
+				rule ||example.com^
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				allowlists https://example.com
+				rule ||example.com^
+			}
+		}`,
+		`filterlist {
+			schedule night {
+				rule ||example.com^
+			}
+			schedule night {
+				rule ||example.net^
+			}
+		}`,
+		`filterlist {
+			timezone Mars/Olympus
+			rule ||example.com^
+		}`,
+	}
+	for i, config := range tests {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupResponseCheck(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		response_check
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+	c = caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		response_check yes
+	}`)
+	if err := setup(c); err == nil {
+		t.Fatalf("expected an error, got no errors")
+	}
+}
+
+func TestSetupAudit(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com https://example.com/aggressive.txt
+		audit https://example.com/aggressive.txt
+		group kids {
+			cidr 10.0.0.0/24
+			block_services tiktok
+			audit service:tiktok
+			schedule night {
+				blocklists https://example.com/night.txt
+			}
+			audit https://example.com/night.txt
+		}
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+	tests := []string{
+		`filterlist {
+			blocklists https://example.com
+			audit
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			audit https://example.com/other.txt
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			allowlists https://example.com/allow.txt
+			audit https://example.com/allow.txt
+		}`,
+		`filterlist {
+			blocklists https://example.com
+			group kids {
+				cidr 10.0.0.0/24
+				audit https://example.com
+			}
+		}`,
+	}
+	for i, config := range tests {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
+
+func TestSetupCheckListen(t *testing.T) {
+	c := caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		check_listen localhost:9154
+	}`)
+	if err := setup(c); err != nil {
+		t.Fatalf("expected no errors, got: %v", err)
+	}
+	// The injector plugin is found at startup, after the plugin is chained.
+	fl := dnsserver.GetConfig(c).Plugin[0](nil).(*FilterList)
+	if fl.overrides == nil {
+		t.Errorf("expected the chained plugin to hold the overrides")
+	}
+	c = caddy.NewTestController("dns", `filterlist {
+		blocklists https://example.com
+		check_listen
+	}`)
+	if err := setup(c); err == nil {
+		t.Fatalf("expected an error, got no errors")
+	}
+}
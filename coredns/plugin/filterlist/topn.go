@@ -0,0 +1,82 @@
+package filterlist
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Interval of updating the most blocked domains gauge.
+const topBlockedInterval = time.Minute
+
+// Domains tracked per reported domain, more domains make the counts of the
+// reported domains more accurate.
+const topBlockedCapacityFactor = 10
+
+// Approximate counts of the most blocked domains, using the space saving
+// algorithm to bound the memory to a fixed count of domains.
+type topDomains struct {
+	mu       sync.Mutex
+	n        int
+	capacity int
+	counts   map[string]uint64
+}
+
+type domainCount struct {
+	domain string
+	count  uint64
+}
+
+func newTopDomains(n int) *topDomains {
+	return &topDomains{
+		n:        n,
+		capacity: n * topBlockedCapacityFactor,
+		counts:   map[string]uint64{},
+	}
+}
+
+func (t *topDomains) add(domain string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.counts[domain]; ok || len(t.counts) < t.capacity {
+		t.counts[domain]++
+		return
+	}
+	// The least counted domain is replaced, and the new domain inherits its
+	// count as an upper bound of its own.
+	minDomain, minCount := "", uint64(0)
+	for d, count := range t.counts {
+		if minDomain == "" || count < minCount {
+			minDomain, minCount = d, count
+		}
+	}
+	delete(t.counts, minDomain)
+	t.counts[domain] = minCount + 1
+}
+
+// Returns the n most counted domains, most counted first.
+func (t *topDomains) top() []domainCount {
+	t.mu.Lock()
+	counts := make([]domainCount, 0, len(t.counts))
+	for domain, count := range t.counts {
+		counts = append(counts, domainCount{domain: domain, count: count})
+	}
+	t.mu.Unlock()
+	slices.SortFunc(counts, func(a, b domainCount) int {
+		if c := cmp.Compare(b.count, a.count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.domain, b.domain)
+	})
+	return counts[:min(t.n, len(counts))]
+}
+
+// Replaces the most blocked domains gauge with the current counts.
+func (t *topDomains) updateGauge() {
+	top := t.top()
+	topBlockedDomains.Reset()
+	for _, c := range top {
+		topBlockedDomains.WithLabelValues(c.domain).Set(float64(c.count))
+	}
+}
@@ -0,0 +1,34 @@
+package filterlist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTopDomains(t *testing.T) {
+	top := newTopDomains(2)
+	for i := 0; i < 5; i++ {
+		top.add("ads.example.com.")
+	}
+	for i := 0; i < 3; i++ {
+		top.add("tracker.example.com.")
+	}
+	// Rare domains evict each other once the capacity is reached, their
+	// counts are overestimated up to the count of the evicted domain.
+	for i := 0; i < 30; i++ {
+		top.add(fmt.Sprintf("rare%d.example.com.", i))
+	}
+	counts := top.top()
+	if len(counts) != 2 {
+		t.Fatalf("expected 2 domains, got %v", counts)
+	}
+	if counts[0].domain != "ads.example.com." || counts[0].count != 5 {
+		t.Errorf("expected ads.example.com. counted 5 times first, got %v", counts[0])
+	}
+	if counts[1].domain != "tracker.example.com." || counts[1].count != 3 {
+		t.Errorf("expected tracker.example.com. counted 3 times second, got %v", counts[1])
+	}
+	if len(top.counts) != 2*topBlockedCapacityFactor {
+		t.Errorf("expected %d tracked domains, got %d", 2*topBlockedCapacityFactor, len(top.counts))
+	}
+}
@@ -14,8 +14,14 @@ import (
 
 const name = "injector"
 
+// CNAME chains are followed through the API records for at most this many
+// CNAME records.
+const maxCNAMEChain = 8
+
 type Injector struct {
-	client   resolver.ResolverClient
+	client resolver.ResolverClient
+	// Answers instead of client while fresh, nil when not watching.
+	local    *localRecords
 	upstream Upstream
 	logger   *zap.Logger
 	next     plugin.Handler
@@ -35,10 +41,7 @@ func (i *Injector) Ready() bool {
 
 func (i *Injector) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
-	res, err := i.client.Resolve(ctx, &resolver.Question{
-		Name:  state.Name(),
-		Qtype: uint32(state.QType()),
-	})
+	res, err := i.resolve(ctx, state.Name(), state.QType())
 	if err != nil {
 		if status.Convert(err).Code() == codes.NotFound {
 			return plugin.NextOrFailure(i.Name(), i.next, ctx, w, r)
@@ -51,6 +54,10 @@ func (i *Injector) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	m := new(dns.Msg)
 	m.SetReply(r)
 	m.Rcode = dns.RcodeSuccess
+	// Negative answers without nxdomain are NODATA, both carry the zone SOA.
+	if res.Nxdomain {
+		m.Rcode = dns.RcodeNameError
+	}
 
 	m.Answer, err = parseRRs(res.Answer)
 	if err != nil {
@@ -70,18 +77,105 @@ func (i *Injector) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return dns.RcodeServerFailure, err
 	}
 
-	// Perform CNAME lookup if needed.
-	if len(m.Answer) == 1 && m.Answer[0].Header().Rrtype == dns.TypeCNAME {
-		if record, ok := m.Answer[0].(*dns.CNAME); ok {
-			i.logger.Info("Querying upstream for CNAME record", zap.String("target", record.Target), zap.Uint16("qtype", state.QType()))
-			if up, err := i.upstream.Lookup(ctx, state, record.Target, state.QType()); err == nil && up != nil {
+	if err = i.followCNAMEs(ctx, state, m); err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	return m.Rcode, w.WriteMsg(m)
+}
+
+// Follows the CNAME chain of the answer through the API records, appending
+// each answer. The upstream is only queried at the end of the chain, for the
+// first name the API does not own. Negative answers for names in the chain
+// set the rcode and authority section of m.
+func (i *Injector) followCNAMEs(ctx context.Context, state request.Request, m *dns.Msg) error {
+	visited := map[string]bool{state.Name(): true}
+	target, ok := cnameTarget(m.Answer, state.QType())
+	for ok {
+		if visited[target] {
+			i.logger.Warn("CNAME loop", zap.String("name", state.Name()), zap.String("target", target))
+			return nil
+		}
+		if maxCNAMEChain < len(visited) {
+			i.logger.Warn("CNAME chain too long", zap.String("name", state.Name()), zap.Int("length", len(visited)))
+			return nil
+		}
+		visited[target] = true
+
+		res, err := i.resolve(ctx, target, state.QType())
+		if err != nil {
+			if status.Convert(err).Code() != codes.NotFound {
+				i.logger.Error("grpc error", zap.Error(err))
+				return err
+			}
+			i.logger.Info("Querying upstream for CNAME record", zap.String("target", target), zap.Uint16("qtype", state.QType()))
+			if up, err := i.upstream.Lookup(ctx, state, target, state.QType()); err == nil && up != nil {
 				m.Truncated = up.Truncated
 				m.Answer = append(m.Answer, up.Answer...)
 			}
+			return nil
+		}
+
+		answer, err := parseRRs(res.Answer)
+		if err != nil {
+			i.logger.Error("RR parsing error", zap.Error(err))
+			return err
+		}
+		m.Answer = append(m.Answer, answer...)
+		if len(answer) == 0 {
+			m.Ns, err = parseRRs(res.Ns)
+			if err != nil {
+				i.logger.Error("RR parsing error", zap.Error(err))
+				return err
+			}
+			if res.Nxdomain {
+				m.Rcode = dns.RcodeNameError
+			}
+			return nil
 		}
+		target, ok = cnameTarget(answer, state.QType())
 	}
+	return nil
+}
 
-	return m.Rcode, w.WriteMsg(m)
+// Returns the target when the answer is a single CNAME record that does not
+// answer the question type.
+func cnameTarget(answer []dns.RR, qtype uint16) (string, bool) {
+	if len(answer) != 1 || qtype == dns.TypeCNAME {
+		return "", false
+	}
+	record, ok := answer[0].(*dns.CNAME)
+	if !ok {
+		return "", false
+	}
+	return dns.CanonicalName(record.Target), true
+}
+
+// Answers from the local copy while fresh, otherwise from the API. The stale
+// local copy is used when the API fails, to keep answering during outages.
+func (i *Injector) resolve(ctx context.Context, name string, qtype uint16) (*resolver.Response, error) {
+	if i.local != nil && i.local.fresh() {
+		return i.local.resolve(name, qtype)
+	}
+	res, err := i.client.Resolve(ctx, &resolver.Question{
+		Name:  name,
+		Qtype: uint32(qtype),
+	})
+	if err != nil && status.Convert(err).Code() != codes.NotFound && i.local != nil && i.local.hasSnapshot() {
+		i.logger.Warn("grpc error, answering from stale local records", zap.Error(err))
+		return i.local.resolve(name, qtype)
+	}
+	return res, err
+}
+
+// Looks up the records of the name in the API, for explaining answers to
+// other plugins. Returns nil when the API does not own the name.
+func (i *Injector) Lookup(ctx context.Context, name string, qtype uint16) (*resolver.Response, error) {
+	res, err := i.resolve(ctx, dns.CanonicalName(name), qtype)
+	if status.Convert(err).Code() == codes.NotFound {
+		return nil, nil
+	}
+	return res, err
 }
 
 func parseRRs(rrs []string) (res []dns.RR, err error) {
@@ -2,6 +2,7 @@ package injector
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -113,6 +114,45 @@ func TestForwardWhenNotFound(t *testing.T) {
 	h.AssertDone()
 }
 
+func TestLookup(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{
+		{
+			In: &resolver.Question{
+				Name:  "example.com.",
+				Qtype: uint32(dns.TypeA),
+			},
+			Result: &resolver.Response{Answer: []string{"example.com.	300	IN	A	192.0.2.1"}},
+		},
+		{
+			In: &resolver.Question{
+				Name:  "other.example.com.",
+				Qtype: uint32(dns.TypeA),
+			},
+			Result: &resolver.Response{},
+			Err:    status.Error(codes.NotFound, "record not found"),
+		},
+	})
+	i := Injector{
+		client: &r,
+		logger: zap.NewNop(),
+	}
+	res, err := i.Lookup(context.Background(), "Example.com", dns.TypeA)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if res == nil || len(res.Answer) != 1 {
+		t.Errorf("Expected the API answer, got %v\n", res)
+	}
+	res, err = i.Lookup(context.Background(), "other.example.com.", dns.TypeA)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if res != nil {
+		t.Errorf("Expected no response for names the API does not own, got %v\n", res)
+	}
+	r.AssertDone()
+}
+
 func TestUnknownError(t *testing.T) {
 	r := NewMockResolver(t, []MockResolverAction{
 		{
@@ -258,6 +298,59 @@ func TestInvalidExtraRR(t *testing.T) {
 	h.AssertDone()
 }
 
+func TestNegativeAnswers(t *testing.T) {
+	soa := "example.com.\t3600\tIN\tSOA\texample.com. hostmaster.example.com. 1 7200 3600 1209600 3600"
+	tests := []struct {
+		nxdomain bool
+		rcode    int
+	}{
+		{nxdomain: false, rcode: dns.RcodeSuccess},
+		{nxdomain: true, rcode: dns.RcodeNameError},
+	}
+	for _, tc := range tests {
+		r := NewMockResolver(t, []MockResolverAction{
+			{
+				In: &resolver.Question{
+					Name:  "example.com.",
+					Qtype: uint32(dns.TypeAAAA),
+				},
+				Result: &resolver.Response{
+					Answer:   []string{},
+					Ns:       []string{soa},
+					Extra:    []string{},
+					Nxdomain: tc.nxdomain,
+				},
+				Err: nil,
+			},
+		})
+		h := NewMockHandler(t, []MockHandlerAction{})
+		i := Injector{
+			client: &r,
+			logger: zap.NewNop(),
+			next:   &h,
+		}
+
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn("example.com"), dns.TypeAAAA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		code, err := i.ServeDNS(context.Background(), rec, req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v\n", err)
+		}
+		if code != tc.rcode || rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Expected rcode %d, got %d\n", tc.rcode, rec.Msg.Rcode)
+		}
+		if len(rec.Msg.Answer) != 0 {
+			t.Errorf("Expected answer length to be 0, got %d\n", len(rec.Msg.Answer))
+		}
+		if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].String() != soa {
+			t.Errorf("Expected ns to be the zone SOA, got %v\n", rec.Msg.Ns)
+		}
+		r.AssertDone()
+		h.AssertDone()
+	}
+}
+
 func TestCname(t *testing.T) {
 	r := NewMockResolver(t, []MockResolverAction{
 		{
@@ -272,6 +365,17 @@ func TestCname(t *testing.T) {
 			},
 			Err: nil,
 		},
+		{
+			In: &resolver.Question{
+				Name:  "example.net.",
+				Qtype: uint32(dns.TypeA),
+			},
+			Result: &resolver.Response{},
+			Err: status.Error(
+				codes.NotFound,
+				"record not found",
+			),
+		},
 	})
 	nextOut := new(dns.Msg)
 	nextOut.Answer = []dns.RR{
@@ -333,6 +437,169 @@ func TestCname(t *testing.T) {
 	h.AssertDone()
 }
 
+func TestCnameChain(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{
+		{
+			In:     &resolver.Question{Name: "a.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"a.example.com. IN CNAME b.example.com."}},
+		},
+		{
+			In:     &resolver.Question{Name: "b.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"b.example.com. IN CNAME c.example.com."}},
+		},
+		{
+			In:     &resolver.Question{Name: "c.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"c.example.com. IN A 127.0.0.1"}},
+		},
+	})
+	h := NewMockHandler(t, []MockHandlerAction{})
+	u := NewMockUpstream(t, []MockUpstreamAction{})
+	i := Injector{
+		client:   &r,
+		logger:   zap.NewNop(),
+		next:     &h,
+		upstream: &u,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	code, err := i.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d\n", dns.RcodeSuccess, code)
+	}
+	expected := []string{
+		"a.example.com.\t3600\tIN\tCNAME\tb.example.com.",
+		"b.example.com.\t3600\tIN\tCNAME\tc.example.com.",
+		"c.example.com.\t3600\tIN\tA\t127.0.0.1",
+	}
+	if len(rec.Msg.Answer) != len(expected) {
+		t.Fatalf("Expected answer length to be %d, got %d\n", len(expected), len(rec.Msg.Answer))
+	}
+	for j, rr := range expected {
+		if rec.Msg.Answer[j].String() != rr {
+			t.Errorf("Expected answer %d to be '%s', got '%s'\n", j, rr, rec.Msg.Answer[j])
+		}
+	}
+	r.AssertDone()
+	h.AssertDone()
+	u.AssertDone()
+}
+
+func TestCnameChainNXDOMAIN(t *testing.T) {
+	soa := "example.com.\t3600\tIN\tSOA\texample.com. hostmaster.example.com. 1 7200 3600 1209600 3600"
+	r := NewMockResolver(t, []MockResolverAction{
+		{
+			In:     &resolver.Question{Name: "a.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"a.example.com. IN CNAME b.example.com."}},
+		},
+		{
+			In:     &resolver.Question{Name: "b.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Ns: []string{soa}, Nxdomain: true},
+		},
+	})
+	h := NewMockHandler(t, []MockHandlerAction{})
+	u := NewMockUpstream(t, []MockUpstreamAction{})
+	i := Injector{
+		client:   &r,
+		logger:   zap.NewNop(),
+		next:     &h,
+		upstream: &u,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	code, err := i.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if code != dns.RcodeNameError {
+		t.Errorf("Expected rcode %d, got %d\n", dns.RcodeNameError, code)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected answer length to be 1, got %d\n", len(rec.Msg.Answer))
+	}
+	if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].String() != soa {
+		t.Errorf("Expected ns to be the zone SOA, got %v\n", rec.Msg.Ns)
+	}
+	r.AssertDone()
+	h.AssertDone()
+	u.AssertDone()
+}
+
+func TestCnameLoop(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{
+		{
+			In:     &resolver.Question{Name: "a.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"a.example.com. IN CNAME b.example.com."}},
+		},
+		{
+			In:     &resolver.Question{Name: "b.example.com.", Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{Answer: []string{"b.example.com. IN CNAME a.example.com."}},
+		},
+	})
+	h := NewMockHandler(t, []MockHandlerAction{})
+	u := NewMockUpstream(t, []MockUpstreamAction{})
+	i := Injector{
+		client:   &r,
+		logger:   zap.NewNop(),
+		next:     &h,
+		upstream: &u,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := i.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if len(rec.Msg.Answer) != 2 {
+		t.Errorf("Expected answer length to be 2, got %d\n", len(rec.Msg.Answer))
+	}
+	r.AssertDone()
+	h.AssertDone()
+	u.AssertDone()
+}
+
+func TestCnameChainLimit(t *testing.T) {
+	actions := []MockResolverAction{}
+	for j := 0; j <= maxCNAMEChain; j++ {
+		name := fmt.Sprintf("%d.example.com.", j)
+		actions = append(actions, MockResolverAction{
+			In: &resolver.Question{Name: name, Qtype: uint32(dns.TypeA)},
+			Result: &resolver.Response{
+				Answer: []string{fmt.Sprintf("%s IN CNAME %d.example.com.", name, j+1)},
+			},
+		})
+	}
+	r := NewMockResolver(t, actions)
+	h := NewMockHandler(t, []MockHandlerAction{})
+	u := NewMockUpstream(t, []MockUpstreamAction{})
+	i := Injector{
+		client:   &r,
+		logger:   zap.NewNop(),
+		next:     &h,
+		upstream: &u,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("0.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := i.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if len(rec.Msg.Answer) != maxCNAMEChain+1 {
+		t.Errorf("Expected answer length to be %d, got %d\n", maxCNAMEChain+1, len(rec.Msg.Answer))
+	}
+	r.AssertDone()
+	h.AssertDone()
+	u.AssertDone()
+}
+
 type MockHandlerAction struct {
 	In    dns.Msg
 	Out   dns.Msg
@@ -432,6 +699,11 @@ func (r *MockResolver) Resolve(ctx context.Context, in *resolver.Question, opts
 	return r.actions[current].Result, r.actions[current].Err
 }
 
+func (r *MockResolver) Watch(ctx context.Context, in *resolver.WatchRequest, opts ...grpc.CallOption) (resolver.Resolver_WatchClient, error) {
+	r.t.Fatalf("Client called Watch when no method calls were expected\n")
+	return nil, nil
+}
+
 func (r *MockResolver) AssertDone() {
 	if r.currentIndex != len(r.actions) {
 		r.t.Fatalf("Expected client to call all mock actions, called %d out of %d method calls\n", r.currentIndex, len(r.actions))
@@ -467,6 +739,12 @@ func (u *MockUpstream) Lookup(ctx context.Context, state request.Request, name s
 	return current.Result, current.Err
 }
 
+func (u *MockUpstream) AssertDone() {
+	if u.currentIndex != len(u.actions) {
+		u.t.Fatalf("Expected client to call all mock actions, called %d out of %d method calls\n", u.currentIndex, len(u.actions))
+	}
+}
+
 func NewMockUpstream(t *testing.T, actions []MockUpstreamAction) MockUpstream {
 	return MockUpstream{
 		actions:      actions,
@@ -0,0 +1,288 @@
+package injector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The API sends heartbeats every 15 seconds, the local copy is stale after
+// missing a few of them.
+const staleAfter = 45 * time.Second
+
+// Delay before watching again after the stream fails.
+const watchRetryInterval = 5 * time.Second
+
+var errLocalNotFound = status.Error(codes.NotFound, "record not found")
+
+// Local copy of the records kept up to date with the Watch RPC.
+type localRecords struct {
+	mu    sync.RWMutex
+	root  *localNode
+	zones map[string]*resolver.Zone
+	// A snapshot was received on the current stream.
+	synced bool
+	// A snapshot was received on any stream, the copy may be stale.
+	loaded   bool
+	received time.Time
+}
+
+func newLocalRecords() *localRecords {
+	return &localRecords{root: newLocalNode(), zones: map[string]*resolver.Zone{}}
+}
+
+// Fresh when the current stream received a snapshot and recently received a
+// change or heartbeat.
+func (l *localRecords) fresh() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.synced && time.Since(l.received) < staleAfter
+}
+
+func (l *localRecords) hasSnapshot() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.loaded
+}
+
+// Keeps the copy up to date until ctx is cancelled.
+func (l *localRecords) watch(ctx context.Context, client resolver.ResolverClient, logger *zap.Logger) {
+	for {
+		err := l.receive(ctx, client)
+		l.setSynced(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Convert(err).Code() == codes.Unimplemented {
+			logger.Warn("API does not support watching records, resolving every query", zap.Error(err))
+			return
+		}
+		logger.Error("record watch failed, retrying", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (l *localRecords) receive(ctx context.Context, client resolver.ResolverClient) error {
+	stream, err := client.Watch(ctx, &resolver.WatchRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		l.apply(resp)
+	}
+}
+
+func (l *localRecords) apply(resp *resolver.WatchResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.received = time.Now()
+	if resp.Snapshot {
+		root := newLocalNode()
+		for _, node := range resp.Nodes {
+			root.set(node)
+		}
+		l.root = root
+		l.zones = map[string]*resolver.Zone{}
+		l.setZones(resp.Zones)
+		l.synced = true
+		l.loaded = true
+		return
+	}
+	// Changes sent before the snapshot of the stream are covered by it.
+	if !l.synced {
+		return
+	}
+	for _, node := range resp.Nodes {
+		l.root.set(node)
+	}
+	l.setZones(resp.Zones)
+}
+
+// Must be called with mu held.
+func (l *localRecords) setZones(zones []*resolver.Zone) {
+	for _, zone := range zones {
+		if zone.Soa == "" {
+			delete(l.zones, zone.Name)
+		} else {
+			l.zones[zone.Name] = zone
+		}
+	}
+}
+
+func (l *localRecords) setSynced(synced bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.synced = synced
+}
+
+// Answers like the Resolve RPC, with a NotFound status error when the name
+// is not owned by the API.
+func (l *localRecords) resolve(name string, qtype uint16) (*resolver.Response, error) {
+	l.mu.RLock()
+	records := l.root.resolve(name, qtype)
+	if len(records) == 0 {
+		defer l.mu.RUnlock()
+		return l.resolveNegative(name)
+	}
+	l.mu.RUnlock()
+	answer := make([]string, len(records))
+	for i, record := range records {
+		rr, err := dns.NewRR(record.Content)
+		if err != nil {
+			return nil, err
+		}
+		rr.Header().Name = name
+		answer[i] = rr.String()
+	}
+	return &resolver.Response{Answer: answer}, nil
+}
+
+// Matches the API: names with records of other types, or matching wildcard
+// records of other types, are NODATA. Other names in authoritative zones are
+// NODATA when records exist below them and NXDOMAIN otherwise, in other zones
+// they are not found. Must be called with mu held.
+func (l *localRecords) resolveNegative(name string) (*resolver.Response, error) {
+	var zone *resolver.Zone
+	// Offsets of the labels, longest name first.
+	for _, i := range dns.Split(name) {
+		if zone = l.zones[name[i:]]; zone != nil {
+			break
+		}
+	}
+	if zone == nil {
+		return nil, errLocalNotFound
+	}
+	node := l.root.lookup(name)
+	if (node != nil && 0 < len(node.records)) || l.root.wildcardCovers(name) {
+		return &resolver.Response{Ns: []string{zone.Soa}}, nil
+	}
+	if !zone.Authoritative {
+		return nil, errLocalNotFound
+	}
+	return &resolver.Response{
+		Ns:       []string{zone.Soa},
+		Nxdomain: node == nil || !node.hasRecordsBelow(),
+	}, nil
+}
+
+// Trie node keyed by labels from the root, holding the records at the node.
+type localNode struct {
+	children        map[string]*localNode
+	records         []*resolver.Record
+	wildcardRecords []*resolver.Record
+}
+
+func newLocalNode() *localNode {
+	return &localNode{children: map[string]*localNode{}}
+}
+
+// Replaces the records at the node, empty nodes are kept until the next
+// snapshot.
+func (n *localNode) set(node *resolver.Node) {
+	current := n
+	labels := dns.SplitDomainName(node.Name)
+	for i := len(labels) - 1; 0 <= i; i-- {
+		child, ok := current.children[labels[i]]
+		if !ok {
+			child = newLocalNode()
+			current.children[labels[i]] = child
+		}
+		current = child
+	}
+	current.records = nil
+	current.wildcardRecords = nil
+	for _, record := range node.Records {
+		if record.Wildcard {
+			current.wildcardRecords = append(current.wildcardRecords, record)
+		} else {
+			current.records = append(current.records, record)
+		}
+	}
+}
+
+// Matches the API: records at the node of the name of the question type or
+// CNAME, otherwise wildcard records of the question type at the closest
+// ancestor, excluding the root.
+func (n *localNode) resolve(name string, qtype uint16) []*resolver.Record {
+	node := n
+	var wildcard []*resolver.Record
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 <= i; i-- {
+		if node != n {
+			if matching := filterType(node.wildcardRecords, qtype, false); 0 < len(matching) {
+				wildcard = matching
+			}
+		}
+		node = node.children[labels[i]]
+		if node == nil {
+			return wildcard
+		}
+	}
+	if matching := filterType(node.records, qtype, true); 0 < len(matching) {
+		return matching
+	}
+	return wildcard
+}
+
+func filterType(records []*resolver.Record, qtype uint16, includeCNAME bool) []*resolver.Record {
+	matching := []*resolver.Record{}
+	for _, record := range records {
+		if uint16(record.Type) == qtype || (includeCNAME && record.Type == uint32(dns.TypeCNAME)) {
+			matching = append(matching, record)
+		}
+	}
+	return matching
+}
+
+func (n *localNode) lookup(name string) *localNode {
+	node := n
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 <= i && node != nil; i-- {
+		node = node.children[labels[i]]
+	}
+	return node
+}
+
+// Whether wildcard records of any type exist at an ancestor of the name,
+// excluding the root.
+func (n *localNode) wildcardCovers(name string) bool {
+	node := n
+	labels := dns.SplitDomainName(name)
+	for i := len(labels) - 1; 0 < i; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			return false
+		}
+		if 0 < len(node.wildcardRecords) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether wildcard records exist at the node or any records below it.
+func (n *localNode) hasRecordsBelow() bool {
+	if 0 < len(n.wildcardRecords) {
+		return true
+	}
+	for _, child := range n.children {
+		if 0 < len(child.records) || child.hasRecordsBelow() {
+			return true
+		}
+	}
+	return false
+}
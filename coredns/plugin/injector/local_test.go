@@ -0,0 +1,298 @@
+package injector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func createTestLocalRecords() *localRecords {
+	l := newLocalRecords()
+	l.apply(testSnapshot())
+	return l
+}
+
+func testSnapshot() *resolver.WatchResponse {
+	return &resolver.WatchResponse{
+		Snapshot: true,
+		Nodes: []*resolver.Node{
+			{
+				Name: "example.com.",
+				Records: []*resolver.Record{
+					{Type: uint32(dns.TypeA), Content: ".\t3600\tIN\tA\t127.0.0.1"},
+				},
+			},
+			{
+				Name: "wild.example.com.",
+				Records: []*resolver.Record{
+					{Type: uint32(dns.TypeA), Wildcard: true, Content: "*.wild.\t3600\tIN\tA\t127.0.0.2"},
+				},
+			},
+		},
+		Zones: []*resolver.Zone{
+			{
+				Name: "example.com.",
+				Soa:  testSOA,
+			},
+		},
+	}
+}
+
+const testSOA = "example.com.\t3600\tIN\tSOA\texample.com. hostmaster.example.com. 1 7200 3600 1209600 3600"
+
+func TestLocalAnswer(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{})
+	h := NewMockHandler(t, []MockHandlerAction{})
+	i := Injector{
+		client: &r,
+		local:  createTestLocalRecords(),
+		logger: zap.NewNop(),
+		next:   &h,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("foo.wild.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	code, err := i.ServeDNS(context.Background(), rec, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if code != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d\n", dns.RcodeSuccess, code)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("Expected answer length to be 1, got %d\n", len(rec.Msg.Answer))
+	}
+	expected := "foo.wild.example.com.\t3600\tIN\tA\t127.0.0.2"
+	if rec.Msg.Answer[0].String() != expected {
+		t.Errorf("Expected answer to be '%s', got '%s'\n", expected, rec.Msg.Answer[0])
+	}
+	r.AssertDone()
+	h.AssertDone()
+}
+
+func TestLocalNegative(t *testing.T) {
+	l := createTestLocalRecords()
+	tests := []struct {
+		name          string
+		qtype         uint16
+		authoritative bool
+		notFound      bool
+		nxdomain      bool
+	}{
+		{name: "example.com.", qtype: dns.TypeAAAA},
+		{name: "foo.wild.example.com.", qtype: dns.TypeTXT},
+		{name: "foo.example.com.", qtype: dns.TypeA, notFound: true},
+		{name: "foo.example.com.", qtype: dns.TypeA, authoritative: true, nxdomain: true},
+		{name: "wild.example.com.", qtype: dns.TypeA, authoritative: true},
+		{name: "example.net.", qtype: dns.TypeA, authoritative: true, notFound: true},
+	}
+	for _, test := range tests {
+		l.zones["example.com."].Authoritative = test.authoritative
+		res, err := l.resolve(test.name, test.qtype)
+		if test.notFound {
+			if status.Convert(err).Code() != codes.NotFound {
+				t.Errorf("%s %d: Expected NotFound, got %v\n", test.name, test.qtype, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s %d: Expected no error, got %v\n", test.name, test.qtype, err)
+		}
+		if len(res.Answer) != 0 || len(res.Ns) != 1 || res.Ns[0] != testSOA {
+			t.Errorf("%s %d: Expected a negative answer with the zone SOA, got %v\n", test.name, test.qtype, res)
+		}
+		if res.Nxdomain != test.nxdomain {
+			t.Errorf("%s %d: Expected nxdomain to be %t\n", test.name, test.qtype, test.nxdomain)
+		}
+	}
+
+	// Deleted zones are removed.
+	l.apply(&resolver.WatchResponse{Zones: []*resolver.Zone{{Name: "example.com."}}})
+	if _, err := l.resolve("example.com.", dns.TypeAAAA); status.Convert(err).Code() != codes.NotFound {
+		t.Errorf("Expected NotFound after deleting the zone, got %v\n", err)
+	}
+}
+
+func TestLocalForwardWhenNotFound(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{})
+	h := NewMockHandler(t, []MockHandlerAction{
+		{
+			In: dns.Msg{
+				Question: []dns.Question{
+					{Name: "foo.example.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+				},
+			},
+			Out:   dns.Msg{},
+			Rcode: dns.RcodeSuccess,
+		},
+	})
+	i := Injector{
+		client: &r,
+		local:  createTestLocalRecords(),
+		logger: zap.NewNop(),
+		next:   &h,
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("foo.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := i.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	r.AssertDone()
+	h.AssertDone()
+}
+
+func TestLocalStale(t *testing.T) {
+	r := NewMockResolver(t, []MockResolverAction{
+		{
+			In: &resolver.Question{
+				Name:  "example.com.",
+				Qtype: uint32(dns.TypeA),
+			},
+			Result: &resolver.Response{
+				Answer: []string{"example.com. IN A 127.0.0.3"},
+			},
+		},
+		{
+			In: &resolver.Question{
+				Name:  "example.com.",
+				Qtype: uint32(dns.TypeA),
+			},
+			Err: status.Error(codes.Unavailable, "unavailable"),
+		},
+	})
+	h := NewMockHandler(t, []MockHandlerAction{})
+	local := createTestLocalRecords()
+	local.received = time.Now().Add(-staleAfter)
+	i := Injector{
+		client: &r,
+		local:  local,
+		logger: zap.NewNop(),
+		next:   &h,
+	}
+
+	// Stale copies are only used when the API fails.
+	for _, expected := range []string{"127.0.0.3", "127.0.0.1"} {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		code, err := i.ServeDNS(context.Background(), rec, req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v\n", err)
+		}
+		if code != dns.RcodeSuccess {
+			t.Errorf("Expected rcode %d, got %d\n", dns.RcodeSuccess, code)
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Expected answer length to be 1, got %d\n", len(rec.Msg.Answer))
+		}
+		if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != expected {
+			t.Errorf("Expected answer address to be %s, got %s\n", expected, a)
+		}
+	}
+	r.AssertDone()
+	h.AssertDone()
+}
+
+func TestLocalApply(t *testing.T) {
+	l := createTestLocalRecords()
+	l.apply(&resolver.WatchResponse{
+		Nodes: []*resolver.Node{
+			{Name: "wild.example.com."},
+			{
+				Name: "foo.example.com.",
+				Records: []*resolver.Record{
+					{Type: uint32(dns.TypeCNAME), Content: "foo.\t3600\tIN\tCNAME\texample.net."},
+				},
+			},
+		},
+	})
+	if _, err := l.resolve("bar.wild.example.com.", dns.TypeA); status.Convert(err).Code() != codes.NotFound {
+		t.Errorf("Expected deleted wildcard record to not be found, got %v\n", err)
+	}
+	res, err := l.resolve("foo.example.com.", dns.TypeA)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if len(res.Answer) != 1 || res.Answer[0] != "foo.example.com.\t3600\tIN\tCNAME\texample.net." {
+		t.Errorf("Expected the created CNAME record, got %v\n", res.Answer)
+	}
+	if _, err := l.resolve("example.com.", dns.TypeA); err != nil {
+		t.Errorf("Expected unchanged record to be found, got %v\n", err)
+	}
+
+	// Changes are ignored until a snapshot is received on the new stream.
+	l.setSynced(false)
+	l.apply(&resolver.WatchResponse{
+		Nodes: []*resolver.Node{{Name: "example.com."}},
+	})
+	if _, err := l.resolve("example.com.", dns.TypeA); err != nil {
+		t.Errorf("Expected record to be found, got %v\n", err)
+	}
+	if l.fresh() {
+		t.Errorf("Expected local records to be stale before a snapshot\n")
+	}
+}
+
+func TestLocalWatchUnimplemented(t *testing.T) {
+	l := newLocalRecords()
+	client := MockWatchResolver{
+		Responses: []*resolver.WatchResponse{
+			testSnapshot(),
+		},
+		Err: status.Error(codes.Unimplemented, "record cache is disabled"),
+	}
+	done := make(chan struct{})
+	go func() {
+		l.watch(context.Background(), &client, zap.NewNop())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected watch to stop after an Unimplemented error\n")
+	}
+	if !l.hasSnapshot() {
+		t.Errorf("Expected the received snapshot to be kept\n")
+	}
+	if l.fresh() {
+		t.Errorf("Expected local records to be stale after the stream ended\n")
+	}
+}
+
+// Watch streams the responses followed by Err.
+type MockWatchResolver struct {
+	MockResolver
+	Responses []*resolver.WatchResponse
+	Err       error
+}
+
+func (r *MockWatchResolver) Watch(ctx context.Context, in *resolver.WatchRequest, opts ...grpc.CallOption) (resolver.Resolver_WatchClient, error) {
+	return &mockWatchClient{responses: r.Responses, err: r.Err}, nil
+}
+
+type mockWatchClient struct {
+	grpc.ClientStream
+	responses []*resolver.WatchResponse
+	err       error
+}
+
+func (c *mockWatchClient) Recv() (*resolver.WatchResponse, error) {
+	if len(c.responses) == 0 {
+		return nil, c.err
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
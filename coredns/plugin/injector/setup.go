@@ -1,6 +1,8 @@
 package injector
 
 import (
+	"context"
+
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -48,10 +50,14 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(name, err)
 	}
 	client := resolver.NewResolverClient(conn)
+	local := newLocalRecords()
+	ctx, cancel := context.WithCancel(context.Background())
+	go local.watch(ctx, client, logger)
 
 	injectorPlugin := Injector{
 		logger:   logger,
 		client:   client,
+		local:    local,
 		upstream: upstream.New(),
 	}
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
@@ -61,6 +67,7 @@ func setup(c *caddy.Controller) error {
 
 	// TODO handle CoreDNS server restarts?
 	c.OnShutdown(func() error {
+		cancel()
 		return conn.Close()
 	})
 
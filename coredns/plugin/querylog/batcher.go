@@ -0,0 +1,136 @@
+package querylog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 10000
+	// The API server rejects larger batches.
+	maxBatchSize = 10000
+	sendTimeout  = 10 * time.Second
+)
+
+// Sends query events to the API server in batches. At most bufferSize events
+// are kept while the API server is unreachable, the oldest events are dropped
+// when the buffer is full so that requests are never delayed.
+type batcher struct {
+	client        resolver.QueryLogClient
+	batchSize     int
+	flushInterval time.Duration
+	bufferSize    int
+	logger        *zap.Logger
+	// Signals run that a full batch is buffered.
+	full chan struct{}
+
+	mu sync.Mutex
+	// Buffered events, oldest first.
+	events []*resolver.QueryEvent
+	// How many events at the start of events are being sent.
+	sending int
+	// How many events being sent were dropped from events, they are counted
+	// as dropped only if sending fails.
+	evicted int
+}
+
+func newBatcher(client resolver.QueryLogClient, batchSize int, flushInterval time.Duration, bufferSize int, logger *zap.Logger) *batcher {
+	return &batcher{
+		client:        client,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		bufferSize:    bufferSize,
+		logger:        logger,
+		full:          make(chan struct{}, 1),
+		events:        []*resolver.QueryEvent{},
+	}
+}
+
+func (b *batcher) add(event *resolver.QueryEvent) {
+	b.mu.Lock()
+	if len(b.events) == b.bufferSize {
+		b.events = b.events[1:]
+		if b.sending != 0 {
+			b.sending--
+			b.evicted++
+		} else {
+			eventsDropped.Inc()
+		}
+	}
+	b.events = append(b.events, event)
+	full := b.batchSize <= len(b.events)
+	b.mu.Unlock()
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Sends full batches immediately and partial batches every flush interval,
+// until ctx is done. After a failure events are only sent every flush
+// interval, until sending succeeds again. Buffered events are sent once more
+// before returning.
+func (b *batcher) run(ctx context.Context) {
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-b.full:
+			if !failing {
+				failing = !b.flush(ctx, false)
+			}
+		case <-ticker.C:
+			failing = !b.flush(ctx, true)
+		case <-ctx.Done():
+			b.flush(context.Background(), true)
+			return
+		}
+	}
+}
+
+// Sends the buffered events in batches, including a last partial batch if
+// partial is set. Returns false if sending failed.
+func (b *batcher) flush(ctx context.Context, partial bool) bool {
+	for {
+		b.mu.Lock()
+		batch := b.events[:min(b.batchSize, len(b.events))]
+		if len(batch) == 0 || (!partial && len(batch) < b.batchSize) {
+			b.mu.Unlock()
+			return true
+		}
+		b.sending = len(batch)
+		b.mu.Unlock()
+		err := b.send(ctx, batch)
+		b.mu.Lock()
+		if err == nil {
+			b.events = b.events[b.sending:]
+		} else {
+			eventsDropped.Add(float64(b.evicted))
+		}
+		b.sending = 0
+		b.evicted = 0
+		b.mu.Unlock()
+		if err != nil {
+			batchFailures.Inc()
+			b.logger.Error("failed to send query events", zap.Int("count", len(batch)), zap.Error(err))
+			return false
+		}
+		eventsSent.Add(float64(len(batch)))
+	}
+}
+
+func (b *batcher) send(ctx context.Context, batch []*resolver.QueryEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	_, err := b.client.AppendQueryLog(ctx, &resolver.AppendQueryLogRequest{Events: batch})
+	return err
+}
@@ -0,0 +1,28 @@
+package querylog
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "events_sent_total",
+		Help:      "Count of query events sent to the API server.",
+	})
+	eventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "events_dropped_total",
+		Help:      "Count of query events dropped because the buffer was full.",
+	})
+	batchFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: name,
+		Name:      "batch_failures_total",
+		Help:      "Count of failures sending batches of query events.",
+	})
+)
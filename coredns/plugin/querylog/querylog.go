@@ -0,0 +1,99 @@
+package querylog
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+)
+
+const name = "querylog"
+
+type QueryLog struct {
+	Next plugin.Handler
+	// EDNS0 local option code carrying client IDs.
+	ClientIDOption uint16
+	batcher        *batcher
+}
+
+func (ql QueryLog) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	start := time.Now()
+	result := &filterResult{}
+	rec := dnstest.NewRecorder(w)
+	rcode, err := plugin.NextOrFailure(ql.Name(), ql.Next, context.WithValue(ctx, contextKeyFilterResult, result), rec, r)
+
+	// The server writes the response of returned rcodes when no plugin wrote
+	// one.
+	if rec.Msg != nil {
+		rcode = rec.Msg.Rcode
+	}
+	state := request.Request{W: w, Req: r}
+	ql.batcher.add(&resolver.QueryEvent{
+		Time:      start.UnixMicro(),
+		Client:    clientIP(state),
+		ClientId:  clientID(r, ql.ClientIDOption),
+		Group:     result.group,
+		Name:      state.Name(),
+		Qtype:     uint32(state.QType()),
+		Rcode:     uint32(rcode),
+		Blocklist: result.blocklist,
+		Duration:  time.Since(start).Microseconds(),
+	})
+	return rcode, err
+}
+
+func (ql QueryLog) Name() string {
+	return name
+}
+
+// Filtering of a request by the filterlist plugin.
+type filterResult struct {
+	group     string
+	blocklist string
+}
+
+// Records the client group filtering the request and the list blocking it in
+// the logged query event. Does nothing when the request is not logged.
+func SetFilterResult(ctx context.Context, group string, blocklist string) {
+	if result, ok := ctx.Value(contextKeyFilterResult).(*filterResult); ok {
+		result.group = group
+		result.blocklist = blocklist
+	}
+}
+
+func clientIP(state request.Request) string {
+	ip, err := netip.ParseAddr(state.IP())
+	if err != nil {
+		return state.IP()
+	}
+	return ip.Unmap().String()
+}
+
+func clientID(r *dns.Msg, clientIDOption uint16) string {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return ""
+	}
+	for _, option := range opt.Option {
+		if local, ok := option.(*dns.EDNS0_LOCAL); ok && local.Code == clientIDOption {
+			return string(local.Data)
+		}
+	}
+	return ""
+}
+
+type contextKey string
+
+func (c contextKey) String() string {
+	return fmt.Sprintf("querylog:%s", string(c))
+}
+
+var (
+	contextKeyFilterResult = contextKey("filterResult")
+)
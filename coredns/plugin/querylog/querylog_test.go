@@ -0,0 +1,199 @@
+package querylog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func TestQueryLog(t *testing.T) {
+	b := newBatcher(&mockQueryLogClient{}, 10, time.Minute, 10, zap.NewNop())
+	ql := QueryLog{
+		Next: plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			SetFilterResult(ctx, "kids", "local")
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeNameError)
+			return dns.RcodeNameError, w.WriteMsg(m)
+		}),
+		ClientIDOption: defaultClientIDOption,
+		batcher:        b,
+	}
+	req := new(dns.Msg)
+	req.SetQuestion("ads.example.com.", dns.TypeAAAA)
+	req.SetEdns0(4096, false)
+	opt := req.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: defaultClientIDOption, Data: []byte("tablet")})
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := ql.ServeDNS(context.Background(), rec, req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	event := b.buffered()[0]
+	if event.Client != "10.240.0.1" || event.ClientId != "tablet" {
+		t.Errorf("expected client 10.240.0.1 and client ID tablet, got %q %q", event.Client, event.ClientId)
+	}
+	if event.Name != "ads.example.com." || event.Qtype != uint32(dns.TypeAAAA) || event.Rcode != uint32(dns.RcodeNameError) {
+		t.Errorf("expected the AAAA query answered with NXDOMAIN, got %v", event)
+	}
+	if event.Group != "kids" || event.Blocklist != "local" {
+		t.Errorf("expected group kids and blocklist local, got %q %q", event.Group, event.Blocklist)
+	}
+}
+
+func TestQueryLogNotWritten(t *testing.T) {
+	b := newBatcher(&mockQueryLogClient{}, 10, time.Minute, 10, zap.NewNop())
+	ql := QueryLog{
+		Next: plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			return dns.RcodeServerFailure, errors.New("test")
+		}),
+		batcher: b,
+	}
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := ql.ServeDNS(context.Background(), rec, req); err == nil {
+		t.Fatalf("expected an error, got none")
+	}
+	event := b.buffered()[0]
+	if event.Rcode != uint32(dns.RcodeServerFailure) || event.Group != "" || event.Blocklist != "" {
+		t.Errorf("expected SERVFAIL without filtering, got %v", event)
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	client := &mockQueryLogClient{}
+	b := newBatcher(client, 2, time.Hour, 10, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+	for _, name := range []string{"a.example.com.", "b.example.com.", "c.example.com."} {
+		b.add(&resolver.QueryEvent{Name: name})
+	}
+	// Full batches are sent without waiting for the flush interval.
+	deadline := time.Now().Add(time.Second)
+	for len(client.sent()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if batches := client.sent(); len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("expected a full batch, got %v", batches)
+	}
+	// Partial batches are sent on shutdown.
+	cancel()
+	<-done
+	if batches := client.sent(); len(batches) != 2 || batches[1][0].Name != "c.example.com." {
+		t.Errorf("expected the pending event to be sent, got %v", batches)
+	}
+}
+
+func TestBatcherFailure(t *testing.T) {
+	client := &mockQueryLogClient{err: errors.New("test")}
+	b := newBatcher(client, 2, time.Hour, 3, zap.NewNop())
+	for _, name := range []string{"a.example.com.", "b.example.com.", "c.example.com.", "d.example.com."} {
+		b.add(&resolver.QueryEvent{Name: name})
+	}
+	// The oldest events are dropped beyond the buffer size.
+	if events := b.buffered(); len(events) != 3 || events[0].Name != "b.example.com." {
+		t.Fatalf("expected the 3 newest events to be kept, got %v", events)
+	}
+	if b.flush(context.Background(), true) {
+		t.Fatalf("expected sending to fail")
+	}
+	if events := b.buffered(); len(events) != 3 {
+		t.Fatalf("expected the events to be kept after a failure, got %v", events)
+	}
+	client.setErr(nil)
+	if !b.flush(context.Background(), true) {
+		t.Errorf("expected all events to be sent")
+	}
+	if batches := client.sent(); len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("expected batches of 2 and 1 events, got %v", batches)
+	}
+}
+
+func TestBatcherRetry(t *testing.T) {
+	client := &mockQueryLogClient{err: errors.New("test")}
+	b := newBatcher(client, 2, time.Hour, 10, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+	b.add(&resolver.QueryEvent{Name: "a.example.com."})
+	b.add(&resolver.QueryEvent{Name: "b.example.com."})
+	deadline := time.Now().Add(time.Second)
+	for client.calls() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := client.calls(); calls != 1 {
+		t.Fatalf("expected a full batch to be sent once, got %d attempts", calls)
+	}
+	// After a failure full batches wait for the flush interval.
+	for i := 0; i < 20; i++ {
+		b.add(&resolver.QueryEvent{Name: "c.example.com."})
+		time.Sleep(time.Millisecond)
+	}
+	if calls := client.calls(); calls != 1 {
+		t.Errorf("expected no attempts before the flush interval, got %d", calls)
+	}
+	cancel()
+	<-done
+	if calls := client.calls(); calls != 2 {
+		t.Errorf("expected a single attempt on shutdown, got %d attempts", calls)
+	}
+}
+
+type mockQueryLogClient struct {
+	mu       sync.Mutex
+	err      error
+	attempts int
+	batches  [][]*resolver.QueryEvent
+}
+
+func (c *mockQueryLogClient) AppendQueryLog(ctx context.Context, in *resolver.AppendQueryLogRequest, opts ...grpc.CallOption) (*resolver.AppendQueryLogResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.batches = append(c.batches, in.Events)
+	return &resolver.AppendQueryLogResponse{}, nil
+}
+
+func (c *mockQueryLogClient) sent() [][]*resolver.QueryEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]*resolver.QueryEvent{}, c.batches...)
+}
+
+func (c *mockQueryLogClient) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts
+}
+
+func (c *mockQueryLogClient) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (b *batcher) buffered() []*resolver.QueryEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*resolver.QueryEvent{}, b.events...)
+}
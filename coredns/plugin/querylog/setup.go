@@ -0,0 +1,132 @@
+package querylog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/sneakybugs/corewarden/coredns/plugin/injector/resolver"
+	"github.com/sneakybugs/corewarden/coredns/plugin/slog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Same as the default of the filterlist plugin.
+const defaultClientIDOption = 65001
+
+func init() {
+	plugin.Register(name, setup)
+}
+
+func setup(c *caddy.Controller) error {
+	target := ""
+	clientIDOption := uint16(defaultClientIDOption)
+	batchSize := defaultBatchSize
+	flushInterval := defaultFlushInterval
+	bufferSize := defaultBufferSize
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return c.ArgErr()
+		}
+		target = args[0]
+		for c.NextBlock() {
+			switch c.Val() {
+			case "batch_size":
+				size, err := parseCount(c, "batch_size")
+				if err != nil {
+					return err
+				}
+				if maxBatchSize < size {
+					return plugin.Error(name, c.Errf("batch_size must be at most %d", maxBatchSize))
+				}
+				batchSize = size
+			case "buffer_size":
+				size, err := parseCount(c, "buffer_size")
+				if err != nil {
+					return err
+				}
+				bufferSize = size
+			case "flush_interval":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				interval, err := time.ParseDuration(args[0])
+				if err != nil || interval <= 0 {
+					return plugin.Error(name, c.Errf("flush_interval must be a positive duration"))
+				}
+				flushInterval = interval
+			case "client_id_option":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				code, err := strconv.ParseUint(args[0], 10, 16)
+				if err != nil {
+					return plugin.Error(name, c.Errf("invalid client_id_option %q", args[0]))
+				}
+				clientIDOption = uint16(code)
+			default:
+				return plugin.Error(name, c.Errf("unknown property %q", c.Val()))
+			}
+		}
+	}
+	if bufferSize < batchSize {
+		return plugin.Error(name, c.Errf("buffer_size must be at least batch_size"))
+	}
+
+	logger, ok := slog.LoggerFromController(c)
+	if !ok {
+		// Use no-op logger when slog plugin is not configured.
+		logger = zap.NewNop()
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return plugin.Error(name, err)
+	}
+	b := newBatcher(resolver.NewQueryLogClient(conn), batchSize, flushInterval, bufferSize, logger)
+	config := dnsserver.GetConfig(c)
+	if config == nil {
+		return fmt.Errorf("failed to get DNS server config")
+	}
+	config.AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return QueryLog{
+			Next:           next,
+			ClientIDOption: clientIDOption,
+			batcher:        b,
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+	// Pending events are sent before shutting down.
+	c.OnShutdown(func() error {
+		cancel()
+		<-done
+		return conn.Close()
+	})
+	return nil
+}
+
+func parseCount(c *caddy.Controller, property string) (int, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 1 {
+		return 0, plugin.Error(name, c.Errf("%s must be a positive integer", property))
+	}
+	return count, nil
+}
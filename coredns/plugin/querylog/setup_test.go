@@ -0,0 +1,57 @@
+package querylog
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	configs := []string{
+		"querylog localhost:6969",
+		`querylog localhost:6969 {
+			batch_size 100
+			buffer_size 1000
+			flush_interval 1s
+			client_id_option 65002
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err != nil {
+			t.Errorf("Test %d: expected no errors, got: %v", i, err)
+		}
+	}
+}
+
+func TestSetupInvalid(t *testing.T) {
+	configs := []string{
+		"querylog",
+		"querylog localhost:6969 localhost:6970",
+		`querylog localhost:6969 {
+			batch_size 0
+		}`,
+		`querylog localhost:6969 {
+			batch_size 20000
+		}`,
+		`querylog localhost:6969 {
+			batch_size 100
+			buffer_size 10
+		}`,
+		`querylog localhost:6969 {
+			flush_interval 0s
+		}`,
+		`querylog localhost:6969 {
+			client_id_option 70000
+		}`,
+		`querylog localhost:6969 {
+			foo bar
+		}`,
+	}
+	for i, config := range configs {
+		c := caddy.NewTestController("dns", config)
+		if err := setup(c); err == nil {
+			t.Errorf("Test %d: expected an error, got no errors", i)
+		}
+	}
+}
@@ -13,6 +13,8 @@ type Options struct {
 	ID          string
 	Secret      string
 	Zones       string
+	OwnerID     string
+	TXTPrefix   string
 	Port        uint16
 	Verbose     bool
 }
@@ -28,6 +30,8 @@ func NewApp(options Options) *fx.App {
 				ID:          options.ID,
 				Secret:      options.Secret,
 				Zones:       options.Zones,
+				OwnerID:     options.OwnerID,
+				TXTPrefix:   options.TXTPrefix,
 			},
 			logger.Options{
 				DevelopmentMode: options.Verbose,
@@ -15,6 +15,8 @@ type Options struct {
 	ID          string
 	Secret      string
 	Zones       string
+	OwnerID     string
+	TXTPrefix   string
 	BindAddress string
 }
 
@@ -24,6 +26,8 @@ func Register(o Options, r *chi.Mux, l *zap.Logger) error {
 		ID:          o.ID,
 		Secret:      o.Secret,
 		Zones:       o.Zones,
+		OwnerID:     o.OwnerID,
+		TXTPrefix:   o.TXTPrefix,
 		Logger:      l,
 	})
 	if err != nil {
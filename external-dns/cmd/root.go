@@ -38,6 +38,8 @@ func CreateRootCommand() Command {
 				ID:          cfg.GetString("api-id"),
 				Secret:      cfg.GetString("api-secret"),
 				Zones:       cfg.GetString("zones"),
+				OwnerID:     cfg.GetString("owner-id"),
+				TXTPrefix:   cfg.GetString("txt-prefix"),
 				Port:        cfg.GetUint16("port"),
 				Verbose:     cfg.GetBool("verbose"),
 			})
@@ -60,6 +62,13 @@ func CreateRootCommand() Command {
 	cmd.Flags().String("zones", "", "Comma-separated list of zones managed by the webhook, for example 'example.com.,example.net.'")
 	_ = cfg.BindPFlag("zones", cmd.Flags().Lookup("zones"))
 
+	cmd.Flags().String("owner-id", "", "ExternalDNS TXT registry owner ID, must match --txt-owner-id, all records in the zones are managed when empty")
+	_ = cfg.BindPFlag("owner-id", cmd.Flags().Lookup("owner-id"))
+
+	cmd.Flags().String("txt-prefix", "", "ExternalDNS TXT registry record prefix, must match --txt-prefix")
+	_ = cfg.BindPFlag("txt-prefix", cmd.Flags().Lookup("txt-prefix"))
+	cfg.SetDefault("txt-prefix", "")
+
 	cmd.Flags().Uint16("port", 0, "HTTP server listen port (default 8888)")
 	_ = cfg.BindPFlag("port", cmd.Flags().Lookup("port"))
 	cfg.SetDefault("port", 8888)
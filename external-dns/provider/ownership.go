@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/sneakybugs/corewarden/client"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Template ExternalDNS replaces with the lowercase record type in TXT prefixes.
+const recordTypeTemplate = "%{record_type}"
+
+// Comment tagging records created by the webhook as owned by ownerID.
+// Uses the same label format as ExternalDNS TXT registry records, for example
+// "heritage=external-dns,external-dns/owner=default".
+func ownershipComment(ownerID string) string {
+	if ownerID == "" {
+		return ""
+	}
+	return endpoint.Labels{endpoint.OwnerLabelKey: ownerID}.SerializePlain(false)
+}
+
+func isOwnedBy(labelText string, ownerID string) bool {
+	labels, err := endpoint.NewLabelsFromStringPlain(labelText)
+	if err != nil {
+		return false
+	}
+	return labels[endpoint.OwnerLabelKey] == ownerID
+}
+
+// Reports whether the record is an ExternalDNS TXT registry record of ownerID.
+func isOwnershipRecord(record client.Record, ownerID string) bool {
+	txt, ok := record.RR.(*dns.TXT)
+	if !ok {
+		return false
+	}
+	return isOwnedBy(strings.Join(txt.Txt, ""), ownerID)
+}
+
+// Names of the TXT registry records ExternalDNS creates for an endpoint, in
+// both the old format and the new format including the record type.
+// Mirrors the naming of the ExternalDNS TXT registry for --txt-prefix.
+func txtRegistryNames(dnsName string, recordType string, prefix string) []string {
+	prefix = strings.ToLower(prefix)
+	recordType = strings.ToLower(recordType)
+	labels := strings.SplitN(strings.ToLower(dnsName), ".", 2)
+
+	oldName := strings.ReplaceAll(prefix, recordTypeTemplate, "") + labels[0]
+	newName := strings.ReplaceAll(prefix, recordTypeTemplate, recordType) + labels[0]
+	if !strings.Contains(prefix, recordTypeTemplate) {
+		newName = prefix + recordType + "-" + labels[0]
+	}
+	if len(labels) == 2 {
+		oldName += "." + labels[1]
+		newName += "." + labels[1]
+	}
+	return []string{oldName, newName}
+}
+
+// Returns the records owned by ownerID, so records created through the REST
+// API are never adopted, updated, or deleted by ExternalDNS.
+//
+// Records are owned when tagged with the ownership comment. Untagged records
+// created before ownership tagging are owned when a TXT registry record of
+// ownerID exists for them.
+// All records are returned when ownerID is empty.
+func filterOwnedRecords(records []client.Record, ownerID string, prefix string) []client.Record {
+	if ownerID == "" {
+		return records
+	}
+	registryNames := map[string]bool{}
+	for _, record := range records {
+		if isOwnershipRecord(record, ownerID) {
+			registryNames[strings.ToLower(formatName(record.Zone, record.RR))] = true
+		}
+	}
+
+	owned := []client.Record{}
+	for _, record := range records {
+		if isOwnedBy(record.Comment, ownerID) {
+			owned = append(owned, record)
+			continue
+		}
+		if record.Comment != "" {
+			// Tagged by another owner or commented by a user.
+			continue
+		}
+		if isOwnershipRecord(record, ownerID) {
+			owned = append(owned, record)
+			continue
+		}
+		rtype := dns.Type(record.RR.Header().Rrtype).String()
+		for _, name := range txtRegistryNames(formatName(record.Zone, record.RR), rtype, prefix) {
+			if registryNames[name] {
+				owned = append(owned, record)
+				break
+			}
+		}
+	}
+	return owned
+}
@@ -15,9 +15,11 @@ import (
 
 type Provider struct {
 	provider.BaseProvider
-	client client.Client
-	zones  []string
-	logger *zap.Logger
+	client    client.Client
+	zones     []string
+	ownerID   string
+	txtPrefix string
+	logger    *zap.Logger
 }
 
 type Configuration struct {
@@ -25,8 +27,13 @@ type Configuration struct {
 	ID          string `env:"CLIENT_ID"`
 	Secret      string `env:"CLIENT_SECRET"`
 	// Comma-separated list of zones to manage records in.
-	Zones  string `env:"CLIENT_ZONES"`
-	Logger *zap.Logger
+	Zones string `env:"CLIENT_ZONES"`
+	// Must match the ExternalDNS --txt-owner-id flag.
+	// Records are not filtered by owner when empty.
+	OwnerID string `env:"CLIENT_OWNER_ID"`
+	// Must match the ExternalDNS --txt-prefix flag.
+	TXTPrefix string `env:"CLIENT_TXT_PREFIX"`
+	Logger    *zap.Logger
 }
 
 func NewProvider(config *Configuration) (provider.Provider, error) {
@@ -36,22 +43,36 @@ func NewProvider(config *Configuration) (provider.Provider, error) {
 		Secret:      config.Secret,
 	})
 	return &Provider{
-		client: &c,
-		zones:  strings.Split(config.Zones, ","),
-		logger: config.Logger,
+		client:    &c,
+		zones:     strings.Split(config.Zones, ","),
+		ownerID:   config.OwnerID,
+		txtPrefix: config.TXTPrefix,
+		logger:    config.Logger,
 	}, nil
 }
 
 func (p *Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	records := []client.Record{}
 	for _, zone := range p.zones {
-		zoneRecords, err := p.client.ListRecords(zone)
+		zoneRecords, err := p.listZoneRecords(zone)
 		if err != nil {
 			return nil, err
 		}
 		records = append(records, zoneRecords...)
 	}
-	return groupByNameAndType(records), nil
+	return groupByNameAndType(filterOwnedRecords(records, p.ownerID, p.txtPrefix)), nil
+}
+
+// Collects all pages of records in the zone.
+func (p *Provider) listZoneRecords(zone string) ([]client.Record, error) {
+	records := []client.Record{}
+	for record, err := range p.client.ListRecords(client.ListRecordsParams{Zone: zone}) {
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
 }
 
 func splitToZoneAndName(domain string, managedZones []string) (string, string, error) {
@@ -69,8 +90,48 @@ func splitToZoneAndName(domain string, managedZones []string) (string, string, e
 	return "", "", fmt.Errorf("domains being split must be subdomains of zones in managedZones")
 }
 
+// Applies all changes in a single changeset, so either all resolvable changes
+// are applied or none of them are.
 func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	errors := []error{}
+	existingRecords := []client.Record{}
+	if 0 < len(changes.Delete) || 0 < len(changes.UpdateNew) {
+		for _, zone := range p.zones {
+			p.logger.Debug("listing records", zap.String("zone", zone))
+			zoneRecords, err := p.listZoneRecords(zone)
+			if err != nil {
+				p.logger.Error("Error listing existing records", zap.String("zone", zone), zap.Error(err))
+				return err
+			}
+			existingRecords = append(existingRecords, zoneRecords...)
+		}
+		existingRecords = filterOwnedRecords(existingRecords, p.ownerID, p.txtPrefix)
+	}
+	operations := []client.ChangesetOperation{}
+	// Deletions are applied first so that a CNAME replacing other records at
+	// the same node does not conflict with the records it replaces.
+	for _, endpoint := range changes.Delete {
+		for _, target := range endpoint.Targets {
+			p.logger.Debug("handling removals for changes.Delete",
+				zap.String("dnsName", endpoint.DNSName),
+				zap.String("target", target),
+			)
+			record, err := findRecord(p.zones, existingRecords, *endpoint, target)
+			if err != nil {
+				p.logger.Error("Error finding existing record for changes.Delete",
+					zap.String("dnsName", endpoint.DNSName),
+					zap.String("target", target),
+					zap.Error(err),
+				)
+				errors = append(errors, err)
+				continue
+			}
+			operations = append(operations, client.ChangesetOperation{
+				Action: client.ChangesetDelete,
+				ID:     record.ID,
+			})
+		}
+	}
 	for _, endpoint := range changes.Create {
 		for _, target := range endpoint.Targets {
 			rr, zone, err := endpointToRR(p.zones, *endpoint, target)
@@ -88,41 +149,13 @@ func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) erro
 				zap.String("zone", zone),
 				zap.String("rr", rr.String()),
 			)
-			_, err = p.client.CreateRecord(client.CreateRecordParams{
+			operations = append(operations, client.ChangesetOperation{
+				Action:  client.ChangesetCreate,
 				Zone:    zone,
 				RR:      rr.String(),
-				Comment: "",
+				Comment: ownershipComment(p.ownerID),
 			})
-			if err != nil {
-				p.logger.Error("Error creating record for changes.Create",
-					zap.String("dnsName", endpoint.DNSName),
-					zap.String("target", target),
-					zap.String("zone", zone),
-					zap.String("rr", rr.String()),
-					zap.Error(err),
-				)
-				errors = append(errors, err)
-				continue
-			}
-		}
-	}
-	if len(changes.UpdateNew) == 0 {
-		// No more updates to perform, avoid listing records.
-		p.logger.Debug("No changes in changes.UpdateNew")
-		if 0 < len(errors) {
-			return fmt.Errorf("encountered %d recoverable errors", len(errors))
-		}
-		return nil
-	}
-	existingRecords := []client.Record{}
-	for _, zone := range p.zones {
-		p.logger.Debug("listing records", zap.String("zone", zone))
-		zoneRecords, err := p.client.ListRecords(zone)
-		if err != nil {
-			p.logger.Error("Error listing existing records", zap.String("zone", zone), zap.Error(err))
-			return err
 		}
-		existingRecords = append(existingRecords, zoneRecords...)
 	}
 	for i, desired := range changes.UpdateNew {
 		current := changes.UpdateOld[i]
@@ -142,18 +175,10 @@ func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) erro
 				errors = append(errors, err)
 				continue
 			}
-			_, err = p.client.DeleteRecord(record.ID)
-			if err != nil {
-				p.logger.Error("Error deleting existing record",
-					zap.String("dnsName", current.DNSName),
-					zap.String("target", target),
-					zap.String("zone", record.Zone),
-					zap.String("rr", record.RR.String()),
-					zap.Error(err),
-				)
-				errors = append(errors, err)
-				continue
-			}
+			operations = append(operations, client.ChangesetOperation{
+				Action: client.ChangesetDelete,
+				ID:     record.ID,
+			})
 		}
 		for _, target := range add {
 			rr, zone, err := endpointToRR(p.zones, *desired, target)
@@ -171,22 +196,12 @@ func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) erro
 				zap.String("zone", zone),
 				zap.String("rr", rr.String()),
 			)
-			_, err = p.client.CreateRecord(client.CreateRecordParams{
+			operations = append(operations, client.ChangesetOperation{
+				Action:  client.ChangesetCreate,
 				Zone:    zone,
 				RR:      rr.String(),
-				Comment: "",
+				Comment: ownershipComment(p.ownerID),
 			})
-			if err != nil {
-				p.logger.Error("Error creating record",
-					zap.String("dnsName", current.DNSName),
-					zap.String("target", target),
-					zap.String("zone", zone),
-					zap.String("rr", rr.String()),
-					zap.Error(err),
-				)
-				errors = append(errors, err)
-				continue
-			}
 		}
 		for _, target := range leave {
 			record, err := findRecord(p.zones, existingRecords, *current, target)
@@ -224,22 +239,31 @@ func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) erro
 				)
 				continue
 			}
-			_, err = p.client.UpdateRecord(client.UpdateRecordParams{
+			comment := record.Comment
+			if p.ownerID != "" {
+				// Tag records adopted through TXT registry records.
+				comment = ownershipComment(p.ownerID)
+			}
+			operations = append(operations, client.ChangesetOperation{
+				Action:  client.ChangesetUpdate,
 				ID:      record.ID,
 				Zone:    record.Zone,
 				RR:      rr.String(),
-				Comment: record.Comment,
+				Comment: comment,
 			})
-			if err != nil {
-				p.logger.Debug("failed to update record",
-					zap.String("dnsName", current.DNSName),
-					zap.String("target", target),
-					zap.String("zone", record.Zone),
-					zap.String("rr", rr.String()),
-				)
-				errors = append(errors, err)
-				continue
-			}
+		}
+	}
+	if 0 < len(operations) {
+		p.logger.Debug("applying changeset", zap.Int("operations", len(operations)))
+		_, err := p.client.ApplyChangeset(client.ApplyChangesetParams{
+			Operations: operations,
+		})
+		if err != nil {
+			p.logger.Error("Error applying changeset",
+				zap.Int("operations", len(operations)),
+				zap.Error(err),
+			)
+			return err
 		}
 	}
 	if 0 < len(errors) {
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"iter"
 	"testing"
 	"time"
 
@@ -129,7 +130,7 @@ type DeleteRecordAction struct {
 	ResponseErr    error
 }
 
-func (c *MockClient) ListRecords(zone string) ([]client.Record, error) {
+func (c *MockClient) ListRecords(params client.ListRecordsParams) iter.Seq2[client.Record, error] {
 	if len(c.actions) <= c.currentActionIndex {
 		c.t.Fatalf("Client called ListRecords when no more method calls were expected\n")
 	}
@@ -137,11 +138,21 @@ func (c *MockClient) ListRecords(zone string) ([]client.Record, error) {
 	if !ok {
 		c.t.Fatalf("Client called unexpected method ListRecords during action %d\n", c.currentActionIndex)
 	}
-	if clientAction.Zone != zone {
-		c.t.Fatalf("Expected zone to be '%s', got '%s'\n", clientAction.Zone, zone)
+	if clientAction.Zone != params.Zone {
+		c.t.Fatalf("Expected zone to be '%s', got '%s'\n", clientAction.Zone, params.Zone)
 	}
 	c.currentActionIndex += 1
-	return clientAction.ResponseRecords, clientAction.ResponseErr
+	return func(yield func(client.Record, error) bool) {
+		if clientAction.ResponseErr != nil {
+			yield(client.Record{}, clientAction.ResponseErr)
+			return
+		}
+		for _, record := range clientAction.ResponseRecords {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
 }
 
 type ListRecordAction struct {
@@ -150,6 +161,25 @@ type ListRecordAction struct {
 	ResponseErr     error
 }
 
+func (c *MockClient) ApplyChangeset(params client.ApplyChangesetParams) ([]client.Record, error) {
+	if len(c.actions) <= c.currentActionIndex {
+		c.t.Fatalf("Client called ApplyChangeset when no more method calls were expected\n")
+	}
+	clientAction, ok := c.actions[c.currentActionIndex].action.(ApplyChangesetAction)
+	if !ok {
+		c.t.Fatalf("Client called unexpected method ApplyChangeset during action %d\n", c.currentActionIndex)
+	}
+	assert.Equal(c.t, clientAction.Operations, params.Operations)
+	c.currentActionIndex += 1
+	return clientAction.ResponseRecords, clientAction.ResponseErr
+}
+
+type ApplyChangesetAction struct {
+	Operations      []client.ChangesetOperation
+	ResponseRecords []client.Record
+	ResponseErr     error
+}
+
 func newTestProvider(t *testing.T, actions []MockClientAction) Provider {
 	mockClient := MockClient{
 		actions:            actions,
@@ -202,6 +232,10 @@ func assertActions(t *testing.T, endpoints []*endpoint.Endpoint, actions []MockC
 
 func assertActionsE(t *testing.T, endpoints []*endpoint.Endpoint, actions []MockClientAction, managedRecords []string) error {
 	p := newTestProvider(t, actions)
+	return applyTestPlan(t, &p, endpoints, managedRecords)
+}
+
+func applyTestPlan(t *testing.T, p *Provider, endpoints []*endpoint.Endpoint, managedRecords []string) error {
 	records, err := p.Records(context.TODO())
 	assert.NoError(t, err)
 	endpoints, err = p.AdjustEndpoints(endpoints)
@@ -236,21 +270,24 @@ func TestNewARecords(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 1, "example.com.", ". 0 IN A 10.0.0.1", ""),
-				ResponseErr:    nil,
-			},
-		},
-		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.1",
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.2",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", ". 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -274,21 +311,24 @@ func TestNewARecordsSubdomain(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "foo.bar.\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 1, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.1", ""),
-				ResponseErr:    nil,
-			},
-		},
-		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "foo.bar.\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "foo.bar.\t0\tIN\tA\t10.0.0.1",
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "foo.bar.\t0\tIN\tA\t10.0.0.2",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -312,21 +352,24 @@ func TestNewARecordsSingleSubdomain(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "my-app.\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 1, "my-app.example.com.", "my-app. 0 IN A 10.0.0.1", ""),
-				ResponseErr:    nil,
-			},
-		},
-		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "my-app.\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "my-app.example.com.", "my-app. 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "my-app.\t0\tIN\tA\t10.0.0.1",
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "my-app.\t0\tIN\tA\t10.0.0.2",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "my-app.example.com.", "my-app. 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "my-app.example.com.", "my-app. 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -363,12 +406,18 @@ func TestNewTargetInExistingARecord(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.3",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 3, "example.com.", ". 0 IN A 10.0.0.3", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.3",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 3, "example.com.", ". 0 IN A 10.0.0.3", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -405,10 +454,17 @@ func TestRemoveTargetInExistingARecord(t *testing.T) {
 			},
 		},
 		{
-			action: DeleteRecordAction{
-				ID:             2,
-				ResponseRecord: createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetDelete,
+						ID:     2,
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -444,29 +500,32 @@ func TestUpdateTargetInExistingARecord(t *testing.T) {
 		},
 		// Must also add or remove target for planner mark endpoint as changed.
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
-			},
-		},
-		{
-			action: UpdateRecordAction{
-				ID:             1,
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 1, "example.com.", ". 0 IN A 10.0.0.1", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.2",
+					},
+					{
+						Action: client.ChangesetUpdate,
+						ID:     1,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.1",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 2, "example.com.", ". 0 IN A 10.0.0.2", ""),
+					createTestRecord(t, 1, "example.com.", ". 0 IN A 10.0.0.1", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
 	assertActions(t, endpoints, actions, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
 }
 
-func TestNewEndpointRecoverableError(t *testing.T) {
+func TestNewEndpointChangesetError(t *testing.T) {
 	endpoints := []*endpoint.Endpoint{
 		{
 			RecordType: "A",
@@ -483,29 +542,29 @@ func TestNewEndpointRecoverableError(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "foo.bar.\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: client.Record{},
-				ResponseErr:    fmt.Errorf("Some error"),
-			},
-		},
-		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             "foo.bar.\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "foo.bar.\t0\tIN\tA\t10.0.0.1",
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "foo.bar.\t0\tIN\tA\t10.0.0.2",
+					},
+				},
+				ResponseRecords: []client.Record{},
+				ResponseErr:     fmt.Errorf("Some error"),
 			},
 		},
 	}
 	err := assertActionsE(t, endpoints, actions, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
-	assert.ErrorContains(t, err, "encountered 1 recoverable errors")
+	assert.ErrorContains(t, err, "Some error")
 }
 
-func TestUpdatedEndpointRecoverableError(t *testing.T) {
+func TestUpdatedEndpointChangesetError(t *testing.T) {
 	endpoints := []*endpoint.Endpoint{
 		{
 			RecordType: "A",
@@ -533,21 +592,160 @@ func TestUpdatedEndpointRecoverableError(t *testing.T) {
 			},
 		},
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.1",
-				Comment:        "",
-				ResponseRecord: client.Record{},
-				ResponseErr:    fmt.Errorf("Some error"),
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.1",
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     ".\t0\tIN\tA\t10.0.0.2",
+					},
+				},
+				ResponseRecords: []client.Record{},
+				ResponseErr:     fmt.Errorf("Some error"),
 			},
 		},
+	}
+	err := assertActionsE(t, endpoints, actions, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+	assert.ErrorContains(t, err, "Some error")
+}
+
+func TestDeleteARecord(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{}
+	actions := []MockClientAction{
 		{
-			action: CreateRecordAction{
-				Zone:           "example.com.",
-				RR:             ".\t0\tIN\tA\t10.0.0.2",
-				Comment:        "",
-				ResponseRecord: createTestRecord(t, 2, "foo.bar.example.com.", "foo.bar. 0 IN A 10.0.0.2", ""),
-				ResponseErr:    nil,
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetDelete,
+						ID:     1,
+					},
+					{
+						Action: client.ChangesetDelete,
+						ID:     2,
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+	}
+	assertActions(t, endpoints, actions, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+}
+
+func TestDeleteBeforeCreate(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		{
+			RecordType: "CNAME",
+			DNSName:    "foo.example.com",
+			Targets:    endpoint.Targets{"bar.example.com"},
+		},
+	}
+	actions := []MockClientAction{
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetDelete,
+						ID:     1,
+					},
+					{
+						Action: client.ChangesetCreate,
+						Zone:   "example.com.",
+						RR:     "foo.\t0\tIN\tCNAME\tbar.example.com.",
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", "foo 0 IN CNAME bar.example.com.", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+	}
+	assertActions(t, endpoints, actions, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+}
+
+func TestDeletedEndpointRecoverableError(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{}
+	actions := []MockClientAction{
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		// Record 1 was deleted between listing endpoints and applying changes.
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetDelete,
+						ID:     2,
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
 			},
 		},
 	}
@@ -688,3 +886,210 @@ func TestRecordsWithTXTAndARecordsTogether(t *testing.T) {
 	assert.Equal(t, "heritage=external-dns,external-dns/owner=default,external-dns/resource=service/telemetry-system/telemetry-system-components-collector-ingress", endpoints[1].Targets[0])
 	assert.Equal(t, "TXT", endpoints[1].RecordType)
 }
+
+const testOwnerComment = "heritage=external-dns,external-dns/owner=default"
+
+func TestRecordsIgnoresUnownedRecords(t *testing.T) {
+	state := []client.Record{
+		createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+		createTestRecord(t, 2, "example.com.", "manual 0 IN A 10.0.0.2", ""),
+		createTestRecord(t, 3, "example.com.", "other 0 IN A 10.0.0.3", "heritage=external-dns,external-dns/owner=other"),
+		createTestRecord(t, 4, "example.com.", "commented 0 IN A 10.0.0.4", "created by hand"),
+	}
+	p := newTestProvider(
+		t,
+		[]MockClientAction{
+			{
+				action: ListRecordAction{
+					Zone:            "example.com.",
+					ResponseRecords: state,
+					ResponseErr:     nil,
+				},
+				stateAfter: state,
+			},
+		},
+	)
+	p.ownerID = "default"
+	endpoints, err := p.Records(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(endpoints), "endpoints length should be 1")
+	assert.Equal(t, "foo.example.com", endpoints[0].DNSName)
+	assert.Equal(t, "10.0.0.1", endpoints[0].Targets[0])
+}
+
+func TestRecordsWithoutOwnerID(t *testing.T) {
+	state := []client.Record{
+		createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+		createTestRecord(t, 2, "example.com.", "manual 0 IN A 10.0.0.2", ""),
+		createTestRecord(t, 3, "example.com.", "other 0 IN A 10.0.0.3", "heritage=external-dns,external-dns/owner=other"),
+	}
+	p := newTestProvider(
+		t,
+		[]MockClientAction{
+			{
+				action: ListRecordAction{
+					Zone:            "example.com.",
+					ResponseRecords: state,
+					ResponseErr:     nil,
+				},
+				stateAfter: state,
+			},
+		},
+	)
+	// All records are managed when no owner ID is set, like before ownership
+	// tagging.
+	endpoints, err := p.Records(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(endpoints), "endpoints length should be 3")
+}
+
+func TestRecordsAdoptsRecordsWithRegistryTXT(t *testing.T) {
+	state := []client.Record{
+		createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+		createTestRecord(t, 2, "example.com.", "a-foo 0 IN TXT \"heritage=external-dns,external-dns/owner=default,external-dns/resource=service/default/foo\"", ""),
+		createTestRecord(t, 3, "example.com.", "bar 0 IN A 10.0.0.2", ""),
+		createTestRecord(t, 4, "example.com.", "a-bar 0 IN TXT \"heritage=external-dns,external-dns/owner=other,external-dns/resource=service/default/bar\"", ""),
+	}
+	p := newTestProvider(
+		t,
+		[]MockClientAction{
+			{
+				action: ListRecordAction{
+					Zone:            "example.com.",
+					ResponseRecords: state,
+					ResponseErr:     nil,
+				},
+				stateAfter: state,
+			},
+		},
+	)
+	p.ownerID = "default"
+	endpoints, err := p.Records(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(endpoints), "endpoints length should be 2")
+	names := []string{endpoints[0].DNSName, endpoints[1].DNSName}
+	assert.Contains(t, names, "foo.example.com")
+	assert.Contains(t, names, "a-foo.example.com")
+}
+
+func TestRecordsAdoptsRecordsWithPrefixedRegistryTXT(t *testing.T) {
+	state := []client.Record{
+		createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", ""),
+		createTestRecord(t, 2, "example.com.", "txt-a-foo 0 IN TXT \"heritage=external-dns,external-dns/owner=default\"", ""),
+		createTestRecord(t, 3, "example.com.", "bar 0 IN CNAME foo.example.com.", ""),
+		createTestRecord(t, 4, "example.com.", "cname.bar 0 IN TXT \"heritage=external-dns,external-dns/owner=default\"", ""),
+	}
+	p := newTestProvider(
+		t,
+		[]MockClientAction{
+			{
+				action: ListRecordAction{
+					Zone:            "example.com.",
+					ResponseRecords: state,
+					ResponseErr:     nil,
+				},
+				stateAfter: state,
+			},
+		},
+	)
+	p.ownerID = "default"
+	p.txtPrefix = "txt-"
+	endpoints, err := p.Records(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(endpoints), "endpoints length should be 3")
+	for _, e := range endpoints {
+		assert.NotEqual(t, "bar.example.com", e.DNSName)
+	}
+}
+
+func TestTXTRegistryNames(t *testing.T) {
+	assert.Equal(t, []string{"foo.example.com", "a-foo.example.com"}, txtRegistryNames("foo.example.com", "A", ""))
+	assert.Equal(t, []string{"txt.foo.example.com", "txt.cname-foo.example.com"}, txtRegistryNames("foo.example.com", "CNAME", "txt."))
+	assert.Equal(t, []string{"com", "a-com"}, txtRegistryNames("com", "A", ""))
+	assert.Equal(t, "a.foo.example.com", txtRegistryNames("foo.example.com", "A", "%{record_type}.")[1])
+}
+
+func TestNewRecordsTaggedWithOwner(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		{
+			RecordType: "A",
+			DNSName:    "foo.example.com",
+			Targets:    endpoint.Targets{"10.0.0.1"},
+		},
+	}
+	actions := []MockClientAction{
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action:  client.ChangesetCreate,
+						Zone:    "example.com.",
+						RR:      "foo.\t0\tIN\tA\t10.0.0.1",
+						Comment: testOwnerComment,
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 2, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+				},
+				ResponseErr: nil,
+			},
+		},
+	}
+	p := newTestProvider(t, actions)
+	p.ownerID = "default"
+	err := applyTestPlan(t, &p, endpoints, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+	assert.NoError(t, err)
+}
+
+func TestDeleteIgnoresUnownedRecords(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{}
+	actions := []MockClientAction{
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+					createTestRecord(t, 2, "example.com.", "manual 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ListRecordAction{
+				Zone: "example.com.",
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+					createTestRecord(t, 2, "example.com.", "manual 0 IN A 10.0.0.2", ""),
+				},
+				ResponseErr: nil,
+			},
+		},
+		{
+			action: ApplyChangesetAction{
+				Operations: []client.ChangesetOperation{
+					{
+						Action: client.ChangesetDelete,
+						ID:     1,
+					},
+				},
+				ResponseRecords: []client.Record{
+					createTestRecord(t, 1, "example.com.", "foo 0 IN A 10.0.0.1", testOwnerComment),
+				},
+				ResponseErr: nil,
+			},
+		},
+	}
+	p := newTestProvider(t, actions)
+	p.ownerID = "default"
+	err := applyTestPlan(t, &p, endpoints, []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME})
+	assert.NoError(t, err)
+}